@@ -0,0 +1,302 @@
+package breaker
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/tal-tech/go-zero/core/logx"
+	"github.com/tal-tech/go-zero/core/mathx"
+	"github.com/tal-tech/go-zero/core/stringx"
+	"github.com/tal-tech/go-zero/core/timex"
+)
+
+const (
+	StateClosed State = iota
+	StateOpen
+	StateHalfOpen
+)
+
+const (
+	numHistoryReasons = 5
+	timeFormat        = "15:04:05"
+)
+
+// ErrServiceUnavailable is returned when the breaker rejects the request.
+var ErrServiceUnavailable = errors.New("circuit breaker is open")
+
+type (
+	State = int32
+
+	// Acceptable checks if it's a successful call, even if the err is not nil.
+	Acceptable func(err error) bool
+
+	// StateChangeHandler is called when the state of the breaker named name changes.
+	StateChangeHandler func(name string, from, to State)
+
+	Breaker interface {
+		// Name returns the name of the breaker.
+		Name() string
+
+		// State returns the current state of the breaker.
+		State() State
+
+		// Allow checks if the request is allowed.
+		// If allowed, a promise will be returned, the caller needs to call promise.Accept()
+		// on success, or call promise.Reject() on failure.
+		// If not allowed, ErrServiceUnavailable will be returned.
+		Allow() (Promise, error)
+
+		// Do runs the given request if the breaker accepts it.
+		// Do returns an error instantly if the breaker rejects the request.
+		// If a panic occurs in the request, the breaker handles it as an error
+		// and causes the same panic again.
+		Do(req func() error) error
+
+		// DoWithAcceptable is like Do, acceptable checks if it's a successful call.
+		DoWithAcceptable(req func() error, acceptable Acceptable) error
+
+		// DoWithFallback is like Do, but runs the fallback if the breaker rejects the request.
+		DoWithFallback(req func() error, fallback func(err error) error) error
+
+		// DoWithFallbackAcceptable is like DoWithFallback, acceptable checks if it's a successful call.
+		DoWithFallbackAcceptable(req func() error, fallback func(err error) error, acceptable Acceptable) error
+	}
+
+	Option func(opts *breakerOptions)
+
+	Promise interface {
+		Accept()
+		Reject(reason string)
+	}
+
+	// throttle is the algorithm of a breaker, notify is called on state changes.
+	throttle interface {
+		allow() error
+		markSuccess()
+		markFailure()
+		state() State
+	}
+
+	breakerOptions struct {
+		name     string
+		classic  *ClassicConf
+		onChange []StateChangeHandler
+	}
+
+	circuitBreaker struct {
+		name string
+		// metrics is false for the unnamed breakers, whose random names would add new series forever
+		metrics  bool
+		throttle throttle
+		errWin   *errorWindow
+		onChange []StateChangeHandler
+	}
+)
+
+// NewBreaker returns a Breaker, which is a google sre breaker unless WithClassic is given.
+func NewBreaker(opts ...Option) Breaker {
+	var options breakerOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	named := len(options.name) > 0
+	if !named {
+		options.name = stringx.Rand()
+	}
+
+	b := &circuitBreaker{
+		name:     options.name,
+		metrics:  named,
+		errWin:   new(errorWindow),
+		onChange: options.onChange,
+	}
+	if options.classic != nil {
+		b.throttle = newClassicBreaker(*options.classic, b.notify)
+	} else {
+		b.throttle = newGoogleBreaker(b.notify)
+	}
+	b.setStateMetric(StateClosed)
+
+	return b
+}
+
+func (cb *circuitBreaker) Name() string {
+	return cb.name
+}
+
+func (cb *circuitBreaker) State() State {
+	return cb.throttle.state()
+}
+
+func (cb *circuitBreaker) Allow() (Promise, error) {
+	if err := cb.throttle.allow(); err != nil {
+		cb.incRequestMetric(resultDropped)
+		return nil, err
+	}
+
+	return promise{b: cb}, nil
+}
+
+func (cb *circuitBreaker) Do(req func() error) error {
+	return cb.doReq(req, nil, defaultAcceptable)
+}
+
+func (cb *circuitBreaker) DoWithAcceptable(req func() error, acceptable Acceptable) error {
+	return cb.doReq(req, nil, acceptable)
+}
+
+func (cb *circuitBreaker) DoWithFallback(req func() error, fallback func(err error) error) error {
+	return cb.doReq(req, fallback, defaultAcceptable)
+}
+
+func (cb *circuitBreaker) DoWithFallbackAcceptable(req func() error, fallback func(err error) error,
+	acceptable Acceptable) error {
+	return cb.doReq(req, fallback, acceptable)
+}
+
+func (cb *circuitBreaker) doReq(req func() error, fallback func(err error) error, acceptable Acceptable) error {
+	if err := cb.throttle.allow(); err != nil {
+		cb.incRequestMetric(resultDropped)
+		if fallback != nil {
+			return fallback(err)
+		}
+
+		return err
+	}
+
+	defer func() {
+		if e := recover(); e != nil {
+			cb.markFailure(fmt.Sprint(e))
+			panic(e)
+		}
+	}()
+
+	err := req()
+	if acceptable(err) {
+		cb.markSuccess()
+	} else if err != nil {
+		cb.markFailure(err.Error())
+	} else {
+		// a custom acceptable might reject nil
+		cb.markFailure("nil error not acceptable")
+	}
+
+	return err
+}
+
+func (cb *circuitBreaker) markSuccess() {
+	cb.incRequestMetric(resultAccepted)
+	cb.throttle.markSuccess()
+}
+
+func (cb *circuitBreaker) markFailure(reason string) {
+	cb.incRequestMetric(resultRejected)
+	cb.errWin.add(reason)
+	cb.throttle.markFailure()
+}
+
+func (cb *circuitBreaker) notify(from, to State) {
+	cb.setStateMetric(to)
+	if to == StateOpen {
+		logx.Errorf("breaker %s is open, last errors:\n%s", cb.name, cb.errWin)
+	} else {
+		logx.Infof("breaker %s changed from %s to %s", cb.name, stateName(from), stateName(to))
+	}
+
+	for _, fn := range cb.onChange {
+		fn(cb.name, from, to)
+	}
+}
+
+func (cb *circuitBreaker) incRequestMetric(result string) {
+	if cb.metrics {
+		metricRequests.WithLabelValues(cb.name, result).Inc()
+	}
+}
+
+func (cb *circuitBreaker) setStateMetric(state State) {
+	if cb.metrics {
+		metricState.WithLabelValues(cb.name).Set(float64(state))
+	}
+}
+
+// WithName sets the name of the breaker, which is used in the logs and the metrics.
+// The breakers without names are given random names, and are not reported in the metrics.
+func WithName(name string) Option {
+	return func(opts *breakerOptions) {
+		opts.name = name
+	}
+}
+
+// WithClassic uses the classic closed/open/half-open breaker instead of the google sre breaker.
+func WithClassic(conf ClassicConf) Option {
+	return func(opts *breakerOptions) {
+		opts.classic = &conf
+	}
+}
+
+// WithStateChange adds a handler that's called on state changes.
+func WithStateChange(fn StateChangeHandler) Option {
+	return func(opts *breakerOptions) {
+		opts.onChange = append(opts.onChange, fn)
+	}
+}
+
+func defaultAcceptable(err error) bool {
+	return err == nil
+}
+
+func stateName(state State) string {
+	switch state {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+type promise struct {
+	b *circuitBreaker
+}
+
+func (p promise) Accept() {
+	p.b.markSuccess()
+}
+
+func (p promise) Reject(reason string) {
+	p.b.markFailure(reason)
+}
+
+type errorWindow struct {
+	reasons [numHistoryReasons]string
+	index   int
+	count   int
+	lock    sync.Mutex
+}
+
+func (ew *errorWindow) add(reason string) {
+	ew.lock.Lock()
+	ew.reasons[ew.index] = fmt.Sprintf("%s %s", timex.Time().Format(timeFormat), reason)
+	ew.index = (ew.index + 1) % numHistoryReasons
+	ew.count = mathx.MinInt(ew.count+1, numHistoryReasons)
+	ew.lock.Unlock()
+}
+
+func (ew *errorWindow) String() string {
+	var reasons []string
+
+	ew.lock.Lock()
+	// reverse order
+	for i := ew.index - 1; i >= ew.index-ew.count; i-- {
+		reasons = append(reasons, ew.reasons[(i+numHistoryReasons)%numHistoryReasons])
+	}
+	ew.lock.Unlock()
+
+	return strings.Join(reasons, "\n")
+}
@@ -0,0 +1,119 @@
+package breaker
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/tal-tech/go-zero/core/logx"
+	"github.com/tal-tech/go-zero/core/stat"
+)
+
+func init() {
+	logx.Disable()
+	stat.SetReporter(nil)
+}
+
+func TestCircuitBreaker_Do(t *testing.T) {
+	b := NewBreaker(WithName("do"))
+	assert.Equal(t, "do", b.Name())
+	assert.Nil(t, b.Do(func() error {
+		return nil
+	}))
+
+	errDummy := errors.New("dummy")
+	assert.Equal(t, errDummy, b.DoWithAcceptable(func() error {
+		return errDummy
+	}, func(err error) bool {
+		return err == errDummy
+	}))
+	assert.Equal(t, float64(2), testutil.ToFloat64(metricRequests.WithLabelValues("do", resultAccepted)))
+
+	assert.Panics(t, func() {
+		b.Do(func() error {
+			panic("boom")
+		})
+	})
+	assert.Equal(t, float64(1), testutil.ToFloat64(metricRequests.WithLabelValues("do", resultRejected)))
+
+	assert.Nil(t, b.DoWithAcceptable(func() error {
+		return nil
+	}, func(err error) bool {
+		return false
+	}))
+	assert.Equal(t, float64(2), testutil.ToFloat64(metricRequests.WithLabelValues("do", resultRejected)))
+}
+
+func TestCircuitBreaker_UnnamedNoMetrics(t *testing.T) {
+	states := testutil.CollectAndCount(metricState)
+	requests := testutil.CollectAndCount(metricRequests)
+	for i := 0; i < 10; i++ {
+		b := NewBreaker()
+		assert.NotEmpty(t, b.Name())
+		assert.Nil(t, b.Do(func() error {
+			return nil
+		}))
+	}
+
+	assert.Equal(t, states, testutil.CollectAndCount(metricState))
+	assert.Equal(t, requests, testutil.CollectAndCount(metricRequests))
+}
+
+func TestCircuitBreaker_Open(t *testing.T) {
+	var changes []State
+	b := NewBreaker(WithName("open"), WithStateChange(func(name string, from, to State) {
+		assert.Equal(t, "open", name)
+		changes = append(changes, to)
+	}))
+	errDummy := errors.New("dummy")
+	for i := 0; i < 1000; i++ {
+		b.Do(func() error {
+			return errDummy
+		})
+	}
+
+	assert.Equal(t, StateOpen, b.State())
+	assert.Equal(t, []State{StateOpen}, changes)
+	assert.Equal(t, float64(StateOpen), testutil.ToFloat64(metricState.WithLabelValues("open")))
+	assert.True(t, testutil.ToFloat64(metricRequests.WithLabelValues("open", resultDropped)) > 0)
+
+	var fallbacks int
+	for i := 0; i < 100; i++ {
+		b.DoWithFallback(func() error {
+			return errDummy
+		}, func(err error) error {
+			assert.Equal(t, ErrServiceUnavailable, err)
+			fallbacks++
+			return nil
+		})
+	}
+	assert.True(t, fallbacks > 0)
+}
+
+func TestCircuitBreaker_Allow(t *testing.T) {
+	b := NewBreaker()
+	assert.True(t, len(b.Name()) > 0)
+	for i := 0; i < 1000; i++ {
+		p, err := b.Allow()
+		if err == nil {
+			p.Reject("dummy")
+		}
+	}
+
+	_, err := b.Allow()
+	for i := 0; i < 100 && err == nil; i++ {
+		_, err = b.Allow()
+	}
+	assert.Equal(t, ErrServiceUnavailable, err)
+}
+
+func TestErrorWindow(t *testing.T) {
+	var ew errorWindow
+	for i := 0; i < numHistoryReasons+2; i++ {
+		ew.add("reason")
+	}
+	assert.Equal(t, numHistoryReasons, ew.count)
+	assert.Equal(t, numHistoryReasons-1, strings.Count(ew.String(), "\n"))
+}
@@ -0,0 +1,75 @@
+package breaker
+
+import "sync"
+
+var (
+	lock     sync.RWMutex
+	breakers = make(map[string]Breaker)
+)
+
+func Do(name string, req func() error) error {
+	return do(name, func(b Breaker) error {
+		return b.Do(req)
+	})
+}
+
+func DoWithAcceptable(name string, req func() error, acceptable Acceptable) error {
+	return do(name, func(b Breaker) error {
+		return b.DoWithAcceptable(req, acceptable)
+	})
+}
+
+func DoWithFallback(name string, req func() error, fallback func(err error) error) error {
+	return do(name, func(b Breaker) error {
+		return b.DoWithFallback(req, fallback)
+	})
+}
+
+func DoWithFallbackAcceptable(name string, req func() error, fallback func(err error) error,
+	acceptable Acceptable) error {
+	return do(name, func(b Breaker) error {
+		return b.DoWithFallbackAcceptable(req, fallback, acceptable)
+	})
+}
+
+// GetBreaker returns the breaker named name, a google sre breaker is created if absent.
+func GetBreaker(name string) Breaker {
+	lock.RLock()
+	b, ok := breakers[name]
+	lock.RUnlock()
+	if ok {
+		return b
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	if b, ok = breakers[name]; !ok {
+		b = NewBreaker(WithName(name))
+		breakers[name] = b
+	}
+
+	return b
+}
+
+// Register creates the breaker named name with opts, like WithClassic or WithStateChange,
+// it replaces the existing one.
+func Register(name string, opts ...Option) Breaker {
+	b := NewBreaker(append(opts, WithName(name))...)
+	lock.Lock()
+	breakers[name] = b
+	lock.Unlock()
+
+	return b
+}
+
+// NoBreakFor disables the breaker named name.
+func NoBreakFor(name string) {
+	lock.Lock()
+	breakers[name] = newNoOpBreaker()
+	lock.Unlock()
+}
+
+func do(name string, execute func(b Breaker) error) error {
+	return execute(GetBreaker(name))
+}
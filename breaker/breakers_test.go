@@ -0,0 +1,80 @@
+package breaker
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBreakers(t *testing.T) {
+	const name = "breakers"
+	errDummy := errors.New("dummy")
+	assert.Nil(t, Do(name, func() error {
+		return nil
+	}))
+	assert.Equal(t, errDummy, DoWithAcceptable(name, func() error {
+		return errDummy
+	}, func(err error) bool {
+		return true
+	}))
+	assert.Equal(t, name, GetBreaker(name).Name())
+	assert.True(t, GetBreaker(name) == GetBreaker(name))
+
+	for i := 0; i < 1000; i++ {
+		DoWithFallback(name, func() error {
+			return errDummy
+		}, func(err error) error {
+			return err
+		})
+	}
+	assert.Equal(t, StateOpen, GetBreaker(name).State())
+	verify(t, func() bool {
+		return DoWithFallbackAcceptable(name, func() error {
+			return nil
+		}, func(err error) error {
+			return nil
+		}, func(err error) bool {
+			return true
+		}) == nil
+	})
+}
+
+func TestRegister(t *testing.T) {
+	const name = "register"
+	b := Register(name, WithClassic(ClassicConf{MinRequests: 1}))
+	assert.Equal(t, name, b.Name())
+	assert.True(t, b == GetBreaker(name))
+	assert.Equal(t, errors.New("dummy"), Do(name, func() error {
+		return errors.New("dummy")
+	}))
+	assert.Equal(t, ErrServiceUnavailable, Do(name, func() error {
+		return nil
+	}))
+}
+
+func TestNoBreakFor(t *testing.T) {
+	const name = "nobreak"
+	NoBreakFor(name)
+	errDummy := errors.New("dummy")
+	for i := 0; i < 1000; i++ {
+		assert.Equal(t, errDummy, Do(name, func() error {
+			return errDummy
+		}))
+	}
+	assert.Equal(t, StateClosed, GetBreaker(name).State())
+	p, err := GetBreaker(name).Allow()
+	assert.Nil(t, err)
+	p.Reject("dummy")
+	p.Accept()
+}
+
+func verify(t *testing.T, fn func() bool) {
+	var count int
+	for i := 0; i < 100; i++ {
+		if fn() {
+			count++
+		}
+	}
+	assert.True(t, count >= 80)
+}
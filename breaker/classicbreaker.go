@@ -0,0 +1,171 @@
+package breaker
+
+import (
+	"sync"
+	"time"
+
+	"github.com/tal-tech/go-zero/core/timex"
+	"github.com/tx991020/utils/collection"
+)
+
+const (
+	defaultFailureRatio     = 0.5
+	defaultMinRequests      = 20
+	defaultOpenTimeout      = time.Second * 5
+	defaultHalfOpenRequests = 1
+)
+
+type (
+	// ClassicConf configures the classic breaker, the zero values are replaced with the defaults.
+	ClassicConf struct {
+		// FailureRatio is the ratio of the failures in the window to open the breaker.
+		FailureRatio float64
+		// MinRequests is the minimum requests in the window to open the breaker.
+		MinRequests int64
+		// OpenTimeout is how long the breaker stays open before half-open.
+		OpenTimeout time.Duration
+		// HalfOpenRequests is how many successful probes in half-open close the breaker,
+		// it's also the most requests allowed in half-open.
+		HalfOpenRequests int
+	}
+
+	// classicBreaker is the closed/open/half-open breaker.
+	classicBreaker struct {
+		conf      ClassicConf
+		lock      sync.Mutex
+		status    State
+		openedAt  time.Duration
+		probes    int
+		successes int
+		stat      *collection.RollingWindow
+		notify    func(from, to State)
+	}
+
+	stateChange struct {
+		from State
+		to   State
+	}
+)
+
+func newClassicBreaker(conf ClassicConf, notify func(from, to State)) *classicBreaker {
+	if conf.FailureRatio <= 0 {
+		conf.FailureRatio = defaultFailureRatio
+	}
+	if conf.MinRequests <= 0 {
+		conf.MinRequests = defaultMinRequests
+	}
+	if conf.OpenTimeout <= 0 {
+		conf.OpenTimeout = defaultOpenTimeout
+	}
+	if conf.HalfOpenRequests <= 0 {
+		conf.HalfOpenRequests = defaultHalfOpenRequests
+	}
+
+	return &classicBreaker{
+		conf:   conf,
+		status: StateClosed,
+		stat:   newClassicWindow(),
+		notify: notify,
+	}
+}
+
+func (b *classicBreaker) allow() error {
+	var changes []stateChange
+	// notify after unlocking, the handlers might call back
+	defer b.fire(&changes)
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	switch b.status {
+	case StateOpen:
+		if timex.Since(b.openedAt) < b.conf.OpenTimeout {
+			return ErrServiceUnavailable
+		}
+		b.probes = 0
+		b.successes = 0
+		changes = append(changes, b.transit(StateHalfOpen))
+		fallthrough
+	case StateHalfOpen:
+		if b.probes >= b.conf.HalfOpenRequests {
+			return ErrServiceUnavailable
+		}
+		b.probes++
+	}
+
+	return nil
+}
+
+func (b *classicBreaker) markSuccess() {
+	var changes []stateChange
+	// notify after unlocking, the handlers might call back
+	defer b.fire(&changes)
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	switch b.status {
+	case StateClosed:
+		b.stat.Add(1)
+	case StateHalfOpen:
+		b.successes++
+		if b.successes >= b.conf.HalfOpenRequests {
+			b.stat = newClassicWindow()
+			changes = append(changes, b.transit(StateClosed))
+		}
+	}
+}
+
+func (b *classicBreaker) markFailure() {
+	var changes []stateChange
+	// notify after unlocking, the handlers might call back
+	defer b.fire(&changes)
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	switch b.status {
+	case StateClosed:
+		b.stat.Add(0)
+		var accepts float64
+		var total int64
+		b.stat.Reduce(func(b *collection.Bucket) {
+			accepts += b.Sum
+			total += b.Count
+		})
+		if total >= b.conf.MinRequests && 1-accepts/float64(total) >= b.conf.FailureRatio {
+			changes = append(changes, b.open())
+		}
+	case StateHalfOpen:
+		changes = append(changes, b.open())
+	}
+}
+
+func (b *classicBreaker) state() State {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.status
+}
+
+// open must be called with the lock held.
+func (b *classicBreaker) open() stateChange {
+	b.openedAt = timex.Now()
+	return b.transit(StateOpen)
+}
+
+// transit must be called with the lock held.
+func (b *classicBreaker) transit(to State) stateChange {
+	from := b.status
+	b.status = to
+	return stateChange{
+		from: from,
+		to:   to,
+	}
+}
+
+func (b *classicBreaker) fire(changes *[]stateChange) {
+	for _, change := range *changes {
+		b.notify(change.from, change.to)
+	}
+}
+
+func newClassicWindow() *collection.RollingWindow {
+	return collection.NewRollingWindow(buckets, time.Duration(int64(window)/int64(buckets)))
+}
@@ -0,0 +1,73 @@
+package breaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassicBreaker(t *testing.T) {
+	var changes []State
+	b := NewBreaker(WithClassic(ClassicConf{
+		FailureRatio:     0.5,
+		MinRequests:      4,
+		OpenTimeout:      time.Millisecond * 50,
+		HalfOpenRequests: 2,
+	}), WithStateChange(func(name string, from, to State) {
+		changes = append(changes, to)
+	}))
+	errDummy := errors.New("dummy")
+	fail := func() error {
+		return errDummy
+	}
+	pass := func() error {
+		return nil
+	}
+
+	assert.Nil(t, b.Do(pass))
+	assert.Nil(t, b.Do(pass))
+	assert.Equal(t, errDummy, b.Do(fail))
+	assert.Equal(t, StateClosed, b.State())
+	assert.Equal(t, errDummy, b.Do(fail))
+	assert.Equal(t, StateOpen, b.State())
+	assert.Equal(t, ErrServiceUnavailable, b.Do(pass))
+
+	// half-open, failed probe opens again
+	time.Sleep(time.Millisecond * 60)
+	assert.Equal(t, errDummy, b.Do(fail))
+	assert.Equal(t, StateOpen, b.State())
+
+	// half-open, allows HalfOpenRequests probes
+	time.Sleep(time.Millisecond * 60)
+	p1, err := b.Allow()
+	assert.Nil(t, err)
+	assert.Equal(t, StateHalfOpen, b.State())
+	p2, err := b.Allow()
+	assert.Nil(t, err)
+	_, err = b.Allow()
+	assert.Equal(t, ErrServiceUnavailable, err)
+	p1.Accept()
+	p2.Accept()
+	assert.Equal(t, StateClosed, b.State())
+
+	// the window is reset on closed
+	assert.Equal(t, errDummy, b.Do(fail))
+	assert.Equal(t, StateClosed, b.State())
+
+	assert.Equal(t, []State{StateOpen, StateHalfOpen, StateOpen, StateHalfOpen, StateClosed}, changes)
+}
+
+func TestClassicBreaker_HookCallsBack(t *testing.T) {
+	var b Breaker
+	b = NewBreaker(WithClassic(ClassicConf{
+		MinRequests: 1,
+	}), WithStateChange(func(name string, from, to State) {
+		assert.Equal(t, to, b.State())
+	}))
+	b.Do(func() error {
+		return errors.New("dummy")
+	})
+	assert.Equal(t, StateOpen, b.State())
+}
@@ -0,0 +1,101 @@
+package breaker
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+
+	"github.com/tal-tech/go-zero/core/mathx"
+	"github.com/tx991020/utils/collection"
+)
+
+const (
+	// 250ms for bucket duration
+	window     = time.Second * 10
+	buckets    = 40
+	k          = 1.5
+	protection = 5
+	// minStateDuration is the minimum time in a state before changing it again,
+	// to not flap and notify on every request under the borderline load.
+	minStateDuration = time.Second
+)
+
+// googleBreaker is the client-side throttling from google, it's open while requests are dropped.
+// see Client-Side Throttling section in https://landing.google.com/sre/sre-book/chapters/handling-overload/
+type googleBreaker struct {
+	// changed is the time of the last state change in unix nanos, zero to change at once,
+	// it's the first field to be 64-bit aligned for atomic operations
+	changed int64
+	k       float64
+	status  int32
+	stat    *collection.RollingWindow
+	proba   *mathx.Proba
+	notify  func(from, to State)
+}
+
+func newGoogleBreaker(notify func(from, to State)) *googleBreaker {
+	bucketDuration := time.Duration(int64(window) / int64(buckets))
+	return &googleBreaker{
+		stat:   collection.NewRollingWindow(buckets, bucketDuration),
+		k:      k,
+		status: StateClosed,
+		proba:  mathx.NewProba(),
+		notify: notify,
+	}
+}
+
+func (b *googleBreaker) allow() error {
+	accepts, total := b.history()
+	weightedAccepts := b.k * float64(accepts)
+	// https://landing.google.com/sre/sre-book/chapters/handling-overload/#eq2101
+	dropRatio := math.Max(0, (float64(total-protection)-weightedAccepts)/float64(total+1))
+	if dropRatio <= 0 {
+		b.transit(StateOpen, StateClosed)
+		return nil
+	}
+
+	b.transit(StateClosed, StateOpen)
+	if b.proba.TrueOnProba(dropRatio) {
+		return ErrServiceUnavailable
+	}
+
+	return nil
+}
+
+// transit changes the state from from to to, if it's been in from for minStateDuration.
+func (b *googleBreaker) transit(from, to State) {
+	if atomic.LoadInt32(&b.status) != from {
+		return
+	}
+
+	now := time.Now().UnixNano()
+	if now-atomic.LoadInt64(&b.changed) < int64(minStateDuration) {
+		return
+	}
+
+	if atomic.CompareAndSwapInt32(&b.status, from, to) {
+		atomic.StoreInt64(&b.changed, now)
+		b.notify(from, to)
+	}
+}
+
+func (b *googleBreaker) markSuccess() {
+	b.stat.Add(1)
+}
+
+func (b *googleBreaker) markFailure() {
+	b.stat.Add(0)
+}
+
+func (b *googleBreaker) state() State {
+	return atomic.LoadInt32(&b.status)
+}
+
+func (b *googleBreaker) history() (accepts, total int64) {
+	b.stat.Reduce(func(b *collection.Bucket) {
+		accepts += int64(b.Sum)
+		total += b.Count
+	})
+
+	return
+}
@@ -0,0 +1,32 @@
+package breaker
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGoogleBreakerMinStateDuration(t *testing.T) {
+	var changes []State
+	b := newGoogleBreaker(func(from, to State) {
+		changes = append(changes, to)
+	})
+	for i := 0; i < 100; i++ {
+		b.markFailure()
+	}
+	b.allow()
+	assert.Equal(t, StateOpen, b.state())
+
+	for i := 0; i < 1000; i++ {
+		b.markSuccess()
+	}
+	assert.Nil(t, b.allow())
+	assert.Equal(t, StateOpen, b.state(), "not closed until open for minStateDuration")
+
+	atomic.StoreInt64(&b.changed, time.Now().Add(-minStateDuration).UnixNano())
+	assert.Nil(t, b.allow())
+	assert.Equal(t, StateClosed, b.state())
+	assert.Equal(t, []State{StateOpen, StateClosed}, changes)
+}
@@ -0,0 +1,26 @@
+package breaker
+
+import "github.com/prometheus/client_golang/prometheus"
+
+const (
+	resultAccepted = "accepted"
+	resultRejected = "rejected"
+	resultDropped  = "dropped"
+)
+
+var (
+	metricState = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "breaker",
+		Name:      "state",
+		Help:      "The state of the breakers, 0 for closed, 1 for open and 2 for half-open.",
+	}, []string{"name"})
+	metricRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "breaker",
+		Name:      "requests_total",
+		Help:      "The requests of the breakers, by accepted, rejected and dropped.",
+	}, []string{"name", "result"})
+)
+
+func init() {
+	prometheus.MustRegister(metricState, metricRequests)
+}
@@ -2,6 +2,7 @@ package collection
 
 import (
 	"container/list"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -81,6 +82,25 @@ func (c *Cache) Del(key string) {
 	c.timingWheel.RemoveTimer(key)
 }
 
+func (c *Cache) DelPrefix(prefix string) {
+	var keys []string
+	c.lock.Lock()
+	for key := range c.data {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	for _, key := range keys {
+		delete(c.data, key)
+		c.lruCache.remove(key)
+	}
+	c.lock.Unlock()
+
+	for _, key := range keys {
+		c.timingWheel.RemoveTimer(key)
+	}
+}
+
 func (c *Cache) Get(key string) (interface{}, bool) {
 	c.lock.Lock()
 	value, ok := c.data[key]
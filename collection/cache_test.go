@@ -40,6 +40,25 @@ func TestCacheDel(t *testing.T) {
 	assert.Equal(t, "second element", value)
 }
 
+func TestCacheDelPrefix(t *testing.T) {
+	cache, err := NewCache(time.Second * 2)
+	assert.Nil(t, err)
+
+	cache.Set("user:1", "first user")
+	cache.Set("user:2", "second user")
+	cache.Set("order:1", "first order")
+	cache.DelPrefix("user:")
+
+	_, ok := cache.Get("user:1")
+	assert.False(t, ok)
+	_, ok = cache.Get("user:2")
+	assert.False(t, ok)
+	value, ok := cache.Get("order:1")
+	assert.True(t, ok)
+	assert.Equal(t, "first order", value)
+	assert.Equal(t, 1, cache.size())
+}
+
 func TestCacheTake(t *testing.T) {
 	cache, err := NewCache(time.Second * 2)
 	assert.Nil(t, err)
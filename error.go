@@ -16,4 +16,6 @@ type Response struct {
 	Code int         `json:"code"`
 	Msg  string      `json:"msg"`
 	Data interface{} `json:"data,omitempty"`
+	// RequestID is only set if the request id is enabled
+	RequestID string `json:"requestId,omitempty"`
 }
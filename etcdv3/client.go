@@ -18,28 +18,35 @@ import (
 	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"errors"
 	"io/ioutil"
 	"strings"
 	"time"
 
-	"github.com/coreos/etcd/clientv3/concurrency"
-	"github.com/tx991020/utils/logger"
-
-	"github.com/coreos/etcd/clientv3"
-	"github.com/coreos/etcd/mvcc/mvccpb"
-
+	"github.com/douyu/jupiter/pkg/xlog"
 	grpcprom "github.com/grpc-ecosystem/go-grpc-prometheus"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
 	"google.golang.org/grpc"
 )
 
+// ErrNoEndpoints is returned on building a client without endpoints.
+var ErrNoEndpoints = errors.New("etcdv3: client etcd endpoints empty")
+
 // Client ...
 type Client struct {
 	*clientv3.Client
 	config *Config
 }
 
-// New ...
-func newClient(config *Config) *Client {
+// NewClient returns a Client connected to the endpoints of config.
+// The default logger is set on config if it has none, like a config not from DefaultConfig.
+func NewClient(config *Config) (*Client, error) {
+	if config.logger == nil {
+		config.logger = defaultLogger()
+	}
+
 	conf := clientv3.Config{
 		Endpoints:            config.Endpoints,
 		DialTimeout:          config.ConnectTimeout,
@@ -53,11 +60,8 @@ func newClient(config *Config) *Client {
 		AutoSyncInterval: config.AutoSyncInterval,
 	}
 
-
-
-	if config.Endpoints == nil {
-		logger.Errora("client etcd endpoints empty")
-
+	if len(config.Endpoints) == 0 {
+		return nil, ErrNoEndpoints
 	}
 
 	if !config.Secure {
@@ -77,7 +81,7 @@ func newClient(config *Config) *Client {
 	if config.CaCert != "" {
 		certBytes, err := ioutil.ReadFile(config.CaCert)
 		if err != nil {
-			logger.Errora(err)
+			return nil, err
 		}
 
 		caCertPool := x509.NewCertPool()
@@ -92,7 +96,7 @@ func newClient(config *Config) *Client {
 	if config.CertFile != "" && config.KeyFile != "" {
 		tlsCert, err := tls.LoadX509KeyPair(config.CertFile, config.KeyFile)
 		if err != nil {
-			logger.Errora(err)
+			return nil, err
 		}
 		tlsConfig.Certificates = []tls.Certificate{tlsCert}
 		tlsEnabled = true
@@ -103,18 +107,16 @@ func newClient(config *Config) *Client {
 	}
 
 	client, err := clientv3.New(conf)
-
 	if err != nil {
-
-		logger.Errora("client etcd start panic",err)
+		return nil, err
 	}
 
 	cc := &Client{
 		Client: client,
 		config: config,
 	}
-	logger.Errora("dial etcd server")
-	return cc
+	config.logger.Info("dial etcd server", xlog.FieldAddrAny(config.Endpoints))
+	return cc, nil
 }
 
 // GetKeyValue queries etcd key, returns mvccpb.KeyValue
@@ -216,7 +218,7 @@ func (client *Client) GetValues(ctx context.Context, keys ...string) (map[string
 	return vars, nil
 }
 
-//GetLeaseSession 创建租约会话
+// GetLeaseSession 创建租约会话
 func (client *Client) GetLeaseSession(ctx context.Context, opts ...concurrency.SessionOption) (leaseSession *concurrency.Session, err error) {
 	return concurrency.NewSession(client.Client, opts...)
 }
@@ -2,18 +2,17 @@ package etcdv3
 
 import (
 	"context"
-	"github.com/coreos/etcd/clientv3"
-	"github.com/coreos/etcd/clientv3/concurrency"
 	"github.com/stretchr/testify/assert"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
 	"testing"
 	"time"
 )
 
 func Test_GetKeyValue(t *testing.T) {
 	config := DefaultConfig()
-	config.Endpoints = []string{"127.0.0.1:2379"}
 	config.TTL = 5
-	etcdCli := newClient(config)
+	etcdCli := newTestClient(config.TTL)
 
 	ctx := context.TODO()
 
@@ -32,9 +31,8 @@ func Test_GetKeyValue(t *testing.T) {
 
 func Test_MutexLock(t *testing.T) {
 	config := DefaultConfig()
-	config.Endpoints = []string{"127.0.0.1:2379"}
 	config.TTL = 10
-	etcdCli := newClient(config)
+	etcdCli := newTestClient(config.TTL)
 
 	etcdMutex1, err := etcdCli.NewMutex("/test/lock",
 		concurrency.WithTTL(int(config.TTL)))
@@ -53,3 +51,36 @@ func Test_MutexLock(t *testing.T) {
 	err = etcdMutex.Lock(time.Second * 1)
 	assert.NotNil(t, err)
 }
+
+func TestNewClient(t *testing.T) {
+	_, err := NewClient(DefaultConfig())
+	assert.Equal(t, ErrNoEndpoints, err)
+
+	config := DefaultConfig()
+	config.Endpoints = []string{"127.0.0.1:1"}
+	config.ConnectTimeout = time.Millisecond * 100
+	_, err = NewClient(config)
+	assert.NotNil(t, err)
+	assert.Panics(t, func() {
+		config.Build()
+	})
+}
+
+func TestNewClientWithoutLogger(t *testing.T) {
+	assert.Panics(t, func() {
+		(&Config{Endpoints: []string{"127.0.0.1:1"}, ConnectTimeout: time.Millisecond * 100}).Build()
+	}, "panics on the failure instead of the nil logger")
+
+	config := &Config{Endpoints: testEndpoints, ConnectTimeout: time.Second, TTL: 10}
+	client, err := NewClient(config)
+	assert.Nil(t, err)
+	assert.NotNil(t, config.logger)
+	defer client.Close()
+
+	w, err := client.WatchPrefix(context.Background(), "/test/nologger/")
+	assert.Nil(t, err)
+	assert.Nil(t, w.Close())
+	r, err := client.Register("/test/nologger", Instance{Name: "svc", Addr: "10.0.0.1:80"}, 5)
+	assert.Nil(t, err)
+	assert.Nil(t, r.Deregister(context.Background()))
+}
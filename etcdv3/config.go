@@ -17,10 +17,10 @@ package etcdv3
 import (
 	"time"
 
-
-
-
-
+	"github.com/douyu/jupiter/pkg/conf"
+	"github.com/douyu/jupiter/pkg/ecode"
+	"github.com/douyu/jupiter/pkg/util/xtime"
+	"github.com/douyu/jupiter/pkg/xlog"
 )
 
 // Config ...
@@ -39,7 +39,7 @@ type (
 		// 自动同步member list的间隔
 		AutoSyncInterval time.Duration `json:"autoAsyncInterval"`
 		TTL              int           // 单位：s
-
+		logger           *xlog.Logger
 	}
 )
 
@@ -49,9 +49,14 @@ func DefaultConfig() *Config {
 		BasicAuth:      false,
 		ConnectTimeout: xtime.Duration("5s"),
 		Secure:         false,
+		logger:         defaultLogger(),
 	}
 }
 
+func defaultLogger() *xlog.Logger {
+	return xlog.JupiterLogger.With(xlog.FieldMod("client.etcd"))
+}
+
 // StdConfig ...
 func StdConfig(name string) *Config {
 	return RawConfig("jupiter.etcdv3." + name)
@@ -72,8 +77,11 @@ func (config *Config) WithLogger(logger *xlog.Logger) *Config {
 	return config
 }
 
-// Build ...
+// Build builds the client, panics on failure, use NewClient to handle the errors.
 func (config *Config) Build() *Client {
-	cc := newClient(config)
+	cc, err := NewClient(config)
+	if err != nil {
+		config.logger.Panic("client etcd start panic", xlog.FieldErrKind(ecode.ErrKindAny), xlog.FieldErr(err), xlog.FieldAddrAny(config.Endpoints))
+	}
 	return cc
 }
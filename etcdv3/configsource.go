@@ -0,0 +1,425 @@
+package etcdv3
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/douyu/jupiter/pkg/xlog"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// FormatJSON is the format of the values like a.json or without extensions by default.
+	FormatJSON = "json"
+	// FormatYAML is the format of the values like a.yaml or a.yml.
+	FormatYAML = "yaml"
+	// FormatTOML is the format of the values like a.toml.
+	FormatTOML = "toml"
+
+	configRetryInterval = time.Second * 3
+)
+
+var (
+	// ErrNoPreviousConfig is returned on rolling back without a previous config.
+	ErrNoPreviousConfig = errors.New("etcdv3: no previous config to roll back to")
+
+	configDecoders = map[string]func(data []byte, v interface{}) error{
+		FormatJSON: json.Unmarshal,
+		FormatYAML: yaml.Unmarshal,
+		FormatTOML: toml.Unmarshal,
+	}
+)
+
+type (
+	// ConfigOption customizes a ConfigSource.
+	ConfigOption func(opts *configOptions)
+
+	configOptions struct {
+		format    string
+		validator func(v interface{}) error
+		cacheFile string
+	}
+
+	// A ConfigValidator validates itself before the config is applied.
+	ConfigValidator interface {
+		Validate() error
+	}
+
+	// A ConfigDecoder decodes the values of the keys under a prefix into a typed config.
+	// The values are decoded by the formats of their key extensions into the same struct
+	// in the order of the keys, so the later keys override the former ones.
+	ConfigDecoder struct {
+		typ     reflect.Type
+		options configOptions
+	}
+
+	// ConfigSource keeps a typed config loaded from the keys under a prefix up to date.
+	ConfigSource struct {
+		client  *Client
+		prefix  string
+		decoder *ConfigDecoder
+		logger  *xlog.Logger
+
+		lock     sync.RWMutex
+		value    interface{}
+		raw      map[string]string
+		previous map[string]string
+		// latest is the latest values in etcd, which might not be valid to apply
+		latest      map[string]string
+		subscribers []func(old, new interface{})
+		watch       *Watch
+		done        chan struct{}
+		once        sync.Once
+	}
+)
+
+// WithConfigFormat sets the format of the values whose keys don't have known extensions.
+func WithConfigFormat(format string) ConfigOption {
+	return func(opts *configOptions) {
+		opts.format = format
+	}
+}
+
+// WithConfigValidator validates the configs with fn before they are applied,
+// besides the Validate method if the config implements ConfigValidator.
+func WithConfigValidator(fn func(v interface{}) error) ConfigOption {
+	return func(opts *configOptions) {
+		opts.validator = fn
+	}
+}
+
+// WithConfigCache caches the values in file on every change,
+// the cached values are loaded on start if etcd is unavailable.
+func WithConfigCache(file string) ConfigOption {
+	return func(opts *configOptions) {
+		opts.cacheFile = file
+	}
+}
+
+// NewConfigSource returns a ConfigSource that loads the keys under prefix into the type of v,
+// which must be a pointer to struct, v is filled with the initial config.
+// If etcd is unavailable on start, the cached values are loaded and etcd is retried in background.
+func (client *Client) NewConfigSource(ctx context.Context, prefix string, v interface{},
+	opts ...ConfigOption) (*ConfigSource, error) {
+	decoder, err := NewConfigDecoder(v, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	cs := &ConfigSource{
+		client:  client,
+		prefix:  prefix,
+		decoder: decoder,
+		logger:  client.config.logger.With(xlog.String("config", prefix)),
+		done:    make(chan struct{}),
+	}
+
+	w, err := client.WatchPrefix(ctx, prefix)
+	if err != nil {
+		raw, cerr := decoder.LoadCache()
+		if cerr != nil {
+			return nil, err
+		}
+
+		cs.logger.Warn("etcd unavailable, config loaded from cache", xlog.FieldErr(err))
+		if err := cs.apply(raw); err != nil {
+			return nil, err
+		}
+		go cs.rewatch()
+	} else {
+		if err := cs.apply(toRaw(w.IncipientKeyValues())); err != nil {
+			w.Close()
+			return nil, err
+		}
+		cs.watch = w
+		go cs.run(w)
+	}
+
+	reflect.ValueOf(v).Elem().Set(reflect.ValueOf(cs.Value()).Elem())
+	return cs, nil
+}
+
+// NewConfigDecoder returns a ConfigDecoder into the type of v, which must be a pointer to struct.
+func NewConfigDecoder(v interface{}, opts ...ConfigOption) (*ConfigDecoder, error) {
+	val := reflect.ValueOf(v)
+	if val.Kind() != reflect.Ptr || val.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("etcdv3: config must be a pointer to struct, got %T", v)
+	}
+
+	d := &ConfigDecoder{
+		typ: val.Elem().Type(),
+		options: configOptions{
+			format: FormatJSON,
+		},
+	}
+	for _, opt := range opts {
+		opt(&d.options)
+	}
+
+	return d, nil
+}
+
+// Decode decodes raw into a new config and validates it, raw is the values by the keys.
+func (d *ConfigDecoder) Decode(raw map[string]string) (interface{}, error) {
+	keys := make([]string, 0, len(raw))
+	for key := range raw {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	value := reflect.New(d.typ).Interface()
+	for _, key := range keys {
+		format := strings.TrimPrefix(path.Ext(key), ".")
+		if format == "yml" {
+			format = FormatYAML
+		}
+		decode, ok := configDecoders[format]
+		if !ok {
+			decode = configDecoders[d.options.format]
+		}
+		if decode == nil {
+			return nil, fmt.Errorf("etcdv3: unknown config format %q", d.options.format)
+		}
+
+		if err := decode([]byte(raw[key]), value); err != nil {
+			return nil, fmt.Errorf("etcdv3: bad config %s: %v", key, err)
+		}
+	}
+
+	if validator, ok := value.(ConfigValidator); ok {
+		if err := validator.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	if d.options.validator != nil {
+		if err := d.options.validator(value); err != nil {
+			return nil, err
+		}
+	}
+
+	return value, nil
+}
+
+// LoadCache loads the values cached by SaveCache, os.ErrNotExist is returned if not cached.
+func (d *ConfigDecoder) LoadCache() (map[string]string, error) {
+	if len(d.options.cacheFile) == 0 {
+		return nil, os.ErrNotExist
+	}
+
+	content, err := ioutil.ReadFile(d.options.cacheFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw map[string]string
+	if err := json.Unmarshal(content, &raw); err != nil {
+		return nil, err
+	}
+
+	return raw, nil
+}
+
+// SaveCache caches raw in the file of WithConfigCache, does nothing without it.
+func (d *ConfigDecoder) SaveCache(raw map[string]string) error {
+	if len(d.options.cacheFile) == 0 {
+		return nil
+	}
+
+	content, err := json.Marshal(raw)
+	if err != nil {
+		return err
+	}
+
+	// write and rename to not leave a broken cache on crash
+	tmp := d.options.cacheFile + ".tmp"
+	if err = ioutil.WriteFile(tmp, content, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, d.options.cacheFile)
+}
+
+// Close stops watching the changes.
+func (cs *ConfigSource) Close() error {
+	cs.once.Do(func() {
+		close(cs.done)
+	})
+
+	cs.lock.Lock()
+	defer cs.lock.Unlock()
+	if cs.watch != nil {
+		return cs.watch.Close()
+	}
+
+	return nil
+}
+
+// Rollback puts the values of the previous config back to etcd, which are then applied.
+// If the latest values in etcd are not applied because they are invalid,
+// the values of the current config are put back instead.
+func (cs *ConfigSource) Rollback(ctx context.Context) error {
+	cs.lock.RLock()
+	previous, latest := cs.previous, cs.latest
+	if !reflect.DeepEqual(cs.raw, latest) {
+		previous = cs.raw
+	}
+	cs.lock.RUnlock()
+
+	if previous == nil {
+		return ErrNoPreviousConfig
+	}
+
+	var ops []clientv3.Op
+	for key, value := range previous {
+		ops = append(ops, clientv3.OpPut(key, value))
+	}
+	for key := range latest {
+		if _, ok := previous[key]; !ok {
+			ops = append(ops, clientv3.OpDelete(key))
+		}
+	}
+
+	_, err := cs.client.Txn(ctx).Then(ops...).Commit()
+	return err
+}
+
+// Subscribe adds fn to be called with the old and new configs after every change is applied.
+func (cs *ConfigSource) Subscribe(fn func(old, new interface{})) {
+	cs.lock.Lock()
+	cs.subscribers = append(cs.subscribers, fn)
+	cs.lock.Unlock()
+}
+
+// Value returns the current config, a pointer to the struct that should not be modified.
+func (cs *ConfigSource) Value() interface{} {
+	cs.lock.RLock()
+	defer cs.lock.RUnlock()
+	return cs.value
+}
+
+// apply decodes and validates raw, and applies it if ok.
+func (cs *ConfigSource) apply(raw map[string]string) error {
+	cs.lock.Lock()
+	cs.latest = raw
+	unchanged := cs.raw != nil && reflect.DeepEqual(cs.raw, raw)
+	cs.lock.Unlock()
+	if unchanged {
+		return nil
+	}
+
+	value, err := cs.decoder.Decode(raw)
+	if err != nil {
+		return err
+	}
+
+	cs.lock.Lock()
+	old := cs.value
+	cs.value = value
+	if cs.raw != nil {
+		cs.previous = cs.raw
+	}
+	cs.raw = raw
+	subscribers := cs.subscribers
+	cs.lock.Unlock()
+
+	if err := cs.decoder.SaveCache(raw); err != nil {
+		cs.logger.Error("save config cache failed", xlog.FieldErr(err))
+	}
+	for _, subscriber := range subscribers {
+		subscriber(old, value)
+	}
+
+	return nil
+}
+
+// rewatch retries watching until done, the current values are applied once watched.
+func (cs *ConfigSource) rewatch() {
+	for {
+		select {
+		case <-cs.done:
+			return
+		case <-time.After(configRetryInterval):
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), configRetryInterval)
+		w, err := cs.client.WatchPrefix(ctx, cs.prefix)
+		cancel()
+		if err != nil {
+			continue
+		}
+
+		cs.lock.Lock()
+		select {
+		case <-cs.done:
+			cs.lock.Unlock()
+			w.Close()
+			return
+		default:
+			cs.watch = w
+		}
+		cs.lock.Unlock()
+
+		cs.logger.Info("etcd available, watching config")
+		if err := cs.apply(toRaw(w.IncipientKeyValues())); err != nil {
+			cs.logger.Error("bad config, ignored", xlog.FieldErr(err))
+		}
+		cs.run(w)
+		return
+	}
+}
+
+// run applies the changes once per batch, so the changes of a revision, like the ones
+// of a transaction, are validated and applied together instead of in intermediate states.
+func (cs *ConfigSource) run(w *Watch) {
+	for {
+		select {
+		case events, ok := <-w.Batches():
+			if !ok {
+				return
+			}
+
+			cs.lock.RLock()
+			raw := make(map[string]string, len(cs.latest)+len(events))
+			for key, value := range cs.latest {
+				raw[key] = value
+			}
+			cs.lock.RUnlock()
+
+			for _, ev := range events {
+				key := string(ev.Kv.Key)
+				if ev.Type == clientv3.EventTypeDelete {
+					delete(raw, key)
+				} else {
+					raw[key] = string(ev.Kv.Value)
+				}
+			}
+			if err := cs.apply(raw); err != nil {
+				cs.logger.Error("bad config, ignored", xlog.FieldErr(err))
+			}
+		case <-cs.done:
+			return
+		}
+	}
+}
+
+func toRaw(kvs []*mvccpb.KeyValue) map[string]string {
+	raw := make(map[string]string, len(kvs))
+	for _, kv := range kvs {
+		raw[string(kv.Key)] = string(kv.Value)
+	}
+
+	return raw
+}
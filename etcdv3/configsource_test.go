@@ -0,0 +1,193 @@
+package etcdv3
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+type testConfig struct {
+	Name string `json:"name" yaml:"name" toml:"name"`
+	Port int    `json:"port" yaml:"port" toml:"port"`
+}
+
+func (c *testConfig) Validate() error {
+	if c.Port <= 0 {
+		return errors.New("bad port")
+	}
+
+	return nil
+}
+
+func TestConfigSource(t *testing.T) {
+	etcdCli := newTestClient(10)
+	ctx := context.Background()
+	_, err := etcdCli.DelPrefix(ctx, "/test/config/app/")
+	assert.Nil(t, err)
+	_, err = etcdCli.Put(ctx, "/test/config/app/a.yaml", "name: app\nport: 80")
+	assert.Nil(t, err)
+	_, err = etcdCli.Put(ctx, "/test/config/app/b", `{"port": 81}`)
+	assert.Nil(t, err)
+
+	var c testConfig
+	cs, err := etcdCli.NewConfigSource(ctx, "/test/config/app/", &c)
+	assert.Nil(t, err)
+	defer cs.Close()
+	assert.Equal(t, testConfig{Name: "app", Port: 81}, c)
+
+	changes := make(chan *testConfig, 10)
+	cs.Subscribe(func(old, new interface{}) {
+		changes <- new.(*testConfig)
+	})
+
+	_, err = etcdCli.Put(ctx, "/test/config/app/b", `{"port": 82}`)
+	assert.Nil(t, err)
+	assert.Equal(t, &testConfig{Name: "app", Port: 82}, waitConfig(t, changes))
+
+	// invalid configs are not applied
+	_, err = etcdCli.Put(ctx, "/test/config/app/b", `{"port": 0}`)
+	assert.Nil(t, err)
+	_, err = etcdCli.Put(ctx, "/test/config/app/c.toml", `name = "toml"`)
+	assert.Nil(t, err)
+	time.Sleep(time.Millisecond * 100)
+	assert.Equal(t, &testConfig{Name: "app", Port: 82}, cs.Value())
+
+	// roll back to the current config
+	assert.Nil(t, cs.Rollback(ctx))
+	assert.Equal(t, &testConfig{Name: "app", Port: 82}, waitLatest(t, cs, "/test/config/app/c.toml", false))
+
+	_, err = etcdCli.Put(ctx, "/test/config/app/c.toml", `name = "toml"`)
+	assert.Nil(t, err)
+	assert.Equal(t, &testConfig{Name: "toml", Port: 82}, waitConfig(t, changes))
+
+	// roll back to the previous config
+	assert.Nil(t, cs.Rollback(ctx))
+	assert.Equal(t, &testConfig{Name: "app", Port: 82}, waitConfig(t, changes))
+}
+
+func TestConfigSourceTxn(t *testing.T) {
+	etcdCli := newTestClient(10)
+	ctx := context.Background()
+	_, err := etcdCli.Put(ctx, "/test/config/txn/a.json", `{"name": "80"}`)
+	assert.Nil(t, err)
+	_, err = etcdCli.Put(ctx, "/test/config/txn/b.json", `{"port": 80}`)
+	assert.Nil(t, err)
+
+	// the name and the port must be changed together
+	var c testConfig
+	cs, err := etcdCli.NewConfigSource(ctx, "/test/config/txn/", &c, WithConfigValidator(func(v interface{}) error {
+		if c := v.(*testConfig); c.Name != strconv.Itoa(c.Port) {
+			return errors.New("inconsistent name and port")
+		}
+		return nil
+	}))
+	assert.Nil(t, err)
+	defer cs.Close()
+
+	changes := make(chan *testConfig, 10)
+	cs.Subscribe(func(old, new interface{}) {
+		changes <- new.(*testConfig)
+	})
+
+	_, err = etcdCli.Txn(ctx).Then(
+		clientv3.OpPut("/test/config/txn/a.json", `{"name": "81"}`),
+		clientv3.OpPut("/test/config/txn/b.json", `{"port": 81}`),
+	).Commit()
+	assert.Nil(t, err)
+	assert.Equal(t, &testConfig{Name: "81", Port: 81}, waitConfig(t, changes))
+
+	// rolled back in one txn too
+	assert.Nil(t, cs.Rollback(ctx))
+	assert.Equal(t, &testConfig{Name: "80", Port: 80}, waitConfig(t, changes))
+	time.Sleep(time.Millisecond * 100)
+	assert.Len(t, changes, 0)
+}
+
+func TestConfigSourceBadConfig(t *testing.T) {
+	etcdCli := newTestClient(10)
+	ctx := context.Background()
+	_, err := etcdCli.Put(ctx, "/test/config/bad/a.json", `{"port": 0}`)
+	assert.Nil(t, err)
+
+	var c testConfig
+	_, err = etcdCli.NewConfigSource(ctx, "/test/config/bad/", &c)
+	assert.NotNil(t, err)
+	_, err = etcdCli.NewConfigSource(ctx, "/test/config/bad/", c)
+	assert.NotNil(t, err)
+	_, err = etcdCli.NewConfigSource(ctx, "/test/config/bad/", &c, WithConfigValidator(func(v interface{}) error {
+		return nil
+	}))
+	assert.NotNil(t, err)
+	_, err = etcdCli.NewConfigSource(ctx, "/test/config/bad/", &c, WithConfigFormat("xml"))
+	assert.NotNil(t, err)
+}
+
+func TestConfigSourceCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	cacheFile := filepath.Join(dir, "cache.json")
+
+	etcdCli := newTestClient(10)
+	ctx := context.Background()
+	_, err = etcdCli.Put(ctx, "/test/config/cached/a.json", `{"name": "cached", "port": 80}`)
+	assert.Nil(t, err)
+
+	var c testConfig
+	cs, err := etcdCli.NewConfigSource(ctx, "/test/config/cached/", &c, WithConfigCache(cacheFile))
+	assert.Nil(t, err)
+	assert.Nil(t, cs.Close())
+	_, err = etcdCli.Put(ctx, "/test/config/cached/a.json", `{"name": "cached", "port": 81}`)
+	assert.Nil(t, err)
+
+	// etcd is unavailable, loaded from cache
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = etcdCli.NewConfigSource(canceled, "/test/config/cached/", &c)
+	assert.NotNil(t, err)
+	cs, err = etcdCli.NewConfigSource(canceled, "/test/config/cached/", &c, WithConfigCache(cacheFile))
+	assert.Nil(t, err)
+	defer cs.Close()
+	assert.Equal(t, testConfig{Name: "cached", Port: 80}, c)
+
+	// reloaded from etcd in background
+	changes := make(chan *testConfig, 10)
+	cs.Subscribe(func(old, new interface{}) {
+		changes <- new.(*testConfig)
+	})
+	assert.Equal(t, &testConfig{Name: "cached", Port: 81}, waitConfig(t, changes))
+}
+
+func waitConfig(t *testing.T, changes <-chan *testConfig) *testConfig {
+	select {
+	case c := <-changes:
+		return c
+	case <-time.After(time.Second * 5):
+		t.Fatal("config not changed")
+		return nil
+	}
+}
+
+func waitLatest(t *testing.T, cs *ConfigSource, key string, exists bool) interface{} {
+	deadline := time.Now().Add(time.Second * 5)
+	for time.Now().Before(deadline) {
+		cs.lock.RLock()
+		_, ok := cs.latest[key]
+		cs.lock.RUnlock()
+		if ok == exists {
+			return cs.Value()
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	t.Fatal("config not changed")
+	return nil
+}
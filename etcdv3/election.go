@@ -0,0 +1,215 @@
+package etcdv3
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/douyu/jupiter/pkg/xlog"
+	"go.etcd.io/etcd/client/v3/concurrency"
+)
+
+const (
+	campaignRetryInterval = time.Second
+	resignTimeout         = time.Second * 3
+)
+
+// ErrNoLeader is returned by Leader if there is no leader elected.
+var ErrNoLeader = concurrency.ErrElectionNoLeader
+
+// Election is a leader election on a prefix, the candidate that campaigns first is elected.
+// An Election campaigns once at a time, use one Election for each candidate.
+type Election struct {
+	client *Client
+	prefix string
+	opts   []concurrency.SessionOption
+	lock   sync.Mutex
+	s      *concurrency.Session
+	// e is the election that elected as leader, nil if not the leader
+	e *concurrency.Election
+}
+
+// NewElection returns an Election on prefix.
+func (client *Client) NewElection(prefix string, opts ...concurrency.SessionOption) (*Election, error) {
+	s, err := concurrency.NewSession(client.Client, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Election{
+		client: client,
+		prefix: prefix,
+		opts:   opts,
+		s:      s,
+	}, nil
+}
+
+// Campaign puts val as a candidate and waits until elected as leader.
+func (election *Election) Campaign(ctx context.Context, val string) error {
+	election.lock.Lock()
+	s, err := newSessionIfDone(election.client, election.s, election.opts)
+	if err != nil {
+		election.lock.Unlock()
+		return err
+	}
+	election.s = s
+	election.lock.Unlock()
+
+	e := concurrency.NewElection(s, election.prefix)
+	if err := e.Campaign(ctx, val); err != nil {
+		return err
+	}
+
+	election.lock.Lock()
+	election.e = e
+	election.lock.Unlock()
+	return nil
+}
+
+// Resign gives up the leadership to start a new election, does nothing if not the leader.
+func (election *Election) Resign(ctx context.Context) error {
+	election.lock.Lock()
+	e := election.e
+	election.e = nil
+	election.lock.Unlock()
+
+	if e == nil {
+		return nil
+	}
+
+	return e.Resign(ctx)
+}
+
+// IsLeader checks if it's the leader, the leadership might be lost without being notified yet.
+func (election *Election) IsLeader() bool {
+	return election.OnLost() != nil
+}
+
+// OnLost returns a channel that is closed if the session lease is lost while being the leader,
+// nil if not the leader.
+func (election *Election) OnLost() <-chan struct{} {
+	election.lock.Lock()
+	defer election.lock.Unlock()
+
+	if election.e == nil {
+		return nil
+	}
+
+	return election.s.Done()
+}
+
+// Leader returns the value of the current leader, or ErrNoLeader if no leader elected.
+func (election *Election) Leader(ctx context.Context) (string, error) {
+	election.lock.Lock()
+	s := election.s
+	election.lock.Unlock()
+
+	resp, err := concurrency.NewElection(s, election.prefix).Leader(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return string(resp.Kvs[0].Value), nil
+}
+
+// Observe returns a channel that receives the value of the leader on every change,
+// the channel is closed after ctx is done.
+func (election *Election) Observe(ctx context.Context) <-chan string {
+	election.lock.Lock()
+	s := election.s
+	election.lock.Unlock()
+
+	leaders := make(chan string)
+	go func() {
+		defer close(leaders)
+		for resp := range concurrency.NewElection(s, election.prefix).Observe(ctx) {
+			select {
+			case leaders <- string(resp.Kvs[0].Value):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return leaders
+}
+
+// RunAsLeader campaigns with val and runs fn once elected. The ctx of fn is canceled if the
+// leadership is lost, then it campaigns again. The leadership is resigned after fn returns.
+// It returns after fn returns while still the leader, or ctx is done.
+func (election *Election) RunAsLeader(ctx context.Context, val string, fn func(ctx context.Context)) error {
+	logger := election.client.config.logger.With(xlog.String("election", election.prefix),
+		xlog.FieldValue(val))
+
+	for {
+		if err := election.Campaign(ctx, val); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			logger.Error("campaign failed", xlog.FieldErr(err))
+			select {
+			case <-time.After(campaignRetryInterval):
+				continue
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		logger.Info("elected as leader")
+		if election.runAsLeader(ctx, fn) {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			logger.Warn("leadership lost, campaign again")
+			election.Resign(ctx)
+			continue
+		}
+
+		resignCtx, cancel := context.WithTimeout(context.Background(), resignTimeout)
+		if err := election.Resign(resignCtx); err != nil {
+			logger.Error("resign failed", xlog.FieldErr(err))
+		} else {
+			logger.Info("resigned leadership")
+		}
+		cancel()
+
+		return ctx.Err()
+	}
+}
+
+// Close resigns the leadership if it's the leader, and closes the session.
+func (election *Election) Close() error {
+	election.lock.Lock()
+	defer election.lock.Unlock()
+
+	election.e = nil
+	return election.s.Close()
+}
+
+// runAsLeader runs fn until it returns, returns true if the leadership is lost during running.
+func (election *Election) runAsLeader(ctx context.Context, fn func(ctx context.Context)) bool {
+	lost := election.OnLost()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-lost:
+			cancel()
+		case <-done:
+		}
+	}()
+
+	fn(ctx)
+
+	select {
+	case <-lost:
+		return true
+	default:
+		return false
+	}
+}
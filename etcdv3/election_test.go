@@ -0,0 +1,124 @@
+package etcdv3
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.etcd.io/etcd/client/v3/concurrency"
+)
+
+func TestElection(t *testing.T) {
+	etcdCli := newTestClient(10)
+	e1, err := etcdCli.NewElection("/test/election/basic", concurrency.WithTTL(10))
+	assert.Nil(t, err)
+	defer e1.Close()
+	e2, err := etcdCli.NewElection("/test/election/basic", concurrency.WithTTL(10))
+	assert.Nil(t, err)
+	defer e2.Close()
+
+	_, err = e1.Leader(context.Background())
+	assert.Equal(t, ErrNoLeader, err)
+
+	assert.Nil(t, e1.Campaign(context.Background(), "a"))
+	assert.True(t, e1.IsLeader())
+	leader, err := e2.Leader(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, "a", leader)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*200)
+	assert.Equal(t, context.DeadlineExceeded, e2.Campaign(ctx, "b"))
+	cancel()
+	assert.False(t, e2.IsLeader())
+
+	elected := make(chan error)
+	go func() {
+		elected <- e2.Campaign(context.Background(), "b")
+	}()
+	assert.Nil(t, e1.Resign(context.Background()))
+	assert.False(t, e1.IsLeader())
+	assert.Nil(t, <-elected)
+	leader, err = e1.Leader(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, "b", leader)
+	assert.Nil(t, e2.Resign(context.Background()))
+}
+
+func TestElectionObserve(t *testing.T) {
+	etcdCli := newTestClient(10)
+	e1, err := etcdCli.NewElection("/test/election/observe", concurrency.WithTTL(10))
+	assert.Nil(t, err)
+	defer e1.Close()
+	e2, err := etcdCli.NewElection("/test/election/observe", concurrency.WithTTL(10))
+	assert.Nil(t, err)
+	defer e2.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	leaders := e1.Observe(ctx)
+	assert.Nil(t, e1.Campaign(context.Background(), "a"))
+	assert.Equal(t, "a", <-leaders)
+
+	go e2.Campaign(context.Background(), "b")
+	assert.Nil(t, e1.Resign(context.Background()))
+	assert.Equal(t, "b", <-leaders)
+
+	cancel()
+	for range leaders {
+	}
+	assert.Nil(t, e2.Resign(context.Background()))
+}
+
+func TestRunAsLeader(t *testing.T) {
+	etcdCli := newTestClient(10)
+	election, err := etcdCli.NewElection("/test/election/run", concurrency.WithTTL(10))
+	assert.Nil(t, err)
+	defer election.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	stopped := make(chan struct{})
+	result := make(chan error)
+	go func() {
+		result <- election.RunAsLeader(ctx, "a", func(ctx context.Context) {
+			started <- struct{}{}
+			<-ctx.Done()
+			stopped <- struct{}{}
+		})
+	}()
+
+	<-started
+	election.lock.Lock()
+	lease := election.s.Lease()
+	election.lock.Unlock()
+	_, err = etcdCli.Revoke(context.Background(), lease)
+	assert.Nil(t, err)
+	select {
+	case <-stopped:
+	case <-time.After(time.Second * 5):
+		t.Fatal("fn not canceled on leadership lost")
+	}
+
+	// elected again with a new session
+	<-started
+	assert.True(t, election.IsLeader())
+	cancel()
+	<-stopped
+	assert.Equal(t, context.Canceled, <-result)
+	assert.False(t, election.IsLeader())
+}
+
+func TestRunAsLeaderReturns(t *testing.T) {
+	etcdCli := newTestClient(10)
+	election, err := etcdCli.NewElection("/test/election/return", concurrency.WithTTL(10))
+	assert.Nil(t, err)
+	defer election.Close()
+
+	var ran bool
+	assert.Nil(t, election.RunAsLeader(context.Background(), "a", func(ctx context.Context) {
+		ran = true
+	}))
+	assert.True(t, ran)
+	_, err = election.Leader(context.Background())
+	assert.Equal(t, ErrNoLeader, err)
+}
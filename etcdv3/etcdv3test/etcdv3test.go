@@ -0,0 +1,50 @@
+// Package etcdv3test runs an embedded etcd server for the tests against etcd.
+package etcdv3test
+
+import (
+	"io/ioutil"
+	"net/url"
+	"os"
+
+	"go.etcd.io/etcd/server/v3/embed"
+)
+
+// Start starts an embedded etcd server on a random port, returns the endpoints of it,
+// and the function to stop it and remove its data.
+func Start() ([]string, func(), error) {
+	dir, err := ioutil.TempDir("", "etcdv3test")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cfg := embed.NewConfig()
+	cfg.LogLevel = "error"
+	cfg.Dir = dir
+	u, _ := url.Parse("http://127.0.0.1:0")
+	cfg.LCUrls = []url.URL{*u}
+	cfg.LPUrls = []url.URL{*u}
+	server, err := embed.StartEtcd(cfg)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, nil, err
+	}
+	<-server.Server.ReadyNotify()
+
+	return []string{server.Clients[0].Addr().String()}, func() {
+		server.Close()
+		os.RemoveAll(dir)
+	}, nil
+}
+
+// Run starts an embedded etcd server, calls run with the endpoints of it, and exits
+// with the code that run returns, for TestMain to run the tests of a package.
+func Run(run func(endpoints []string) int) {
+	endpoints, stop, err := Start()
+	if err != nil {
+		panic(err)
+	}
+
+	code := run(endpoints)
+	stop()
+	os.Exit(code)
+}
@@ -0,0 +1,215 @@
+package etcdv3
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/douyu/jupiter/pkg/xlog"
+	"github.com/tx991020/utils/collection"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+const (
+	defaultInvalidationTTL = time.Second * 10
+	invalidateKeyKind      = "/key/"
+	invalidatePrefixKind   = "/prefix/"
+)
+
+type (
+	// InvalidatorOption customizes an Invalidator.
+	InvalidatorOption func(inv *Invalidator)
+
+	// Invalidator invalidates the entries of the local caches on all the instances,
+	// the invalidations are published under a prefix with short leases, and delivered at least once.
+	Invalidator struct {
+		client *Client
+		prefix string
+		ttl    time.Duration
+		watch  *Watch
+		lock   sync.Mutex
+		caches []*collection.Cache
+		// lease is shared by the invalidations published in ttl after it's granted,
+		// then a new one is granted, instead of a lease per invalidation
+		lease        clientv3.LeaseID
+		leaseGranted time.Time
+		leaseLock    sync.Mutex
+		// applied is the revisions of the applied invalidations, to skip the redelivered ones
+		applied map[string]int64
+		done    chan struct{}
+		once    sync.Once
+	}
+)
+
+// WithInvalidationTTL keeps the invalidations in etcd for ttl to 2*ttl, the instances that are
+// disconnected for longer than ttl might miss them.
+func WithInvalidationTTL(ttl time.Duration) InvalidatorOption {
+	return func(inv *Invalidator) {
+		inv.ttl = ttl
+	}
+}
+
+// NewInvalidator returns an Invalidator that publishes and watches the invalidations under prefix.
+func (client *Client) NewInvalidator(ctx context.Context, prefix string,
+	opts ...InvalidatorOption) (*Invalidator, error) {
+	inv := &Invalidator{
+		client:  client,
+		prefix:  prefix,
+		ttl:     defaultInvalidationTTL,
+		applied: make(map[string]int64),
+		done:    make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(inv)
+	}
+
+	w, err := client.WatchPrefix(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	// the invalidations before start are not for the caches, which are empty
+	for _, kv := range w.IncipientKeyValues() {
+		inv.applied[string(kv.Key)] = kv.ModRevision
+	}
+	inv.watch = w
+	go inv.run()
+
+	return inv, nil
+}
+
+// AddCache adds cache to be invalidated.
+func (inv *Invalidator) AddCache(cache *collection.Cache) {
+	inv.lock.Lock()
+	inv.caches = append(inv.caches, cache)
+	inv.lock.Unlock()
+}
+
+// Close stops watching the invalidations.
+func (inv *Invalidator) Close() error {
+	inv.once.Do(func() {
+		close(inv.done)
+	})
+	return inv.watch.Close()
+}
+
+// Invalidate deletes key from the caches on all the instances, the local caches first.
+func (inv *Invalidator) Invalidate(ctx context.Context, key string) error {
+	return inv.publish(ctx, invalidateKeyKind, key)
+}
+
+// InvalidatePrefix deletes the keys with prefix from the caches on all the instances,
+// the local caches first.
+func (inv *Invalidator) InvalidatePrefix(ctx context.Context, prefix string) error {
+	return inv.publish(ctx, invalidatePrefixKind, prefix)
+}
+
+func (inv *Invalidator) apply(kind, key string) {
+	inv.lock.Lock()
+	caches := inv.caches
+	inv.lock.Unlock()
+
+	for _, cache := range caches {
+		if kind == invalidatePrefixKind {
+			cache.DelPrefix(key)
+		} else {
+			cache.Del(key)
+		}
+	}
+}
+
+func (inv *Invalidator) handle(ev *clientv3.Event) {
+	key := string(ev.Kv.Key)
+	if ev.Type == clientv3.EventTypeDelete {
+		// expired, the later invalidations on key have greater revisions anyway
+		inv.lock.Lock()
+		delete(inv.applied, key)
+		inv.lock.Unlock()
+		return
+	}
+
+	inv.lock.Lock()
+	if ev.Kv.ModRevision <= inv.applied[key] {
+		inv.lock.Unlock()
+		return
+	}
+	inv.applied[key] = ev.Kv.ModRevision
+	inv.lock.Unlock()
+
+	name := strings.TrimPrefix(key, inv.prefix)
+	switch {
+	case strings.HasPrefix(name, invalidateKeyKind):
+		inv.apply(invalidateKeyKind, strings.TrimPrefix(name, invalidateKeyKind))
+	case strings.HasPrefix(name, invalidatePrefixKind):
+		inv.apply(invalidatePrefixKind, strings.TrimPrefix(name, invalidatePrefixKind))
+	default:
+		inv.client.config.logger.Error("bad invalidation", xlog.FieldKey(key))
+	}
+}
+
+func (inv *Invalidator) publish(ctx context.Context, kind, key string) error {
+	inv.apply(kind, key)
+
+	lease, err := inv.grantLease(ctx)
+	if err != nil {
+		return err
+	}
+
+	// every put is a new revision, even on the same key, so the instances are notified every time
+	_, err = inv.client.Put(ctx, inv.prefix+kind+key, key, clientv3.WithLease(lease))
+	if err != rpctypes.ErrLeaseNotFound {
+		return err
+	}
+
+	// the lease is lost, like etcd is restored from a snapshot, publish with a new one
+	inv.resetLease(lease)
+	if lease, err = inv.grantLease(ctx); err != nil {
+		return err
+	}
+	_, err = inv.client.Put(ctx, inv.prefix+kind+key, key, clientv3.WithLease(lease))
+	return err
+}
+
+// grantLease returns the shared lease, a new one is granted if it's granted more than ttl ago.
+// The lease lives for 2*ttl, so the invalidations put in the last moment live for ttl as well.
+func (inv *Invalidator) grantLease(ctx context.Context) (clientv3.LeaseID, error) {
+	inv.leaseLock.Lock()
+	defer inv.leaseLock.Unlock()
+
+	if inv.lease != clientv3.NoLease && time.Since(inv.leaseGranted) < inv.ttl {
+		return inv.lease, nil
+	}
+
+	resp, err := inv.client.Grant(ctx, leaseSeconds(inv.ttl*2))
+	if err != nil {
+		return clientv3.NoLease, err
+	}
+	inv.lease = resp.ID
+	inv.leaseGranted = time.Now()
+
+	return resp.ID, nil
+}
+
+func (inv *Invalidator) resetLease(lease clientv3.LeaseID) {
+	inv.leaseLock.Lock()
+	if inv.lease == lease {
+		inv.lease = clientv3.NoLease
+	}
+	inv.leaseLock.Unlock()
+}
+
+func (inv *Invalidator) run() {
+	for {
+		select {
+		case ev, ok := <-inv.watch.C():
+			if !ok {
+				return
+			}
+			inv.handle(ev)
+		case <-inv.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package etcdv3
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tx991020/utils/collection"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+func TestInvalidator(t *testing.T) {
+	etcdCli := newTestClient(10)
+	ctx := context.Background()
+	etcdCli.DelPrefix(ctx, "/test/invalidations")
+
+	local, err := collection.NewCache(time.Minute)
+	assert.Nil(t, err)
+	remote, err := collection.NewCache(time.Minute)
+	assert.Nil(t, err)
+	publisher, err := etcdCli.NewInvalidator(ctx, "/test/invalidations")
+	assert.Nil(t, err)
+	defer publisher.Close()
+	publisher.AddCache(local)
+	subscriber, err := etcdCli.NewInvalidator(ctx, "/test/invalidations", WithInvalidationTTL(time.Second))
+	assert.Nil(t, err)
+	defer subscriber.Close()
+	subscriber.AddCache(remote)
+
+	for _, cache := range []*collection.Cache{local, remote} {
+		cache.Set("user:1", 1)
+		cache.Set("user:2", 2)
+		cache.Set("order:1", 1)
+	}
+
+	assert.Nil(t, publisher.Invalidate(ctx, "user:1"))
+	_, ok := local.Get("user:1")
+	assert.False(t, ok, "local caches are invalidated at once")
+	assert.Eventually(t, func() bool {
+		_, ok := remote.Get("user:1")
+		return !ok
+	}, time.Second*3, time.Millisecond*10)
+
+	assert.Nil(t, publisher.InvalidatePrefix(ctx, "user:"))
+	assert.Eventually(t, func() bool {
+		_, ok := remote.Get("user:2")
+		return !ok
+	}, time.Second*3, time.Millisecond*10)
+	_, ok = remote.Get("order:1")
+	assert.True(t, ok)
+
+	// invalidated again on the same key
+	remote.Set("user:1", 1)
+	assert.Nil(t, publisher.Invalidate(ctx, "user:1"))
+	assert.Eventually(t, func() bool {
+		_, ok := remote.Get("user:1")
+		return !ok
+	}, time.Second*3, time.Millisecond*10)
+}
+
+func TestInvalidatorDedup(t *testing.T) {
+	etcdCli := newTestClient(10)
+	inv, err := etcdCli.NewInvalidator(context.Background(), "/test/dedup")
+	assert.Nil(t, err)
+	defer inv.Close()
+	cache, err := collection.NewCache(time.Minute)
+	assert.Nil(t, err)
+	inv.AddCache(cache)
+
+	ev := &clientv3.Event{
+		Type: clientv3.EventTypePut,
+		Kv: &mvccpb.KeyValue{
+			Key:         []byte("/test/dedup/key/a"),
+			ModRevision: 100,
+		},
+	}
+	cache.Set("a", 1)
+	inv.handle(ev)
+	_, ok := cache.Get("a")
+	assert.False(t, ok)
+
+	// redelivered, like on relisting after compaction
+	cache.Set("a", 1)
+	inv.handle(ev)
+	_, ok = cache.Get("a")
+	assert.True(t, ok)
+
+	ev.Kv.ModRevision++
+	inv.handle(ev)
+	_, ok = cache.Get("a")
+	assert.False(t, ok)
+}
+
+func TestInvalidatorSharedLease(t *testing.T) {
+	etcdCli := newTestClient(10)
+	ctx := context.Background()
+	inv, err := etcdCli.NewInvalidator(ctx, "/test/lease", WithInvalidationTTL(time.Second))
+	assert.Nil(t, err)
+	defer inv.Close()
+
+	leaseOf := func(key string) int64 {
+		kv, err := etcdCli.GetKeyValue(ctx, "/test/lease"+invalidateKeyKind+key)
+		assert.Nil(t, err)
+		if kv == nil {
+			return 0
+		}
+		return kv.Lease
+	}
+
+	assert.Nil(t, inv.Invalidate(ctx, "a"))
+	assert.Nil(t, inv.Invalidate(ctx, "b"))
+	lease := leaseOf("a")
+	assert.NotZero(t, lease)
+	assert.Equal(t, lease, leaseOf("b"), "the invalidations share the lease")
+
+	// published with a new lease if the lease is lost
+	_, err = etcdCli.Revoke(ctx, clientv3.LeaseID(lease))
+	assert.Nil(t, err)
+	assert.Nil(t, inv.Invalidate(ctx, "c"))
+	assert.NotEqual(t, lease, leaseOf("c"))
+
+	// a new lease is granted after ttl
+	lease = leaseOf("c")
+	time.Sleep(time.Second + time.Millisecond*100)
+	assert.Nil(t, inv.Invalidate(ctx, "d"))
+	assert.NotEqual(t, lease, leaseOf("d"))
+	assert.NotZero(t, leaseOf("c"), "the invalidations live for ttl at least")
+}
@@ -16,48 +16,466 @@ package etcdv3
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sync"
 	"time"
 
-	"github.com/coreos/etcd/clientv3/concurrency"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
 )
 
-// Mutex ...
-type Mutex struct {
-	s *concurrency.Session
-	m *concurrency.Mutex
-}
+// defaultHolder is the holder of Lock, TryLock and Unlock.
+const defaultHolder = ""
+
+var (
+	// ErrLocked is returned by TryLock if the lock is held by others.
+	ErrLocked = errors.New("etcdv3: locked by another holder")
+	// ErrNotLocked is returned on unlocking a lock that is not held by the holder.
+	ErrNotLocked = errors.New("etcdv3: not locked by the holder")
+	// ErrLockLost is returned on unlocking a lock whose session ended before.
+	ErrLockLost = errors.New("etcdv3: lock lost")
+)
+
+type (
+	// Mutex is a distributed lock, reentrant for the same holder.
+	// The keys are compatible with concurrency.Mutex.
+	Mutex struct {
+		client *Client
+		key    string
+		opts   []concurrency.SessionOption
+		// sem is held from acquiring until the last unlock
+		sem    chan struct{}
+		lock   sync.Mutex
+		s      *concurrency.Session
+		h      *holding
+		holder string
+		count  int
+	}
+
+	// RWMutex is a distributed readers–writer lock, writers are not starved by readers.
+	// A RWMutex is held once at a time, use one RWMutex for each concurrent holder.
+	RWMutex struct {
+		client *Client
+		key    string
+		opts   []concurrency.SessionOption
+		sem    chan struct{}
+		lock   sync.Mutex
+		s      *concurrency.Session
+		h      *holding
+	}
+
+	// holding is an acquired lock key, lost is closed if the session ends before released.
+	holding struct {
+		key  string
+		rev  int64
+		lost chan struct{}
+		done chan struct{}
+	}
+)
 
 // NewMutex ...
 func (client *Client) NewMutex(key string, opts ...concurrency.SessionOption) (mutex *Mutex, err error) {
-	mutex = &Mutex{}
+	mutex = &Mutex{
+		client: client,
+		key:    key,
+		opts:   opts,
+		sem:    make(chan struct{}, 1),
+	}
 	// 默认session ttl = 60s
 	mutex.s, err = concurrency.NewSession(client.Client, opts...)
-	if err != nil {
-		return
-	}
-	mutex.m = concurrency.NewMutex(mutex.s, key)
 	return
 }
 
 // Lock ...
 func (mutex *Mutex) Lock(timeout time.Duration) (err error) {
+	return mutex.LockAs(defaultHolder, timeout)
+}
+
+// TryLock locks the mutex, or returns ErrLocked if it's held by others.
+func (mutex *Mutex) TryLock(timeout time.Duration) (err error) {
+	return mutex.TryLockAs(defaultHolder, timeout)
+}
+
+// Unlock ...
+func (mutex *Mutex) Unlock() (err error) {
+	return mutex.UnlockAs(defaultHolder)
+}
+
+// LockAs locks the mutex for holder, the holder of the mutex locks it again without waiting,
+// and it's released after unlocked as many times.
+func (mutex *Mutex) LockAs(holder string, timeout time.Duration) error {
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
-	return mutex.m.Lock(ctx)
+	return mutex.acquire(ctx, holder, false)
 }
 
-// TryLock ...
-func (mutex *Mutex) TryLock(timeout time.Duration) (err error) {
+// TryLockAs is like LockAs, but returns ErrLocked instead of waiting if it's held by others.
+func (mutex *Mutex) TryLockAs(holder string, timeout time.Duration) error {
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
-	return mutex.m.Lock(ctx)
+	return mutex.acquire(ctx, holder, true)
 }
 
-// Unlock ...
-func (mutex *Mutex) Unlock() (err error) {
-	err = mutex.m.Unlock(context.TODO())
+// UnlockAs unlocks the mutex once for holder.
+func (mutex *Mutex) UnlockAs(holder string) error {
+	mutex.lock.Lock()
+	if mutex.count == 0 || mutex.holder != holder {
+		mutex.lock.Unlock()
+		return ErrNotLocked
+	}
+	mutex.count--
+	if mutex.count > 0 {
+		mutex.lock.Unlock()
+		return nil
+	}
+	s, h := mutex.s, mutex.h
+	mutex.s, mutex.h = nil, nil
+	mutex.lock.Unlock()
+
+	defer func() {
+		<-mutex.sem
+	}()
+	return h.release(s)
+}
+
+// Token returns the fencing token of the holding, which is the revision that the lock is
+// acquired with, it increases on every acquiring, 0 if not locked.
+func (mutex *Mutex) Token() int64 {
+	mutex.lock.Lock()
+	defer mutex.lock.Unlock()
+	return mutex.h.token()
+}
+
+// OnLost returns a channel that is closed if the session lease is lost before unlocked,
+// nil if not locked.
+func (mutex *Mutex) OnLost() <-chan struct{} {
+	mutex.lock.Lock()
+	defer mutex.lock.Unlock()
+	return mutex.h.onLost()
+}
+
+// IsOwner returns the comparison that the mutex is still held, to guard transactions with.
+func (mutex *Mutex) IsOwner() clientv3.Cmp {
+	mutex.lock.Lock()
+	defer mutex.lock.Unlock()
+	return mutex.h.isOwner()
+}
+
+func (mutex *Mutex) acquire(ctx context.Context, holder string, try bool) error {
+	mutex.lock.Lock()
+	if mutex.count > 0 && mutex.holder == holder {
+		mutex.count++
+		mutex.lock.Unlock()
+		return nil
+	}
+	mutex.lock.Unlock()
+
+	if try {
+		select {
+		case mutex.sem <- struct{}{}:
+		default:
+			return ErrLocked
+		}
+	} else {
+		select {
+		case mutex.sem <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	// the session is only touched by the one that holds sem
+	s, err := newSessionIfDone(mutex.client, mutex.s, mutex.opts)
+	if err != nil {
+		<-mutex.sem
+		return err
+	}
+
+	pfx := mutex.key + "/"
+	h, err := acquire(ctx, s, fmt.Sprintf("%s%x", pfx, s.Lease()), pfx, try)
+	mutex.lock.Lock()
+	defer mutex.lock.Unlock()
+	mutex.s = s
 	if err != nil {
-		return
+		<-mutex.sem
+		return err
+	}
+
+	mutex.h = h
+	mutex.holder = holder
+	mutex.count = 1
+	return nil
+}
+
+// NewRWMutex returns a RWMutex on key.
+func (client *Client) NewRWMutex(key string, opts ...concurrency.SessionOption) (*RWMutex, error) {
+	s, err := concurrency.NewSession(client.Client, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RWMutex{
+		client: client,
+		key:    key,
+		opts:   opts,
+		sem:    make(chan struct{}, 1),
+		s:      s,
+	}, nil
+}
+
+// RLock locks rw for reading, waits for the writers that come before.
+func (rw *RWMutex) RLock(timeout time.Duration) error {
+	return rw.acquire(timeout, "read", rw.key+"/write/")
+}
+
+// Lock locks rw for writing, waits for all the readers and writers that come before.
+func (rw *RWMutex) Lock(timeout time.Duration) error {
+	return rw.acquire(timeout, "write", rw.key+"/")
+}
+
+// RUnlock undoes a single RLock call.
+func (rw *RWMutex) RUnlock() error {
+	return rw.release()
+}
+
+// Unlock unlocks rw for writing.
+func (rw *RWMutex) Unlock() error {
+	return rw.release()
+}
+
+// Token returns the fencing token of the holding, 0 if not locked.
+func (rw *RWMutex) Token() int64 {
+	rw.lock.Lock()
+	defer rw.lock.Unlock()
+	return rw.h.token()
+}
+
+// OnLost returns a channel that is closed if the session lease is lost before unlocked,
+// nil if not locked.
+func (rw *RWMutex) OnLost() <-chan struct{} {
+	rw.lock.Lock()
+	defer rw.lock.Unlock()
+	return rw.h.onLost()
+}
+
+// IsOwner returns the comparison that rw is still held, to guard transactions with.
+func (rw *RWMutex) IsOwner() clientv3.Cmp {
+	rw.lock.Lock()
+	defer rw.lock.Unlock()
+	return rw.h.isOwner()
+}
+
+func (rw *RWMutex) acquire(timeout time.Duration, kind, waitPrefix string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	select {
+	case rw.sem <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	s, err := newSessionIfDone(rw.client, rw.s, rw.opts)
+	if err != nil {
+		<-rw.sem
+		return err
+	}
+
+	h, err := acquire(ctx, s, fmt.Sprintf("%s/%s/%x", rw.key, kind, s.Lease()), waitPrefix, false)
+	rw.lock.Lock()
+	defer rw.lock.Unlock()
+	rw.s = s
+	if err != nil {
+		<-rw.sem
+		return err
+	}
+
+	rw.h = h
+	return nil
+}
+
+func (rw *RWMutex) release() error {
+	rw.lock.Lock()
+	if rw.h == nil {
+		rw.lock.Unlock()
+		return ErrNotLocked
+	}
+	s, h := rw.s, rw.h
+	rw.s, rw.h = nil, nil
+	rw.lock.Unlock()
+
+	defer func() {
+		<-rw.sem
+	}()
+	return h.release(s)
+}
+
+func (h *holding) isOwner() clientv3.Cmp {
+	if h == nil {
+		return clientv3.Compare(clientv3.CreateRevision("\x00"), "=", -1)
+	}
+
+	return clientv3.Compare(clientv3.CreateRevision(h.key), "=", h.rev)
+}
+
+func (h *holding) onLost() <-chan struct{} {
+	if h == nil {
+		return nil
+	}
+
+	return h.lost
+}
+
+// release deletes the key and closes the session.
+func (h *holding) release(s *concurrency.Session) error {
+	close(h.done)
+	select {
+	case <-h.lost:
+		s.Close()
+		return ErrLockLost
+	default:
+	}
+
+	_, err := s.Client().Delete(context.TODO(), h.key)
+	if cerr := s.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func (h *holding) token() int64 {
+	if h == nil {
+		return 0
+	}
+
+	return h.rev
+}
+
+func (h *holding) watch(s *concurrency.Session) {
+	select {
+	case <-s.Done():
+		select {
+		case <-h.done:
+		default:
+			close(h.lost)
+		}
+	case <-h.done:
+	}
+}
+
+// acquire puts key with the lease of s, and waits for the keys under waitPrefix that are
+// created before key to be deleted, or returns ErrLocked if try.
+func acquire(ctx context.Context, s *concurrency.Session, key, waitPrefix string, try bool) (*holding, error) {
+	client := s.Client()
+	rev, err := enqueue(ctx, client, key, s.Lease())
+	if err == nil {
+		if try {
+			var last *mvccpb.KeyValue
+			if last, err = lastBefore(ctx, client, waitPrefix, rev); err == nil && last != nil {
+				err = ErrLocked
+			}
+		} else {
+			err = waitDeletes(ctx, client, waitPrefix, rev)
+		}
+	}
+	if err != nil {
+		// the put might be done even if enqueue failed on timeout,
+		// the key is deleted along with the lease anyway.
+		client.Delete(client.Ctx(), key)
+		return nil, err
+	}
+
+	h := &holding{
+		key:  key,
+		rev:  rev,
+		lost: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go h.watch(s)
+	return h, nil
+}
+
+// enqueue puts key if absent, returns its create revision.
+func enqueue(ctx context.Context, client *clientv3.Client, key string, lease clientv3.LeaseID) (int64, error) {
+	resp, err := client.Txn(ctx).
+		If(clientv3.Compare(clientv3.CreateRevision(key), "=", 0)).
+		Then(clientv3.OpPut(key, "", clientv3.WithLease(lease))).
+		Else(clientv3.OpGet(key)).
+		Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	if resp.Succeeded {
+		return resp.Header.Revision, nil
+	}
+
+	return resp.Responses[0].GetResponseRange().Kvs[0].CreateRevision, nil
+}
+
+// lastBefore returns the last created key under prefix before rev, nil if none.
+func lastBefore(ctx context.Context, client *clientv3.Client, prefix string, rev int64) (*mvccpb.KeyValue, error) {
+	opts := append(clientv3.WithLastCreate(), clientv3.WithMaxCreateRev(rev-1))
+	resp, err := client.Get(ctx, prefix, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.Kvs) == 0 {
+		return nil, nil
+	}
+
+	return resp.Kvs[0], nil
+}
+
+// newSessionIfDone returns s if it's alive, otherwise a new session.
+func newSessionIfDone(client *Client, s *concurrency.Session, opts []concurrency.SessionOption) (
+	*concurrency.Session, error) {
+	if s != nil {
+		select {
+		case <-s.Done():
+		default:
+			return s, nil
+		}
+	}
+
+	return concurrency.NewSession(client.Client, opts...)
+}
+
+// waitDelete waits for key to be deleted after rev.
+func waitDelete(ctx context.Context, client *clientv3.Client, key string, rev int64) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	for resp := range client.Watch(ctx, key, clientv3.WithRev(rev+1)) {
+		if err := resp.Err(); err != nil {
+			return err
+		}
+		for _, ev := range resp.Events {
+			if ev.Type == mvccpb.DELETE {
+				return nil
+			}
+		}
+	}
+
+	return ctx.Err()
+}
+
+// waitDeletes waits for all the keys under prefix that are created before rev to be deleted.
+func waitDeletes(ctx context.Context, client *clientv3.Client, prefix string, rev int64) error {
+	for {
+		last, err := lastBefore(ctx, client, prefix, rev)
+		if err != nil {
+			return err
+		}
+		if last == nil {
+			return nil
+		}
+
+		if err = waitDelete(ctx, client, string(last.Key), last.ModRevision); err != nil {
+			return err
+		}
 	}
-	return mutex.s.Close()
 }
@@ -0,0 +1,145 @@
+package etcdv3
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
+)
+
+func TestMutexToken(t *testing.T) {
+	etcdCli := newTestClient(10)
+	mutex, err := etcdCli.NewMutex("/test/lock/token", concurrency.WithTTL(10))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), mutex.Token())
+	assert.Nil(t, mutex.OnLost())
+
+	assert.Nil(t, mutex.Lock(time.Second))
+	first := mutex.Token()
+	assert.True(t, first > 0)
+	assert.Nil(t, mutex.Unlock())
+	assert.Equal(t, int64(0), mutex.Token())
+
+	assert.Nil(t, mutex.Lock(time.Second))
+	assert.True(t, mutex.Token() > first)
+
+	// the token guards the writes of the holder
+	resp, err := etcdCli.Txn(context.Background()).If(mutex.IsOwner()).
+		Then(clientv3.OpPut("/test/guarded", "v")).Commit()
+	assert.Nil(t, err)
+	assert.True(t, resp.Succeeded)
+	assert.Nil(t, mutex.Unlock())
+	assert.Equal(t, ErrNotLocked, mutex.Unlock())
+}
+
+func TestMutexTryLock(t *testing.T) {
+	etcdCli := newTestClient(10)
+	mutex1, err := etcdCli.NewMutex("/test/lock/try", concurrency.WithTTL(10))
+	assert.Nil(t, err)
+	mutex2, err := etcdCli.NewMutex("/test/lock/try", concurrency.WithTTL(10))
+	assert.Nil(t, err)
+
+	assert.Nil(t, mutex1.TryLock(time.Second))
+	start := time.Now()
+	assert.Equal(t, ErrLocked, mutex2.TryLock(time.Second))
+	assert.True(t, time.Since(start) < time.Second)
+	assert.Nil(t, mutex1.Unlock())
+	assert.Nil(t, mutex2.TryLock(time.Second))
+	assert.Nil(t, mutex2.Unlock())
+}
+
+func TestMutexReentrant(t *testing.T) {
+	etcdCli := newTestClient(10)
+	mutex, err := etcdCli.NewMutex("/test/lock/reentrant", concurrency.WithTTL(10))
+	assert.Nil(t, err)
+	other, err := etcdCli.NewMutex("/test/lock/reentrant", concurrency.WithTTL(10))
+	assert.Nil(t, err)
+
+	assert.Nil(t, mutex.LockAs("a", time.Second))
+	token := mutex.Token()
+	assert.Nil(t, mutex.LockAs("a", time.Second))
+	assert.Equal(t, token, mutex.Token())
+	assert.Equal(t, ErrLocked, mutex.TryLockAs("b", time.Second))
+	assert.NotNil(t, mutex.LockAs("b", time.Millisecond*100))
+	assert.Equal(t, ErrNotLocked, mutex.UnlockAs("b"))
+	assert.Equal(t, ErrLocked, other.TryLockAs("a", time.Second))
+
+	assert.Nil(t, mutex.UnlockAs("a"))
+	assert.Equal(t, ErrLocked, other.TryLock(time.Second))
+	assert.Nil(t, mutex.UnlockAs("a"))
+	assert.Nil(t, other.TryLock(time.Second))
+	assert.Nil(t, other.Unlock())
+}
+
+func TestMutexWaits(t *testing.T) {
+	etcdCli := newTestClient(10)
+	mutex1, err := etcdCli.NewMutex("/test/lock/wait", concurrency.WithTTL(10))
+	assert.Nil(t, err)
+	mutex2, err := etcdCli.NewMutex("/test/lock/wait", concurrency.WithTTL(10))
+	assert.Nil(t, err)
+
+	assert.Nil(t, mutex1.Lock(time.Second))
+	token := mutex1.Token()
+	go func() {
+		time.Sleep(time.Millisecond * 200)
+		mutex1.Unlock()
+	}()
+	assert.Nil(t, mutex2.Lock(time.Second*3))
+	assert.True(t, mutex2.Token() > token)
+	assert.Nil(t, mutex2.Unlock())
+}
+
+func TestMutexOnLost(t *testing.T) {
+	etcdCli := newTestClient(10)
+	mutex, err := etcdCli.NewMutex("/test/lock/lost", concurrency.WithTTL(10))
+	assert.Nil(t, err)
+	assert.Nil(t, mutex.Lock(time.Second))
+
+	_, err = etcdCli.Revoke(context.Background(), mutex.s.Lease())
+	assert.Nil(t, err)
+	select {
+	case <-mutex.OnLost():
+	case <-time.After(time.Second * 5):
+		t.Fatal("lost not notified")
+	}
+	assert.Equal(t, ErrLockLost, mutex.Unlock())
+
+	// a new session is started for the next locking
+	assert.Nil(t, mutex.Lock(time.Second))
+	select {
+	case <-mutex.OnLost():
+		t.Fatal("unexpected lost")
+	default:
+	}
+	assert.Nil(t, mutex.Unlock())
+}
+
+func TestRWMutex(t *testing.T) {
+	etcdCli := newTestClient(10)
+	reader1, err := etcdCli.NewRWMutex("/test/rwlock", concurrency.WithTTL(10))
+	assert.Nil(t, err)
+	reader2, err := etcdCli.NewRWMutex("/test/rwlock", concurrency.WithTTL(10))
+	assert.Nil(t, err)
+	writer, err := etcdCli.NewRWMutex("/test/rwlock", concurrency.WithTTL(10))
+	assert.Nil(t, err)
+
+	assert.Nil(t, reader1.RLock(time.Second))
+	assert.Nil(t, reader2.RLock(time.Second))
+	assert.NotNil(t, writer.Lock(time.Millisecond*200))
+	assert.Nil(t, reader1.RUnlock())
+	assert.Nil(t, reader2.RUnlock())
+
+	assert.Nil(t, writer.Lock(time.Second))
+	assert.True(t, writer.Token() > 0)
+	assert.NotNil(t, reader1.RLock(time.Millisecond*200))
+	go func() {
+		time.Sleep(time.Millisecond * 200)
+		writer.Unlock()
+	}()
+	assert.Nil(t, reader1.RLock(time.Second*3))
+	assert.Nil(t, reader1.RUnlock())
+	assert.Equal(t, ErrNotLocked, reader1.RUnlock())
+}
@@ -0,0 +1,29 @@
+package etcdv3
+
+import (
+	"testing"
+
+	"github.com/tx991020/utils/etcdv3/etcdv3test"
+)
+
+// testEndpoints are the endpoints of the embedded etcd server that the tests run against.
+var testEndpoints []string
+
+func TestMain(m *testing.M) {
+	etcdv3test.Run(func(endpoints []string) int {
+		testEndpoints = endpoints
+		return m.Run()
+	})
+}
+
+func newTestClient(ttl int) *Client {
+	config := DefaultConfig()
+	config.Endpoints = testEndpoints
+	config.TTL = ttl
+	client, err := NewClient(config)
+	if err != nil {
+		panic(err)
+	}
+
+	return client
+}
@@ -0,0 +1,34 @@
+package etcdv3
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	metricWatchEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "etcd",
+		Subsystem: "watch",
+		Name:      "events_total",
+		Help:      "The events received by the watches, by prefix and event type.",
+	}, []string{"prefix", "type"})
+	metricWatchErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "etcd",
+		Subsystem: "watch",
+		Name:      "errors_total",
+		Help:      "The errors of the watches, by prefix and compacted, no_leader or other.",
+	}, []string{"prefix", "kind"})
+	metricWatchReconnects = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "etcd",
+		Subsystem: "watch",
+		Name:      "reconnects_total",
+		Help:      "The reconnects of the watches after the watch channels are closed.",
+	}, []string{"prefix"})
+	metricWatchRelists = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "etcd",
+		Subsystem: "watch",
+		Name:      "relists_total",
+		Help:      "The relists of the watches after the revisions are compacted.",
+	}, []string{"prefix"})
+)
+
+func init() {
+	prometheus.MustRegister(metricWatchEvents, metricWatchErrors, metricWatchReconnects, metricWatchRelists)
+}
@@ -0,0 +1,113 @@
+package etcdv3
+
+import (
+	"sync"
+
+	"github.com/tx991020/utils/hash"
+)
+
+type (
+	// A Picker picks an instance for a request, the key is used by the pickers that pick by key.
+	Picker interface {
+		Pick(key string) (Instance, bool)
+	}
+
+	hashPicker struct {
+		h         *hash.ConsistentHash
+		instances map[string]Instance
+	}
+
+	// weightedPicker is the smooth weighted round-robin picker of nginx.
+	weightedPicker struct {
+		lock  sync.Mutex
+		nodes []*weightedNode
+	}
+
+	weightedNode struct {
+		inst    Instance
+		weight  int
+		current int
+	}
+)
+
+// NewHashPicker returns a Picker that picks the instances by the consistent hash of the keys,
+// the instances own the parts of the ring by their weights.
+func NewHashPicker(instances []Instance) Picker {
+	p := &hashPicker{
+		h:         hash.NewConsistentHash(),
+		instances: make(map[string]Instance, len(instances)),
+	}
+	for _, inst := range instances {
+		p.h.AddWithWeight(inst.Addr, inst.weight())
+		p.instances[inst.Addr] = inst
+	}
+
+	return p
+}
+
+func (p *hashPicker) Pick(key string) (Instance, bool) {
+	addr, ok := p.h.Get(key)
+	if !ok {
+		return Instance{}, false
+	}
+
+	return p.instances[addr.(string)], true
+}
+
+// NewWeightedPicker returns a Picker that picks the instances in the weighted round-robin way,
+// the keys are ignored.
+func NewWeightedPicker(instances []Instance) Picker {
+	p := new(weightedPicker)
+	for _, inst := range instances {
+		p.nodes = append(p.nodes, &weightedNode{
+			inst:   inst,
+			weight: inst.weight(),
+		})
+	}
+
+	return p
+}
+
+func (p *weightedPicker) Pick(_ string) (Instance, bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	var best *weightedNode
+	var total int
+	for _, node := range p.nodes {
+		node.current += node.weight
+		total += node.weight
+		if best == nil || node.current > best.current {
+			best = node
+		}
+	}
+	if best == nil {
+		return Instance{}, false
+	}
+
+	best.current -= total
+	return best.inst, true
+}
+
+// PreferZone returns the instances in zone, or all the instances if none is in zone.
+func PreferZone(instances []Instance, zone string) []Instance {
+	var local []Instance
+	for _, inst := range instances {
+		if inst.Zone == zone {
+			local = append(local, inst)
+		}
+	}
+	if len(local) == 0 {
+		return instances
+	}
+
+	return local
+}
+
+func (inst Instance) weight() int {
+	if inst.Weight <= 0 || inst.Weight > hash.TopWeight {
+		return hash.TopWeight
+	}
+
+	return inst.Weight
+}
@@ -0,0 +1,66 @@
+package etcdv3
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashPicker(t *testing.T) {
+	_, ok := NewHashPicker(nil).Pick("a")
+	assert.False(t, ok)
+
+	instances := []Instance{
+		{Name: "svc", Addr: "10.0.0.1:8080"},
+		{Name: "svc", Addr: "10.0.0.2:8080", Weight: 50},
+		{Name: "svc", Addr: "10.0.0.3:8080"},
+	}
+	picker := NewHashPicker(instances)
+	counts := make(map[string]int)
+	for i := 0; i < 1000; i++ {
+		inst, ok := picker.Pick(string(rune('a' + i%26)))
+		assert.True(t, ok)
+		counts[inst.Addr]++
+	}
+	// the same key goes to the same instance
+	first, _ := picker.Pick("key")
+	for i := 0; i < 10; i++ {
+		inst, _ := picker.Pick("key")
+		assert.Equal(t, first, inst)
+	}
+	assert.True(t, len(counts) > 1)
+}
+
+func TestWeightedPicker(t *testing.T) {
+	_, ok := NewWeightedPicker(nil).Pick("")
+	assert.False(t, ok)
+
+	picker := NewWeightedPicker([]Instance{
+		{Addr: "a", Weight: 50},
+		{Addr: "b", Weight: 20},
+		{Addr: "c", Weight: 30},
+	})
+	counts := make(map[string]int)
+	var seq []string
+	for i := 0; i < 100; i++ {
+		inst, ok := picker.Pick("")
+		assert.True(t, ok)
+		counts[inst.Addr]++
+		if i < 10 {
+			seq = append(seq, inst.Addr)
+		}
+	}
+	assert.Equal(t, map[string]int{"a": 50, "b": 20, "c": 30}, counts)
+	// smooth, a is not picked in a row all the time
+	assert.Equal(t, []string{"a", "c", "b", "a", "a", "c", "a", "b", "c", "a"}, seq)
+}
+
+func TestPreferZone(t *testing.T) {
+	instances := []Instance{
+		{Addr: "a", Zone: "z1"},
+		{Addr: "b", Zone: "z2"},
+		{Addr: "c", Zone: "z1"},
+	}
+	assert.Equal(t, []Instance{instances[0], instances[2]}, PreferZone(instances, "z1"))
+	assert.Equal(t, instances, PreferZone(instances, "z3"))
+}
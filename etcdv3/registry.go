@@ -0,0 +1,269 @@
+package etcdv3
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/douyu/jupiter/pkg/xlog"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
+)
+
+const (
+	defaultRegisterTTL = 10
+	registerTimeout    = time.Second * 3
+	registerRetryDelay = time.Second
+)
+
+type (
+	// Instance is an instance of a service, it's registered under prefix/name/addr.
+	Instance struct {
+		Name string `json:"name"`
+		Addr string `json:"addr"`
+		// Weight is the weight of the instance, 1 to 100, 0 means 100.
+		Weight   int               `json:"weight,omitempty"`
+		Zone     string            `json:"zone,omitempty"`
+		Metadata map[string]string `json:"metadata,omitempty"`
+	}
+
+	// Registration is a registered instance, which is kept registered until deregistered.
+	Registration struct {
+		client *Client
+		key    string
+		value  string
+		ttl    int
+		lock   sync.Mutex
+		s      *concurrency.Session
+		done   chan struct{}
+		closed bool
+	}
+
+	// Discovery keeps the instances of a service up to date.
+	Discovery struct {
+		client    *Client
+		watch     *Watch
+		lock      sync.RWMutex
+		instances map[string]Instance
+		listeners []func(instances []Instance)
+		done      chan struct{}
+		once      sync.Once
+	}
+)
+
+// Register registers inst under prefix with a lease of ttl seconds that is kept alive,
+// it's registered again if the lease is lost, until deregistered.
+func (client *Client) Register(prefix string, inst Instance, ttl int) (*Registration, error) {
+	value, err := json.Marshal(inst)
+	if err != nil {
+		return nil, err
+	}
+
+	if ttl <= 0 {
+		ttl = defaultRegisterTTL
+	}
+	r := &Registration{
+		client: client,
+		key:    InstanceKey(prefix, inst.Name, inst.Addr),
+		value:  string(value),
+		ttl:    ttl,
+		done:   make(chan struct{}),
+	}
+	if err := r.register(); err != nil {
+		return nil, err
+	}
+
+	go r.keepRegistered()
+	return r, nil
+}
+
+// Deregister deletes the instance and stops keeping it registered.
+func (r *Registration) Deregister(ctx context.Context) error {
+	r.lock.Lock()
+	if r.closed {
+		r.lock.Unlock()
+		return nil
+	}
+	r.closed = true
+	close(r.done)
+	s := r.s
+	r.lock.Unlock()
+
+	_, err := r.client.Delete(ctx, r.key)
+	// revoking the lease deletes the key as well
+	if cerr := s.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Key returns the key that the instance is registered with.
+func (r *Registration) Key() string {
+	return r.key
+}
+
+func (r *Registration) keepRegistered() {
+	logger := r.client.config.logger.With(xlog.FieldKey(r.key))
+
+	for {
+		r.lock.Lock()
+		s := r.s
+		r.lock.Unlock()
+
+		select {
+		case <-r.done:
+			return
+		case <-s.Done():
+		}
+
+		logger.Warn("registration lease lost, register again")
+		for {
+			err := r.register()
+			if err == nil {
+				break
+			}
+
+			logger.Error("register failed", xlog.FieldErr(err))
+			select {
+			case <-time.After(registerRetryDelay):
+			case <-r.done:
+				return
+			}
+		}
+	}
+}
+
+func (r *Registration) register() error {
+	s, err := concurrency.NewSession(r.client.Client, concurrency.WithTTL(r.ttl))
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), registerTimeout)
+	defer cancel()
+	if _, err = r.client.Put(ctx, r.key, r.value, clientv3.WithLease(s.Lease())); err != nil {
+		s.Close()
+		return err
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.closed {
+		// deregistered during registering
+		return s.Close()
+	}
+	r.s = s
+	return nil
+}
+
+// Discover returns a Discovery of the instances of name registered under prefix.
+func (client *Client) Discover(ctx context.Context, prefix, name string) (*Discovery, error) {
+	w, err := client.WatchPrefix(ctx, InstanceKey(prefix, name, ""))
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Discovery{
+		client:    client,
+		watch:     w,
+		instances: make(map[string]Instance),
+		done:      make(chan struct{}),
+	}
+	for _, kv := range w.IncipientKeyValues() {
+		d.put(kv)
+	}
+	go d.run()
+
+	return d, nil
+}
+
+// AddListener adds fn to be called with the instances on every change.
+func (d *Discovery) AddListener(fn func(instances []Instance)) {
+	d.lock.Lock()
+	d.listeners = append(d.listeners, fn)
+	d.lock.Unlock()
+}
+
+// Close stops watching the changes.
+func (d *Discovery) Close() error {
+	d.once.Do(func() {
+		close(d.done)
+	})
+	return d.watch.Close()
+}
+
+// Instances returns the instances sorted by addr.
+func (d *Discovery) Instances() []Instance {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+	return d.snapshot()
+}
+
+func (d *Discovery) handle(ev *clientv3.Event) {
+	switch ev.Type {
+	case clientv3.EventTypePut:
+		if !d.put(ev.Kv) {
+			return
+		}
+	case clientv3.EventTypeDelete:
+		d.lock.Lock()
+		delete(d.instances, string(ev.Kv.Key))
+		d.lock.Unlock()
+	}
+
+	d.lock.RLock()
+	instances := d.snapshot()
+	listeners := d.listeners
+	d.lock.RUnlock()
+	for _, listener := range listeners {
+		listener(instances)
+	}
+}
+
+func (d *Discovery) put(kv *mvccpb.KeyValue) bool {
+	var inst Instance
+	if err := json.Unmarshal(kv.Value, &inst); err != nil {
+		d.client.config.logger.Error("bad instance", xlog.FieldKey(string(kv.Key)), xlog.FieldErr(err))
+		return false
+	}
+
+	d.lock.Lock()
+	d.instances[string(kv.Key)] = inst
+	d.lock.Unlock()
+	return true
+}
+
+func (d *Discovery) run() {
+	for {
+		select {
+		case ev, ok := <-d.watch.C():
+			if !ok {
+				return
+			}
+			d.handle(ev)
+		case <-d.done:
+			return
+		}
+	}
+}
+
+func (d *Discovery) snapshot() []Instance {
+	instances := make([]Instance, 0, len(d.instances))
+	for _, inst := range d.instances {
+		instances = append(instances, inst)
+	}
+	sort.Slice(instances, func(i, j int) bool {
+		return instances[i].Addr < instances[j].Addr
+	})
+	return instances
+}
+
+// InstanceKey returns the key of the instance of name on addr registered under prefix,
+// an empty addr gives the prefix of the instances of name.
+func InstanceKey(prefix, name, addr string) string {
+	return fmt.Sprintf("%s/%s/%s", prefix, name, addr)
+}
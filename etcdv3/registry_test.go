@@ -0,0 +1,84 @@
+package etcdv3
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistryDiscover(t *testing.T) {
+	etcdCli := newTestClient(10)
+	d, err := etcdCli.Discover(context.Background(), "/test/services", "svc")
+	assert.Nil(t, err)
+	defer d.Close()
+	assert.Empty(t, d.Instances())
+
+	changes := make(chan []Instance, 10)
+	d.AddListener(func(instances []Instance) {
+		changes <- instances
+	})
+
+	r1, err := etcdCli.Register("/test/services", Instance{Name: "svc", Addr: "10.0.0.1:80", Zone: "z1"}, 5)
+	assert.Nil(t, err)
+	assert.Equal(t, "/test/services/svc/10.0.0.1:80", r1.Key())
+	r2, err := etcdCli.Register("/test/services", Instance{Name: "svc", Addr: "10.0.0.2:80", Weight: 10}, 5)
+	assert.Nil(t, err)
+	other, err := etcdCli.Register("/test/services", Instance{Name: "other", Addr: "10.0.0.3:80"}, 5)
+	assert.Nil(t, err)
+	defer other.Deregister(context.Background())
+
+	assert.Equal(t, []Instance{
+		{Name: "svc", Addr: "10.0.0.1:80", Zone: "z1"},
+		{Name: "svc", Addr: "10.0.0.2:80", Weight: 10},
+	}, waitInstances(changes, 2))
+
+	assert.Nil(t, r1.Deregister(context.Background()))
+	assert.Nil(t, r1.Deregister(context.Background()))
+	assert.Equal(t, []Instance{{Name: "svc", Addr: "10.0.0.2:80", Weight: 10}}, waitInstances(changes, 1))
+	assert.Equal(t, []Instance{{Name: "svc", Addr: "10.0.0.2:80", Weight: 10}}, d.Instances())
+
+	// discovered on start
+	d2, err := etcdCli.Discover(context.Background(), "/test/services", "svc")
+	assert.Nil(t, err)
+	defer d2.Close()
+	assert.Equal(t, d.Instances(), d2.Instances())
+	assert.Nil(t, r2.Deregister(context.Background()))
+}
+
+func TestRegistrationLeaseLost(t *testing.T) {
+	etcdCli := newTestClient(10)
+	r, err := etcdCli.Register("/test/services", Instance{Name: "lost", Addr: "10.0.0.1:80"}, 5)
+	assert.Nil(t, err)
+	defer r.Deregister(context.Background())
+
+	r.lock.Lock()
+	lease := r.s.Lease()
+	r.lock.Unlock()
+	_, err = etcdCli.Revoke(context.Background(), lease)
+	assert.Nil(t, err)
+
+	deadline := time.Now().Add(time.Second * 5)
+	for {
+		kv, err := etcdCli.GetKeyValue(context.Background(), r.Key())
+		assert.Nil(t, err)
+		if kv != nil && kv.Lease != int64(lease) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("not registered again")
+		}
+		time.Sleep(time.Millisecond * 50)
+	}
+}
+
+func waitInstances(changes <-chan []Instance, n int) []Instance {
+	for instances := range changes {
+		if len(instances) == n {
+			return instances
+		}
+	}
+
+	return nil
+}
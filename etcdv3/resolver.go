@@ -0,0 +1,100 @@
+package etcdv3
+
+import (
+	"context"
+	"sync"
+
+	"github.com/douyu/jupiter/pkg/xlog"
+	"google.golang.org/grpc/attributes"
+	"google.golang.org/grpc/resolver"
+)
+
+// ResolverScheme is the scheme of the targets resolved by the etcd resolver, like etcd:///name.
+const ResolverScheme = "etcd"
+
+type (
+	instanceAttributeKey struct{}
+
+	resolverBuilder struct {
+		client *Client
+		prefix string
+	}
+
+	etcdResolver struct {
+		d    *Discovery
+		cc   resolver.ClientConn
+		lock sync.Mutex
+	}
+)
+
+// NewResolverBuilder returns a grpc resolver.Builder that resolves the targets like etcd:///name
+// to the instances of name registered under prefix, register it with resolver.Register.
+// The instances are kept in the address attributes, see InstanceOf.
+func NewResolverBuilder(client *Client, prefix string) resolver.Builder {
+	return &resolverBuilder{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+// InstanceOf returns the instance of the resolved address.
+func InstanceOf(addr resolver.Address) (Instance, bool) {
+	if addr.Attributes == nil {
+		return Instance{}, false
+	}
+
+	inst, ok := addr.Attributes.Value(instanceAttributeKey{}).(Instance)
+	return inst, ok
+}
+
+func (b *resolverBuilder) Build(target resolver.Target, cc resolver.ClientConn,
+	_ resolver.BuildOptions) (resolver.Resolver, error) {
+	d, err := b.client.Discover(context.Background(), b.prefix, target.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &etcdResolver{
+		d:  d,
+		cc: cc,
+	}
+	d.AddListener(func([]Instance) {
+		r.update()
+	})
+	r.update()
+
+	return r, nil
+}
+
+func (b *resolverBuilder) Scheme() string {
+	return ResolverScheme
+}
+
+func (r *etcdResolver) Close() {
+	r.d.Close()
+}
+
+// ResolveNow does nothing, the addresses are updated on changes.
+func (r *etcdResolver) ResolveNow(resolver.ResolveNowOptions) {
+}
+
+// update updates the addresses with the latest instances, in case the updates are out of order.
+func (r *etcdResolver) update() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	instances := r.d.Instances()
+	addrs := make([]resolver.Address, 0, len(instances))
+	for _, inst := range instances {
+		addrs = append(addrs, resolver.Address{
+			Addr:       inst.Addr,
+			Attributes: attributes.New(instanceAttributeKey{}, inst),
+		})
+	}
+
+	if err := r.cc.UpdateState(resolver.State{
+		Addresses: addrs,
+	}); err != nil {
+		r.d.client.config.logger.Error("update resolver state failed", xlog.FieldErr(err))
+	}
+}
@@ -0,0 +1,51 @@
+package etcdv3
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/resolver"
+)
+
+type mockClientConn struct {
+	resolver.ClientConn
+	states chan resolver.State
+}
+
+func (cc *mockClientConn) UpdateState(state resolver.State) error {
+	cc.states <- state
+	return nil
+}
+
+func TestResolver(t *testing.T) {
+	etcdCli := newTestClient(10)
+	r, err := etcdCli.Register("/test/resolver", Instance{Name: "svc", Addr: "10.0.0.1:80", Zone: "z1"}, 5)
+	assert.Nil(t, err)
+
+	builder := NewResolverBuilder(etcdCli, "/test/resolver")
+	assert.Equal(t, ResolverScheme, builder.Scheme())
+	cc := &mockClientConn{
+		states: make(chan resolver.State, 10),
+	}
+	res, err := builder.Build(resolver.Target{Scheme: ResolverScheme, Endpoint: "svc"}, cc,
+		resolver.BuildOptions{})
+	assert.Nil(t, err)
+	defer res.Close()
+
+	state := <-cc.states
+	assert.Len(t, state.Addresses, 1)
+	assert.Equal(t, "10.0.0.1:80", state.Addresses[0].Addr)
+	inst, ok := InstanceOf(state.Addresses[0])
+	assert.True(t, ok)
+	assert.Equal(t, "z1", inst.Zone)
+	_, ok = InstanceOf(resolver.Address{Addr: "10.0.0.1:80"})
+	assert.False(t, ok)
+
+	assert.Nil(t, r.Deregister(context.Background()))
+	for state = range cc.states {
+		if len(state.Addresses) == 0 {
+			break
+		}
+	}
+}
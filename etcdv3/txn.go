@@ -0,0 +1,191 @@
+package etcdv3
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/tx991020/utils/fx"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+const (
+	updateRetries      = 10
+	updateBackoffBase  = time.Millisecond * 10
+	updateBackoffMax   = time.Millisecond * 500
+	defaultPageSize    = 1000
+	minLeaseTTLSeconds = 1
+)
+
+// ErrTooManyConflicts is returned by AtomicUpdate if the key is changed by others on every retry.
+var ErrTooManyConflicts = errors.New("etcdv3: too many conflicts on updating")
+
+// errConflict is returned by an update attempt if the key is changed by others.
+var errConflict = errors.New("etcdv3: key changed by others")
+
+// PutIfAbsent puts value on key if key doesn't exist, returns false if it exists.
+func (client *Client) PutIfAbsent(ctx context.Context, key, value string, opts ...clientv3.OpOption) (bool, error) {
+	return client.CompareAndSwapRevision(ctx, key, 0, value, opts...)
+}
+
+// CompareAndSwap puts value on key if the current value is old, returns false if not.
+func (client *Client) CompareAndSwap(ctx context.Context, key, old, value string,
+	opts ...clientv3.OpOption) (bool, error) {
+	resp, err := client.Txn(ctx).
+		If(clientv3.Compare(clientv3.Value(key), "=", old)).
+		Then(clientv3.OpPut(key, value, opts...)).
+		Commit()
+	if err != nil {
+		return false, err
+	}
+
+	return resp.Succeeded, nil
+}
+
+// CompareAndSwapRevision puts value on key if the mod revision of key is rev, 0 means not exists,
+// returns false if not.
+func (client *Client) CompareAndSwapRevision(ctx context.Context, key string, rev int64, value string,
+	opts ...clientv3.OpOption) (bool, error) {
+	resp, err := client.Txn(ctx).
+		If(clientv3.Compare(clientv3.ModRevision(key), "=", rev)).
+		Then(clientv3.OpPut(key, value, opts...)).
+		Commit()
+	if err != nil {
+		return false, err
+	}
+
+	return resp.Succeeded, nil
+}
+
+// AtomicUpdate puts the value that fn returns with the current value of key, exists is false
+// if key doesn't exist. If key is changed by others in between, it's retried with backoff,
+// and ErrTooManyConflicts is returned after the retries are used up. The errors of fn are returned
+// without retrying, and ctx.Err() is returned if ctx is done before updated. It returns the updated value.
+func (client *Client) AtomicUpdate(ctx context.Context, key string,
+	fn func(old string, exists bool) (string, error)) (string, error) {
+	var value string
+	var err error
+	retryErr := fx.DoWithRetries(func() error {
+		value, err = client.tryUpdate(ctx, key, fn)
+		return err
+	}, fx.WithRetries(updateRetries), fx.WithRetryContext(ctx),
+		fx.WithBackoff(fx.DecorrelatedJitterBackoff(updateBackoffBase, updateBackoffMax)),
+		fx.WithRetryable(func(err error) bool {
+			return err == errConflict
+		}))
+	if retryErr == nil {
+		return value, nil
+	}
+
+	// no attempt made if err is nil, like ctx is done before the first one
+	if err != nil && err != errConflict {
+		return "", err
+	}
+	if ctx.Err() != nil {
+		return "", ctx.Err()
+	}
+	if err == errConflict {
+		return "", ErrTooManyConflicts
+	}
+
+	return "", retryErr
+}
+
+// GetPrefixPaged calls fn with the key values under prefix in pages of at most pageSize,
+// sorted by keys. All the pages are read at the same revision.
+func (client *Client) GetPrefixPaged(ctx context.Context, prefix string, pageSize int64,
+	fn func(kvs []*mvccpb.KeyValue) error) error {
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+
+	end := clientv3.GetPrefixRangeEnd(prefix)
+	key := prefix
+	var rev int64
+	for {
+		opts := []clientv3.OpOption{
+			clientv3.WithRange(end),
+			clientv3.WithLimit(pageSize),
+			clientv3.WithSort(clientv3.SortByKey, clientv3.SortAscend),
+		}
+		if rev > 0 {
+			opts = append(opts, clientv3.WithRev(rev))
+		}
+		resp, err := client.Get(ctx, key, opts...)
+		if err != nil {
+			return err
+		}
+		if rev == 0 {
+			rev = resp.Header.Revision
+		}
+
+		if len(resp.Kvs) > 0 {
+			if err := fn(resp.Kvs); err != nil {
+				return err
+			}
+		}
+		if !resp.More || len(resp.Kvs) == 0 {
+			return nil
+		}
+
+		// the smallest key after the last one
+		key = string(resp.Kvs[len(resp.Kvs)-1].Key) + "\x00"
+	}
+}
+
+// PutWithTTL puts value on key with a new lease of ttl, which is at least a second.
+// The lease is revoked if the put fails, the key is deleted after ttl unless put again.
+func (client *Client) PutWithTTL(ctx context.Context, key, value string, ttl time.Duration) (
+	clientv3.LeaseID, error) {
+	lease, err := client.Grant(ctx, leaseSeconds(ttl))
+	if err != nil {
+		return clientv3.NoLease, err
+	}
+
+	if _, err = client.Put(ctx, key, value, clientv3.WithLease(lease.ID)); err != nil {
+		client.Revoke(client.Ctx(), lease.ID)
+		return clientv3.NoLease, err
+	}
+
+	return lease.ID, nil
+}
+
+func leaseSeconds(ttl time.Duration) int64 {
+	seconds := int64(math.Ceil(ttl.Seconds()))
+	if seconds < minLeaseTTLSeconds {
+		seconds = minLeaseTTLSeconds
+	}
+
+	return seconds
+}
+
+func (client *Client) tryUpdate(ctx context.Context, key string,
+	fn func(old string, exists bool) (string, error)) (string, error) {
+	resp, err := client.Get(ctx, key)
+	if err != nil {
+		return "", err
+	}
+
+	var old string
+	var rev int64
+	if len(resp.Kvs) > 0 {
+		old = string(resp.Kvs[0].Value)
+		rev = resp.Kvs[0].ModRevision
+	}
+	value, err := fn(old, rev > 0)
+	if err != nil {
+		return "", err
+	}
+
+	ok, err := client.CompareAndSwapRevision(ctx, key, rev, value)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", errConflict
+	}
+
+	return value, nil
+}
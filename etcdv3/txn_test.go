@@ -0,0 +1,175 @@
+package etcdv3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+)
+
+func TestPutIfAbsent(t *testing.T) {
+	etcdCli := newTestClient(10)
+	ctx := context.Background()
+	etcdCli.DelPrefix(ctx, "/test/txn/absent")
+
+	ok, err := etcdCli.PutIfAbsent(ctx, "/test/txn/absent", "a")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	ok, err = etcdCli.PutIfAbsent(ctx, "/test/txn/absent", "b")
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	kv, err := etcdCli.GetKeyValue(ctx, "/test/txn/absent")
+	assert.Nil(t, err)
+	assert.Equal(t, "a", string(kv.Value))
+}
+
+func TestCompareAndSwap(t *testing.T) {
+	etcdCli := newTestClient(10)
+	ctx := context.Background()
+	_, err := etcdCli.Put(ctx, "/test/txn/cas", "a")
+	assert.Nil(t, err)
+
+	ok, err := etcdCli.CompareAndSwap(ctx, "/test/txn/cas", "b", "c")
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	ok, err = etcdCli.CompareAndSwap(ctx, "/test/txn/cas", "a", "b")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	kv, err := etcdCli.GetKeyValue(ctx, "/test/txn/cas")
+	assert.Nil(t, err)
+	assert.Equal(t, "b", string(kv.Value))
+
+	ok, err = etcdCli.CompareAndSwapRevision(ctx, "/test/txn/cas", kv.ModRevision-1, "c")
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	ok, err = etcdCli.CompareAndSwapRevision(ctx, "/test/txn/cas", kv.ModRevision, "c")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+}
+
+func TestAtomicUpdate(t *testing.T) {
+	etcdCli := newTestClient(10)
+	ctx := context.Background()
+	etcdCli.DelPrefix(ctx, "/test/txn/counter")
+
+	const total = 10
+	var wg sync.WaitGroup
+	for i := 0; i < total; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := etcdCli.AtomicUpdate(ctx, "/test/txn/counter", func(old string, exists bool) (string, error) {
+				if !exists {
+					return "1", nil
+				}
+				n, err := strconv.Atoi(old)
+				return strconv.Itoa(n + 1), err
+			})
+			assert.Nil(t, err)
+		}()
+	}
+	wg.Wait()
+
+	kv, err := etcdCli.GetKeyValue(ctx, "/test/txn/counter")
+	assert.Nil(t, err)
+	assert.Equal(t, strconv.Itoa(total), string(kv.Value))
+
+	errDummy := errors.New("dummy")
+	_, err = etcdCli.AtomicUpdate(ctx, "/test/txn/counter", func(string, bool) (string, error) {
+		return "", errDummy
+	})
+	assert.Equal(t, errDummy, err)
+}
+
+func TestAtomicUpdateConflicts(t *testing.T) {
+	etcdCli := newTestClient(10)
+	ctx := context.Background()
+
+	var i int
+	_, err := etcdCli.AtomicUpdate(ctx, "/test/txn/conflict", func(string, bool) (string, error) {
+		// always change the key in between
+		i++
+		_, err := etcdCli.Put(ctx, "/test/txn/conflict", strconv.Itoa(i))
+		return "x", err
+	})
+	assert.Equal(t, ErrTooManyConflicts, err)
+	assert.Equal(t, updateRetries, i)
+}
+
+func TestAtomicUpdateCanceled(t *testing.T) {
+	etcdCli := newTestClient(10)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var called bool
+	value, err := etcdCli.AtomicUpdate(ctx, "/test/txn/canceled", func(string, bool) (string, error) {
+		called = true
+		return "x", nil
+	})
+	assert.Equal(t, context.Canceled, err)
+	assert.Empty(t, value)
+	assert.False(t, called)
+}
+
+func TestGetPrefixPaged(t *testing.T) {
+	etcdCli := newTestClient(10)
+	ctx := context.Background()
+	etcdCli.DelPrefix(ctx, "/test/txn/paged/")
+	for i := 0; i < 7; i++ {
+		_, err := etcdCli.Put(ctx, fmt.Sprintf("/test/txn/paged/%d", i), strconv.Itoa(i))
+		assert.Nil(t, err)
+	}
+	_, err := etcdCli.Put(ctx, "/test/txn/pagedx", "x")
+	assert.Nil(t, err)
+
+	var pages [][]string
+	err = etcdCli.GetPrefixPaged(ctx, "/test/txn/paged/", 3, func(kvs []*mvccpb.KeyValue) error {
+		var page []string
+		for _, kv := range kvs {
+			page = append(page, string(kv.Value))
+		}
+		pages = append(pages, page)
+		// not seen by the later pages, which are read at the same revision
+		_, err := etcdCli.Put(ctx, "/test/txn/paged/9", "9")
+		return err
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{{"0", "1", "2"}, {"3", "4", "5"}, {"6"}}, pages)
+
+	errDummy := errors.New("dummy")
+	err = etcdCli.GetPrefixPaged(ctx, "/test/txn/paged/", 0, func([]*mvccpb.KeyValue) error {
+		return errDummy
+	})
+	assert.Equal(t, errDummy, err)
+}
+
+func TestPutWithTTL(t *testing.T) {
+	etcdCli := newTestClient(10)
+	ctx := context.Background()
+
+	lease, err := etcdCli.PutWithTTL(ctx, "/test/txn/ttl", "a", time.Millisecond*2500)
+	assert.Nil(t, err)
+	resp, err := etcdCli.TimeToLive(ctx, lease)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), resp.GrantedTTL)
+
+	kv, err := etcdCli.GetKeyValue(ctx, "/test/txn/ttl")
+	assert.Nil(t, err)
+	assert.Equal(t, "a", string(kv.Value))
+	assert.Equal(t, int64(lease), kv.Lease)
+
+	// the server might raise the ttl to its minimum
+	lease, err = etcdCli.PutWithTTL(ctx, "/test/txn/ttl", "b", 0)
+	assert.Nil(t, err)
+	resp, err = etcdCli.TimeToLive(ctx, lease)
+	assert.Nil(t, err)
+	assert.True(t, resp.GrantedTTL >= minLeaseTTLSeconds)
+}
@@ -16,180 +16,297 @@ package etcdv3
 
 import (
 	"context"
+	"fmt"
 	"sync"
+	"time"
 
-	"github.com/coreos/etcd/clientv3"
-	"github.com/coreos/etcd/mvcc/mvccpb"
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
+	"github.com/douyu/jupiter/pkg/ecode"
+	"github.com/douyu/jupiter/pkg/util/xgo"
+	"github.com/douyu/jupiter/pkg/xlog"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
 
+const (
+	watchRetryDelay = time.Second
+	errChanSize     = 10
+)
 
+// WatchError is an error occurred in watching, the watch recovers from it by itself.
+type WatchError struct {
+	Prefix string
+	// Revision is the revision that the watch resumes from.
+	Revision int64
+	Err      error
+}
 
+func (e *WatchError) Error() string {
+	return fmt.Sprintf("watch %s from revision %d: %v", e.Prefix, e.Revision, e.Err)
+}
 
-	
-	"github.com/douyu/jupiter/pkg/util/xgo"
-	"github.com/douyu/jupiter/pkg/xlog"
-)
+// Unwrap returns the underlying error, like rpctypes.ErrCompacted.
+func (e *WatchError) Unwrap() error {
+	return e.Err
+}
 
 // Watch A watch only tells the latest revision
 type Watch struct {
+	client    *Client
+	prefix    string
 	revision  int64
+	ctx       context.Context
 	cancel    context.CancelFunc
 	eventChan chan *clientv3.Event
-	lock      *sync.RWMutex
-	logger    *xlog.Logger
+	batchChan chan []*clientv3.Event
+	// flattenOnce starts flattening the batches into eventChan on the first call of C
+	flattenOnce sync.Once
+	errChan     chan *WatchError
+	lock        *sync.RWMutex
+	logger      *xlog.Logger
+	// kvs is the known state of the keys, to diff with on relisting after compaction
+	kvs map[string]*mvccpb.KeyValue
 
 	incipientKVs []*mvccpb.KeyValue
 }
 
-// C ...
+// C returns the channel of the events, which is closed after the watch is closed.
+// Use either C or Batches on a watch, not both.
 func (w *Watch) C() chan *clientv3.Event {
+	w.flattenOnce.Do(func() {
+		xgo.Go(w.flatten)
+	})
 	return w.eventChan
 }
 
+// Batches returns the channel of the events in batches, which is closed after the watch is closed.
+// A batch is the events of a watch response, or the differences found on relisting,
+// so the state after a batch is the state of etcd at a revision.
+// Use either C or Batches on a watch, not both.
+func (w *Watch) Batches() <-chan []*clientv3.Event {
+	return w.batchChan
+}
+
+// Errors returns the channel of the errors occurred in watching, the errors are dropped
+// if not received in time.
+func (w *Watch) Errors() <-chan *WatchError {
+	return w.errChan
+}
+
 // IncipientKeyValues incipient key and values
 func (w *Watch) IncipientKeyValues() []*mvccpb.KeyValue {
 	return w.incipientKVs
 }
 
-// NewWatch ...
+// Revision returns the revision that the events are received up to.
+func (w *Watch) Revision() int64 {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+	return w.revision
+}
+
+// WatchPrefix lists the keys under prefix, and watches the changes after that.
+// On disconnection, the watch resumes from the last revision, and if the revision is
+// compacted, the keys are listed again and the differences are sent as synthetic events.
 func (client *Client) WatchPrefix(ctx context.Context, prefix string) (*Watch, error) {
 	resp, err := client.Get(ctx, prefix, clientv3.WithPrefix())
 	if err != nil {
 		return nil, err
 	}
 
+	w := client.newWatch(prefix, resp)
+	xgo.Go(w.run)
+
+	return w, nil
+}
+
+func (client *Client) newWatch(prefix string, resp *clientv3.GetResponse) *Watch {
+	ctx, cancel := context.WithCancel(context.Background())
 	var w = &Watch{
+		client:       client,
+		prefix:       prefix,
 		revision:     resp.Header.Revision,
+		ctx:          ctx,
+		cancel:       cancel,
 		eventChan:    make(chan *clientv3.Event, 100),
+		batchChan:    make(chan []*clientv3.Event, 100),
+		errChan:      make(chan *WatchError, errChanSize),
+		lock:         new(sync.RWMutex),
+		logger:       client.config.logger.With(xlog.FieldAddr(prefix)),
+		kvs:          make(map[string]*mvccpb.KeyValue, len(resp.Kvs)),
 		incipientKVs: resp.Kvs,
 	}
+	for _, kv := range resp.Kvs {
+		w.kvs[string(kv.Key)] = kv
+	}
+
+	return w
+}
+
+// Close close watch
+func (w *Watch) Close() error {
+	w.cancel()
+	return nil
+}
+
+func (w *Watch) fail(err error) {
+	metricWatchErrors.WithLabelValues(w.prefix, errorKind(err)).Inc()
+	w.logger.Error(ecode.MsgWatchRequestErr, xlog.FieldErrKind(ecode.ErrKindRegisterErr), xlog.FieldErr(err))
+
+	select {
+	case w.errChan <- &WatchError{
+		Prefix:   w.prefix,
+		Revision: w.Revision(),
+		Err:      err,
+	}:
+	default:
+	}
+}
+
+// relist lists the keys again, and sends the differences from the known state as events.
+func (w *Watch) relist() error {
+	resp, err := w.client.Get(w.ctx, w.prefix, clientv3.WithPrefix())
+	if err != nil {
+		return err
+	}
+
+	metricWatchRelists.WithLabelValues(w.prefix).Inc()
+	listed := make(map[string]*mvccpb.KeyValue, len(resp.Kvs))
+	var events []*clientv3.Event
+	for _, kv := range resp.Kvs {
+		key := string(kv.Key)
+		listed[key] = kv
+		prev, ok := w.kvs[key]
+		if !ok || prev.ModRevision != kv.ModRevision {
+			events = append(events, &clientv3.Event{
+				Type:   mvccpb.PUT,
+				Kv:     kv,
+				PrevKv: prev,
+			})
+		}
+	}
+	for key, prev := range w.kvs {
+		if _, ok := listed[key]; !ok {
+			events = append(events, &clientv3.Event{
+				Type: mvccpb.DELETE,
+				Kv: &mvccpb.KeyValue{
+					Key:         prev.Key,
+					ModRevision: resp.Header.Revision,
+				},
+				PrevKv: prev,
+			})
+		}
+	}
 
-	xgo.Go(func() {
-		ctx, cancel := context.WithCancel(context.Background())
-		w.cancel = cancel
-		rch := client.Client.Watch(ctx, prefix, clientv3.WithPrefix(), clientv3.WithCreatedNotify(), clientv3.WithRev(w.revision))
-		for {
-			for n := range rch {
-				if n.CompactRevision > w.revision {
-					w.revision = n.CompactRevision
-				}
-				if n.Header.GetRevision() > w.revision {
-					w.revision = n.Header.GetRevision()
-				}
-				if err := n.Err(); err != nil {
-					xlog.Error(ecode.MsgWatchRequestErr, xlog.FieldErrKind(ecode.ErrKindRegisterErr), xlog.FieldErr(err), xlog.FieldAddr(prefix))
-					continue
-				}
-				for _, ev := range n.Events {
-					select {
-					case w.eventChan <- ev:
-					default:
-						xlog.Error("watch etcd with prefix", xlog.Any("err", "block event chan, drop event message"))
-					}
-				}
+	if len(events) > 0 && !w.send(events) {
+		return w.ctx.Err()
+	}
+	w.setRevision(resp.Header.Revision)
+
+	return nil
+}
+
+// flatten sends the events of the batches one by one to eventChan.
+func (w *Watch) flatten() {
+	defer close(w.eventChan)
+
+	for batch := range w.batchChan {
+		for _, ev := range batch {
+			select {
+			case w.eventChan <- ev:
+			case <-w.ctx.Done():
+				return
 			}
-			ctx, cancel := context.WithCancel(context.Background())
-			w.cancel = cancel
-			if w.revision > 0 {
-				rch = client.Watch(ctx, prefix, clientv3.WithPrefix(), clientv3.WithCreatedNotify(), clientv3.WithRev(w.revision))
-			} else {
-				rch = client.Watch(ctx, prefix, clientv3.WithPrefix(), clientv3.WithCreatedNotify())
+		}
+	}
+}
+
+func (w *Watch) run() {
+	defer close(w.batchChan)
+
+	for {
+		err := w.watch()
+		if w.ctx.Err() != nil {
+			return
+		}
+
+		if err == rpctypes.ErrCompacted {
+			w.fail(err)
+			if err = w.relist(); err == nil {
+				continue
 			}
 		}
-	})
+		if err != nil {
+			w.fail(err)
+		}
 
-	return w, nil
+		metricWatchReconnects.WithLabelValues(w.prefix).Inc()
+		select {
+		case <-time.After(watchRetryDelay):
+		case <-w.ctx.Done():
+			return
+		}
+	}
 }
 
-// Close close watch
-func (w *Watch) Close() error {
-	if w.cancel != nil {
-		w.cancel()
+// send sends the batch of events and updates the known state, returns false if the watch is closed.
+func (w *Watch) send(events []*clientv3.Event) bool {
+	for _, ev := range events {
+		if ev.Type == mvccpb.DELETE {
+			delete(w.kvs, string(ev.Kv.Key))
+		} else {
+			w.kvs[string(ev.Kv.Key)] = ev.Kv
+		}
+	}
+
+	select {
+	case w.batchChan <- events:
+		for _, ev := range events {
+			metricWatchEvents.WithLabelValues(w.prefix, ev.Type.String()).Inc()
+		}
+		return true
+	case <-w.ctx.Done():
+		return false
+	}
+}
+
+func (w *Watch) setRevision(revision int64) {
+	w.lock.Lock()
+	if revision > w.revision {
+		w.revision = revision
+	}
+	w.lock.Unlock()
+}
+
+// watch watches from the next revision until the watch channel is closed.
+func (w *Watch) watch() error {
+	ctx, cancel := context.WithCancel(w.ctx)
+	defer cancel()
+
+	// require leader to get notified on partition instead of hanging on a stale member
+	rch := w.client.Watch(clientv3.WithRequireLeader(ctx), w.prefix, clientv3.WithPrefix(),
+		clientv3.WithRev(w.Revision()+1), clientv3.WithProgressNotify())
+	for n := range rch {
+		if err := n.Err(); err != nil {
+			return err
+		}
+
+		if len(n.Events) > 0 && !w.send(n.Events) {
+			return w.ctx.Err()
+		}
+		w.setRevision(n.Header.GetRevision())
+	}
+
+	return w.ctx.Err()
+}
+
+func errorKind(err error) string {
+	switch err {
+	case rpctypes.ErrCompacted:
+		return "compacted"
+	case rpctypes.ErrNoLeader:
+		return "no_leader"
+	default:
+		return "other"
 	}
-	return nil
 }
@@ -0,0 +1,133 @@
+package etcdv3
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+func TestWatchPrefix(t *testing.T) {
+	etcdCli := newTestClient(10)
+	ctx := context.Background()
+	_, err := etcdCli.Put(ctx, "/test/watch/a", "1")
+	assert.Nil(t, err)
+
+	w, err := etcdCli.WatchPrefix(ctx, "/test/watch/")
+	assert.Nil(t, err)
+	assert.Len(t, w.IncipientKeyValues(), 1)
+	assert.Equal(t, "1", string(w.IncipientKeyValues()[0].Value))
+
+	_, err = etcdCli.Put(ctx, "/test/watch/b", "2")
+	assert.Nil(t, err)
+	resp, err := etcdCli.Delete(ctx, "/test/watch/a")
+	assert.Nil(t, err)
+
+	// the listed revision is not sent again
+	ev := waitEvent(t, w)
+	assert.Equal(t, mvccpb.PUT, ev.Type)
+	assert.Equal(t, "/test/watch/b", string(ev.Kv.Key))
+	ev = waitEvent(t, w)
+	assert.Equal(t, mvccpb.DELETE, ev.Type)
+	assert.Equal(t, "/test/watch/a", string(ev.Kv.Key))
+	assert.Equal(t, resp.Header.Revision, w.Revision())
+
+	assert.Nil(t, w.Close())
+	for range w.C() {
+	}
+	_, err = etcdCli.Delete(ctx, "/test/watch/b")
+	assert.Nil(t, err)
+}
+
+func TestWatchBatches(t *testing.T) {
+	etcdCli := newTestClient(10)
+	ctx := context.Background()
+	w, err := etcdCli.WatchPrefix(ctx, "/test/batches/")
+	assert.Nil(t, err)
+	defer w.Close()
+
+	_, err = etcdCli.Txn(ctx).Then(
+		clientv3.OpPut("/test/batches/a", "1"),
+		clientv3.OpPut("/test/batches/b", "2"),
+	).Commit()
+	assert.Nil(t, err)
+
+	select {
+	case events := <-w.Batches():
+		assert.Len(t, events, 2, "the events of a txn are in one batch")
+	case <-time.After(time.Second * 5):
+		t.Fatal("no batch received")
+	}
+}
+
+func TestWatchCompacted(t *testing.T) {
+	etcdCli := newTestClient(10)
+	ctx := context.Background()
+	for _, key := range []string{"a", "b", "c"} {
+		_, err := etcdCli.Put(ctx, "/test/compact/"+key, key)
+		assert.Nil(t, err)
+	}
+	listed, err := etcdCli.Get(ctx, "/test/compact/", clientv3.WithPrefix())
+	assert.Nil(t, err)
+
+	// changed after listed and compacted before watched
+	_, err = etcdCli.Put(ctx, "/test/compact/a", "changed")
+	assert.Nil(t, err)
+	_, err = etcdCli.Delete(ctx, "/test/compact/b")
+	assert.Nil(t, err)
+	_, err = etcdCli.Put(ctx, "/test/compact/d", "d")
+	assert.Nil(t, err)
+	resp, err := etcdCli.Put(ctx, "/test/compact/a", "changed again")
+	assert.Nil(t, err)
+	_, err = etcdCli.Compact(ctx, resp.Header.Revision)
+	assert.Nil(t, err)
+
+	w := etcdCli.newWatch("/test/compact/", listed)
+	go w.run()
+	defer w.Close()
+
+	var events []string
+	for i := 0; i < 3; i++ {
+		ev := waitEvent(t, w)
+		events = append(events, ev.Type.String()+" "+string(ev.Kv.Key)+" "+string(ev.Kv.Value))
+	}
+	sort.Strings(events)
+	assert.Equal(t, []string{
+		"DELETE /test/compact/b ",
+		"PUT /test/compact/a changed again",
+		"PUT /test/compact/d d",
+	}, events)
+	assert.Equal(t, resp.Header.Revision, w.Revision())
+
+	werr := <-w.Errors()
+	assert.True(t, errors.Is(werr, rpctypes.ErrCompacted))
+	assert.Equal(t, "/test/compact/", werr.Prefix)
+
+	// keeps watching after relisted
+	_, err = etcdCli.Delete(ctx, "/test/compact/", clientv3.WithPrefix())
+	assert.Nil(t, err)
+	events = events[:0]
+	for i := 0; i < 3; i++ {
+		ev := waitEvent(t, w)
+		assert.Equal(t, mvccpb.DELETE, ev.Type)
+		events = append(events, string(ev.Kv.Key))
+	}
+	sort.Strings(events)
+	assert.Equal(t, []string{"/test/compact/a", "/test/compact/c", "/test/compact/d"}, events)
+}
+
+func waitEvent(t *testing.T, w *Watch) *clientv3.Event {
+	select {
+	case ev := <-w.C():
+		return ev
+	case <-time.After(time.Second * 5):
+		t.Fatal("no event")
+		return nil
+	}
+}
@@ -0,0 +1,335 @@
+package fx
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/tal-tech/go-zero/core/errorx"
+	"github.com/tal-tech/go-zero/core/lang"
+	"github.com/tx991020/utils/syncx"
+)
+
+type (
+	// EmitFunc sends an item downstream, it returns the error of the context if the stream is stopped.
+	EmitFunc func(item interface{}) error
+
+	ErrGenerateFunc func(ctx context.Context, emit EmitFunc) error
+	ErrWalkFunc     func(ctx context.Context, item interface{}, emit EmitFunc) error
+	ErrMapFunc      func(ctx context.Context, item interface{}) (interface{}, error)
+	ErrFilterFunc   func(ctx context.Context, item interface{}) (bool, error)
+	ErrForEachFunc  func(ctx context.Context, item interface{}) error
+	ErrReduceFunc   func(ctx context.Context, pipe <-chan interface{}) (interface{}, error)
+
+	// ErrStream is like Stream, but any stage can fail and stop the whole pipeline, and the pipeline
+	// is stopped when the context is done. It must be ended with ForEach, Collect, Reduce or Done,
+	// which return after all the goroutines of the pipeline exit.
+	ErrStream struct {
+		source <-chan interface{}
+		p      *pipeline
+	}
+
+	pipeline struct {
+		parent context.Context
+		ctx    context.Context
+		cancel context.CancelFunc
+		err    errorx.AtomicError
+		once   sync.Once
+		wg     sync.WaitGroup
+	}
+)
+
+// FromContext constructs an ErrStream from the given ErrGenerateFunc, the stream fails if generate fails.
+func FromContext(ctx context.Context, generate ErrGenerateFunc) ErrStream {
+	p := newPipeline(ctx)
+	source := make(chan interface{})
+	p.goSafe(func() {
+		defer close(source)
+		if err := generate(p.ctx, p.emitter(source)); err != nil {
+			p.fail(err)
+		}
+	})
+
+	return ErrStream{
+		source: source,
+		p:      p,
+	}
+}
+
+// JustContext converts the given arbitrary items to an ErrStream.
+func JustContext(ctx context.Context, items ...interface{}) ErrStream {
+	return FromContext(ctx, func(ctx context.Context, emit EmitFunc) error {
+		for _, item := range items {
+			if err := emit(item); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// RangeContext converts the given channel to an ErrStream, the channel is not read after the stream stops.
+func RangeContext(ctx context.Context, source <-chan interface{}) ErrStream {
+	return FromContext(ctx, func(ctx context.Context, emit EmitFunc) error {
+		for {
+			select {
+			case item, ok := <-source:
+				if !ok {
+					return nil
+				}
+				if err := emit(item); err != nil {
+					return err
+				}
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	})
+}
+
+// Filter filters the items by the given ErrFilterFunc.
+func (s ErrStream) Filter(fn ErrFilterFunc, opts ...Option) ErrStream {
+	return s.Walk(func(ctx context.Context, item interface{}, emit EmitFunc) error {
+		ok, err := fn(ctx, item)
+		if err != nil || !ok {
+			return err
+		}
+
+		return emit(item)
+	}, opts...)
+}
+
+// Map converts each item to another corresponding item, which means it's a 1:1 model.
+func (s ErrStream) Map(fn ErrMapFunc, opts ...Option) ErrStream {
+	return s.Walk(func(ctx context.Context, item interface{}, emit EmitFunc) error {
+		val, err := fn(ctx, item)
+		if err != nil {
+			return err
+		}
+
+		return emit(val)
+	}, opts...)
+}
+
+// Walk lets the callers handle each item, the caller may emit zero, one or more items base on the given item.
+func (s ErrStream) Walk(fn ErrWalkFunc, opts ...Option) ErrStream {
+	option := buildOptions(opts...)
+	pipe := make(chan interface{}, option.workers)
+	s.p.goSafe(func() {
+		var wg sync.WaitGroup
+		defer func() {
+			wg.Wait()
+			close(pipe)
+		}()
+
+		var pool chan lang.PlaceholderType
+		if !option.unlimitedWorkers {
+			pool = make(chan lang.PlaceholderType, option.workers)
+		}
+		emit := s.p.emitter(pipe)
+		for {
+			if pool != nil {
+				select {
+				case pool <- lang.Placeholder:
+				case <-s.p.ctx.Done():
+					return
+				}
+			}
+
+			item, ok := s.next()
+			if !ok {
+				return
+			}
+
+			wg.Add(1)
+			go func() {
+				defer func() {
+					if pool != nil {
+						<-pool
+					}
+					wg.Done()
+				}()
+
+				s.p.runSafe(func() {
+					if err := fn(s.p.ctx, item, emit); err != nil {
+						s.p.fail(err)
+					}
+				})
+			}()
+		}
+	})
+
+	return ErrStream{
+		source: pipe,
+		p:      s.p,
+	}
+}
+
+// Collect returns all the items, or the first error of the pipeline.
+func (s ErrStream) Collect() ([]interface{}, error) {
+	var items []interface{}
+	err := s.ForEach(func(ctx context.Context, item interface{}) error {
+		items = append(items, item)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// Done waits all the stages to be done, returns the first error of the pipeline.
+func (s ErrStream) Done() error {
+	return s.ForEach(func(ctx context.Context, item interface{}) error {
+		return nil
+	})
+}
+
+// ForEach seals the ErrStream with the ErrForEachFunc on each item, returns the first error of the pipeline.
+func (s ErrStream) ForEach(fn ErrForEachFunc) error {
+	var interrupted bool
+	s.p.runSafe(func() {
+		for {
+			select {
+			case item, ok := <-s.source:
+				if !ok {
+					return
+				}
+				if err := fn(s.p.ctx, item); err != nil {
+					s.p.fail(err)
+					return
+				}
+			case <-s.p.ctx.Done():
+				interrupted = true
+				return
+			}
+		}
+	})
+
+	return s.p.wait(interrupted)
+}
+
+// Reduce lets the caller deal with the underlying channel, which is closed when the pipeline stops.
+func (s ErrStream) Reduce(fn ErrReduceFunc) (interface{}, error) {
+	pipe := make(chan interface{})
+	var interrupted syncx.AtomicBool
+	s.p.goSafe(func() {
+		defer close(pipe)
+		for {
+			select {
+			case item, ok := <-s.source:
+				if !ok {
+					return
+				}
+				select {
+				case pipe <- item:
+				case <-s.p.ctx.Done():
+					interrupted.Set(true)
+					return
+				}
+			case <-s.p.ctx.Done():
+				interrupted.Set(true)
+				return
+			}
+		}
+	})
+
+	var val interface{}
+	s.p.runSafe(func() {
+		var err error
+		val, err = fn(s.p.ctx, pipe)
+		if err != nil {
+			s.p.fail(err)
+		}
+	})
+	// let the feeding goroutine exit if fn returns early
+	s.p.stop()
+	drain(pipe)
+
+	if err := s.p.wait(interrupted.True()); err != nil {
+		return nil, err
+	}
+
+	return val, nil
+}
+
+func (s ErrStream) next() (interface{}, bool) {
+	select {
+	case item, ok := <-s.source:
+		return item, ok
+	case <-s.p.ctx.Done():
+		return nil, false
+	}
+}
+
+func newPipeline(ctx context.Context) *pipeline {
+	p := &pipeline{
+		parent: ctx,
+	}
+	p.ctx, p.cancel = context.WithCancel(ctx)
+	return p
+}
+
+func (p *pipeline) emitter(pipe chan<- interface{}) EmitFunc {
+	return func(item interface{}) error {
+		select {
+		case pipe <- item:
+			return nil
+		case <-p.ctx.Done():
+			return p.ctx.Err()
+		}
+	}
+}
+
+func (p *pipeline) fail(err error) {
+	p.once.Do(func() {
+		p.err.Set(err)
+		p.cancel()
+	})
+}
+
+func (p *pipeline) goSafe(fn func()) {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		p.runSafe(fn)
+	}()
+}
+
+func (p *pipeline) runSafe(fn func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			p.fail(fmt.Errorf("%v", r))
+		}
+	}()
+
+	fn()
+}
+
+// stop stops the pipeline without error.
+func (p *pipeline) stop() {
+	p.once.Do(p.cancel)
+}
+
+// wait stops the pipeline, waits all the goroutines to exit, and returns the first error,
+// or the error of the parent context if the pipeline is interrupted by it.
+func (p *pipeline) wait(interrupted bool) error {
+	p.stop()
+	p.wg.Wait()
+
+	if err := p.err.Load(); err != nil {
+		return err
+	}
+	if interrupted {
+		return p.parent.Err()
+	}
+
+	return nil
+}
+
+func drain(channel <-chan interface{}) {
+	for range channel {
+	}
+}
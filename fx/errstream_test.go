@@ -0,0 +1,140 @@
+package fx
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrStream_Collect(t *testing.T) {
+	items, err := JustContext(context.Background(), 1, 2, 3, 4).
+		Filter(func(ctx context.Context, item interface{}) (bool, error) {
+			return item.(int)%2 == 0, nil
+		}).
+		Map(func(ctx context.Context, item interface{}) (interface{}, error) {
+			return item.(int) * 10, nil
+		}).
+		Collect()
+	assert.Nil(t, err)
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].(int) < items[j].(int)
+	})
+	assert.Equal(t, []interface{}{20, 40}, items)
+}
+
+func TestErrStream_StageError(t *testing.T) {
+	errDummy := errors.New("dummy")
+	goroutines := runtime.NumGoroutine()
+	var generated int32
+	err := FromContext(context.Background(), func(ctx context.Context, emit EmitFunc) error {
+		for i := 0; ; i++ {
+			atomic.AddInt32(&generated, 1)
+			if err := emit(i); err != nil {
+				return err
+			}
+		}
+	}).Map(func(ctx context.Context, item interface{}) (interface{}, error) {
+		if item.(int) == 100 {
+			return nil, errDummy
+		}
+		return item, nil
+	}, WithWorkers(4)).Walk(func(ctx context.Context, item interface{}, emit EmitFunc) error {
+		return emit(item)
+	}, UnlimitedWorkers()).Done()
+	assert.Equal(t, errDummy, err)
+	assert.True(t, atomic.LoadInt32(&generated) < 1000)
+	assertNoLeak(t, goroutines)
+}
+
+func TestErrStream_GenerateError(t *testing.T) {
+	errDummy := errors.New("dummy")
+	_, err := FromContext(context.Background(), func(ctx context.Context, emit EmitFunc) error {
+		if err := emit(1); err != nil {
+			return err
+		}
+		return errDummy
+	}).Collect()
+	assert.Equal(t, errDummy, err)
+}
+
+func TestErrStream_ForEachError(t *testing.T) {
+	errDummy := errors.New("dummy")
+	goroutines := runtime.NumGoroutine()
+	var count int
+	err := JustContext(context.Background(), 1, 2, 3, 4).ForEach(func(ctx context.Context, item interface{}) error {
+		count++
+		if count == 2 {
+			return errDummy
+		}
+		return nil
+	})
+	assert.Equal(t, errDummy, err)
+	assert.Equal(t, 2, count)
+	assertNoLeak(t, goroutines)
+}
+
+func TestErrStream_Panic(t *testing.T) {
+	err := JustContext(context.Background(), 1, 2).Map(func(ctx context.Context, item interface{}) (interface{}, error) {
+		panic("boom")
+	}).Done()
+	assert.NotNil(t, err)
+	assert.Equal(t, "boom", err.Error())
+}
+
+func TestErrStream_Canceled(t *testing.T) {
+	goroutines := runtime.NumGoroutine()
+	ctx, cancel := context.WithCancel(context.Background())
+	source := make(chan interface{})
+	go func() {
+		time.Sleep(time.Millisecond * 10)
+		cancel()
+	}()
+	err := RangeContext(ctx, source).Map(func(ctx context.Context, item interface{}) (interface{}, error) {
+		return item, nil
+	}).Done()
+	assert.Equal(t, context.Canceled, err)
+	assertNoLeak(t, goroutines)
+}
+
+func TestErrStream_Reduce(t *testing.T) {
+	goroutines := runtime.NumGoroutine()
+	val, err := JustContext(context.Background(), 1, 2, 3).Reduce(
+		func(ctx context.Context, pipe <-chan interface{}) (interface{}, error) {
+			var sum int
+			for item := range pipe {
+				sum += item.(int)
+			}
+			return sum, nil
+		})
+	assert.Nil(t, err)
+	assert.Equal(t, 6, val)
+
+	// returns early without reading all
+	val, err = JustContext(context.Background(), 1, 2, 3).Reduce(
+		func(ctx context.Context, pipe <-chan interface{}) (interface{}, error) {
+			return <-pipe, nil
+		})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, val)
+
+	errDummy := errors.New("dummy")
+	_, err = JustContext(context.Background(), 1, 2, 3).Reduce(
+		func(ctx context.Context, pipe <-chan interface{}) (interface{}, error) {
+			return nil, errDummy
+		})
+	assert.Equal(t, errDummy, err)
+	assertNoLeak(t, goroutines)
+}
+
+func assertNoLeak(t *testing.T, goroutines int) {
+	for i := 0; i < 100 && runtime.NumGoroutine() > goroutines; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	assert.True(t, runtime.NumGoroutine() <= goroutines)
+}
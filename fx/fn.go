@@ -18,6 +18,8 @@ type (
 	rxOptions struct {
 		unlimitedWorkers bool
 		workers          int
+		ordered          bool
+		buffer           int
 	}
 
 	FilterFunc   func(item interface{}) bool
@@ -255,7 +257,9 @@ func (p Stream) Tail(n int64) Stream {
 // Walk lets the callers handle each item, the caller may write zero, one or more items base on the given item.
 func (p Stream) Walk(fn WalkFunc, opts ...Option) Stream {
 	option := buildOptions(opts...)
-	if option.unlimitedWorkers {
+	if option.ordered {
+		return p.walkOrdered(fn, option)
+	} else if option.unlimitedWorkers {
 		return p.walkUnlimited(fn, option)
 	} else {
 		return p.walkLimited(fn, option)
@@ -330,6 +334,15 @@ func UnlimitedWorkers() Option {
 	}
 }
 
+// WithOrdered keeps the items in the order of the source, the slower items are waited with
+// at most buffer items in flight or done, buffer is at least the workers.
+func WithOrdered(buffer int) Option {
+	return func(opts *rxOptions) {
+		opts.ordered = true
+		opts.buffer = buffer
+	}
+}
+
 // WithWorkers lets the caller to customize the concurrent workers.
 func WithWorkers(workers int) Option {
 	return func(opts *rxOptions) {
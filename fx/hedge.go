@@ -0,0 +1,137 @@
+package fx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/tal-tech/go-zero/core/errorx"
+)
+
+// ErrQuorumUnreachable is returned if there are not enough calls for the quorum.
+var ErrQuorumUnreachable = errors.New("quorum unreachable")
+
+type (
+	// CallFunc is a call that returns a result, it should return early when ctx is done,
+	// which happens when the call loses.
+	CallFunc func(ctx context.Context) (interface{}, error)
+
+	callResult struct {
+		val interface{}
+		err error
+	}
+)
+
+// Hedge calls fn, and starts a backup call every delay until one succeeds or copies calls are started,
+// or as soon as all the started calls failed. The first success is returned,
+// the other calls are cancelled. If all the calls fail, the errors are returned in batch.
+func Hedge(ctx context.Context, fn CallFunc, delay time.Duration, copies int) (interface{}, error) {
+	if copies < 1 {
+		copies = 1
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan callResult, copies)
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	var berr errorx.BatchError
+	started, done := 1, 0
+	call(ctx, fn, results)
+	for done < copies {
+		select {
+		case r := <-results:
+			done++
+			if r.err == nil {
+				return r.val, nil
+			}
+
+			berr.Add(r.err)
+			if started < copies && started == done {
+				started++
+				call(ctx, fn, results)
+			}
+		case <-timer.C:
+			if started < copies {
+				started++
+				call(ctx, fn, results)
+				timer.Reset(delay)
+			}
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	return nil, berr.Err()
+}
+
+// FirstSuccess calls fns in parallel and returns the first success, the other calls are cancelled.
+// If all the calls fail, the errors are returned in batch.
+func FirstSuccess(ctx context.Context, fns ...CallFunc) (interface{}, error) {
+	vals, err := Quorum(ctx, 1, fns...)
+	if err != nil {
+		return nil, err
+	}
+
+	return vals[0], nil
+}
+
+// Quorum calls fns in parallel and returns the results of the first n successes,
+// the other calls are cancelled. Once n successes are impossible, the errors are returned in batch.
+func Quorum(ctx context.Context, n int, fns ...CallFunc) ([]interface{}, error) {
+	if n < 1 || n > len(fns) {
+		return nil, ErrQuorumUnreachable
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan callResult, len(fns))
+	for _, fn := range fns {
+		call(ctx, fn, results)
+	}
+
+	var berr errorx.BatchError
+	var vals []interface{}
+	var failures int
+	for range fns {
+		select {
+		case r := <-results:
+			if r.err != nil {
+				berr.Add(r.err)
+				failures++
+				if len(fns)-failures < n {
+					return nil, berr.Err()
+				}
+			} else {
+				vals = append(vals, r.val)
+				if len(vals) == n {
+					return vals, nil
+				}
+			}
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	return nil, berr.Err()
+}
+
+// call runs fn in a goroutine, results must be buffered to not leak the goroutine,
+// the panics are reported as errors.
+func call(ctx context.Context, fn CallFunc, results chan<- callResult) {
+	go func() {
+		var r callResult
+		defer func() {
+			if p := recover(); p != nil {
+				r.err = fmt.Errorf("%v", p)
+			}
+			results <- r
+		}()
+
+		r.val, r.err = fn(ctx)
+	}()
+}
@@ -0,0 +1,111 @@
+package fx
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHedge(t *testing.T) {
+	var calls int32
+	val, err := Hedge(context.Background(), func(ctx context.Context) (interface{}, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return "backup", nil
+	}, time.Millisecond*10, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, "backup", val)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestHedgeFastEnough(t *testing.T) {
+	var calls int32
+	val, err := Hedge(context.Background(), func(ctx context.Context) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		return "first", nil
+	}, time.Millisecond*50, 3)
+	assert.Nil(t, err)
+	assert.Equal(t, "first", val)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestHedgeFailFast(t *testing.T) {
+	var calls int32
+	start := time.Now()
+	_, err := Hedge(context.Background(), func(ctx context.Context) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		return nil, errors.New("any")
+	}, time.Second, 3)
+	assert.NotNil(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+	assert.True(t, time.Since(start) < time.Second)
+}
+
+func TestHedgeCanceled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	_, err := Hedge(ctx, func(ctx context.Context) (interface{}, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}, time.Millisecond, 2)
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestFirstSuccess(t *testing.T) {
+	var canceled int32
+	val, err := FirstSuccess(context.Background(), func(ctx context.Context) (interface{}, error) {
+		return nil, errors.New("any")
+	}, func(ctx context.Context) (interface{}, error) {
+		time.Sleep(time.Millisecond * 10)
+		return 2, nil
+	}, func(ctx context.Context) (interface{}, error) {
+		<-ctx.Done()
+		atomic.AddInt32(&canceled, 1)
+		return nil, ctx.Err()
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, val)
+
+	time.Sleep(time.Millisecond * 10)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&canceled))
+}
+
+func TestFirstSuccessAllFailed(t *testing.T) {
+	_, err := FirstSuccess(context.Background(), func(ctx context.Context) (interface{}, error) {
+		return nil, errors.New("any")
+	}, func(ctx context.Context) (interface{}, error) {
+		panic("boom")
+	})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "boom")
+
+	_, err = FirstSuccess(context.Background())
+	assert.Equal(t, ErrQuorumUnreachable, err)
+}
+
+func TestQuorum(t *testing.T) {
+	success := func(val int) CallFunc {
+		return func(ctx context.Context) (interface{}, error) {
+			return val, nil
+		}
+	}
+	failure := func(ctx context.Context) (interface{}, error) {
+		return nil, errors.New("any")
+	}
+
+	vals, err := Quorum(context.Background(), 2, success(1), failure, success(1))
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{1, 1}, vals)
+
+	_, err = Quorum(context.Background(), 2, success(1), failure, failure)
+	assert.NotNil(t, err)
+
+	_, err = Quorum(context.Background(), 3, success(1), success(1))
+	assert.Equal(t, ErrQuorumUnreachable, err)
+}
@@ -0,0 +1,87 @@
+package fx
+
+import (
+	"github.com/tal-tech/go-zero/core/lang"
+	"github.com/tal-tech/go-zero/core/threading"
+)
+
+// walkOrdered runs fn with the workers, the outputs of each item are emitted in the order of the source.
+// The items in flight or waiting for the slower ones are bounded by the buffer.
+func (p Stream) walkOrdered(fn WalkFunc, option *rxOptions) Stream {
+	workers := option.workers
+	buffer := option.buffer
+	if option.unlimitedWorkers {
+		workers = buffer
+	}
+	if workers < minWorkers {
+		workers = minWorkers
+	}
+	if buffer < workers {
+		buffer = workers
+	}
+
+	pipe := make(chan interface{}, workers)
+	results := make(chan chan []interface{}, buffer)
+	slots := make(chan lang.PlaceholderType, buffer)
+
+	go func() {
+		defer close(results)
+
+		pool := make(chan lang.PlaceholderType, workers)
+		for {
+			slots <- lang.Placeholder
+			pool <- lang.Placeholder
+			item, ok := <-p.source
+			if !ok {
+				<-pool
+				<-slots
+				break
+			}
+
+			result := make(chan []interface{}, 1)
+			results <- result
+			go func() {
+				defer func() {
+					<-pool
+				}()
+
+				result <- collect(func(output chan<- interface{}) {
+					fn(item, output)
+				})
+			}()
+		}
+	}()
+
+	go func() {
+		defer close(pipe)
+
+		for result := range results {
+			for _, item := range <-result {
+				pipe <- item
+			}
+			<-slots
+		}
+	}()
+
+	return Range(pipe)
+}
+
+// collect runs fn safely and returns what fn writes.
+func collect(fn func(output chan<- interface{})) []interface{} {
+	output := make(chan interface{})
+	done := make(chan []interface{})
+	go func() {
+		var items []interface{}
+		for item := range output {
+			items = append(items, item)
+		}
+		done <- items
+	}()
+
+	threading.RunSafe(func() {
+		fn(output)
+	})
+	close(output)
+
+	return <-done
+}
@@ -0,0 +1,69 @@
+package fx
+
+import (
+	"math/rand"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrderedMap(t *testing.T) {
+	var items []interface{}
+	var expect []interface{}
+	From(func(source chan<- interface{}) {
+		for i := 0; i < 100; i++ {
+			source <- i
+		}
+	}).Map(func(item interface{}) interface{} {
+		time.Sleep(time.Duration(rand.Intn(3)) * time.Millisecond)
+		return item.(int) * 2
+	}, WithWorkers(8), WithOrdered(16)).ForEach(func(item interface{}) {
+		items = append(items, item)
+	})
+	for i := 0; i < 100; i++ {
+		expect = append(expect, i*2)
+	}
+	assert.Equal(t, expect, items)
+}
+
+func TestOrderedWalk(t *testing.T) {
+	var items []interface{}
+	Just(1, 2, 3).Walk(func(item interface{}, pipe chan<- interface{}) {
+		if item.(int) == 1 {
+			time.Sleep(time.Millisecond * 10)
+		}
+		for i := 0; i < item.(int); i++ {
+			pipe <- item
+		}
+	}, WithOrdered(0), UnlimitedWorkers()).ForEach(func(item interface{}) {
+		items = append(items, item)
+	})
+	assert.Equal(t, []interface{}{1, 2, 2, 3, 3, 3}, items)
+}
+
+func TestOrderedBounded(t *testing.T) {
+	const buffer = 4
+	var started int32
+	var maxAhead int32
+	Range(func() chan interface{} {
+		source := make(chan interface{})
+		go func() {
+			for i := 0; i < 20; i++ {
+				source <- i
+			}
+			close(source)
+		}()
+		return source
+	}()).Map(func(item interface{}) interface{} {
+		n := atomic.AddInt32(&started, 1)
+		if item.(int) == 0 {
+			// the others can't go too far while the first one is slow
+			time.Sleep(time.Millisecond * 50)
+			atomic.StoreInt32(&maxAhead, atomic.LoadInt32(&started))
+		}
+		return n
+	}, WithWorkers(2), WithOrdered(buffer)).Done()
+	assert.Equal(t, int32(buffer), atomic.LoadInt32(&maxAhead))
+}
@@ -1,17 +1,35 @@
 package fx
 
-import "github.com/tal-tech/go-zero/core/errorx"
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"github.com/tal-tech/go-zero/core/errorx"
+	"github.com/tal-tech/go-zero/core/timex"
+)
 
 const defaultRetryTimes = 3
 
 type (
 	RetryOption func(*retryOptions)
 
+	// Backoff returns the delay before the given retry, starting from 1, prev is the previous delay.
+	Backoff func(retry int, prev time.Duration) time.Duration
+
 	retryOptions struct {
-		times int
+		times      int
+		ctx        context.Context
+		backoff    Backoff
+		maxElapsed time.Duration
+		timeout    time.Duration
+		retryable  func(err error) bool
+		onRetry    func(retry int, err error, delay time.Duration)
 	}
 )
 
+// DoWithRetries calls fn until it succeeds or the retries are used up, the errors are returned in batch.
+// It stops early if the context is done, the error is not retryable or the max elapsed time is exceeded.
 func DoWithRetries(fn func() error, opts ...RetryOption) error {
 	var options = newRetryOptions()
 	for _, opt := range opts {
@@ -19,25 +37,159 @@ func DoWithRetries(fn func() error, opts ...RetryOption) error {
 	}
 
 	var berr errorx.BatchError
+	var delay time.Duration
+	start := timex.Now()
 	for i := 0; i < options.times; i++ {
-		if err := fn(); err != nil {
+		if err := options.ctx.Err(); err != nil {
 			berr.Add(err)
-		} else {
+			break
+		}
+
+		err := options.do(fn)
+		if err == nil {
 			return nil
 		}
+
+		berr.Add(err)
+		if i == options.times-1 || (options.retryable != nil && !options.retryable(err)) {
+			break
+		}
+
+		delay = options.backoff(i+1, delay)
+		if options.maxElapsed > 0 && timex.Since(start)+delay > options.maxElapsed {
+			break
+		}
+		if options.onRetry != nil {
+			options.onRetry(i+1, err, delay)
+		}
+		if err := options.wait(delay); err != nil {
+			berr.Add(err)
+			break
+		}
 	}
 
 	return berr.Err()
 }
 
+// ConstantBackoff waits delay before each retry.
+func ConstantBackoff(delay time.Duration) Backoff {
+	return func(retry int, prev time.Duration) time.Duration {
+		return delay
+	}
+}
+
+// ExponentialBackoff waits base, base*2, base*4, ... before the retries, at most max.
+func ExponentialBackoff(base, max time.Duration) Backoff {
+	return func(retry int, prev time.Duration) time.Duration {
+		if retry > 62 {
+			return max
+		}
+
+		delay := base << uint(retry-1)
+		if delay <= 0 || delay > max {
+			return max
+		}
+
+		return delay
+	}
+}
+
+// DecorrelatedJitterBackoff waits a random delay between base and 3 times the previous delay, at most max.
+// see https://aws.amazon.com/blogs/architecture/exponential-backoff-and-jitter/
+func DecorrelatedJitterBackoff(base, max time.Duration) Backoff {
+	return func(retry int, prev time.Duration) time.Duration {
+		if prev < base {
+			prev = base
+		}
+
+		upper := prev * 3
+		if upper <= base || upper > max {
+			upper = max
+		}
+		if upper <= base {
+			return upper
+		}
+
+		return base + time.Duration(rand.Int63n(int64(upper-base)))
+	}
+}
+
 func WithRetries(times int) RetryOption {
 	return func(options *retryOptions) {
 		options.times = times
 	}
 }
 
+// WithBackoff sets the delays between the retries, retries immediately by default.
+func WithBackoff(backoff Backoff) RetryOption {
+	return func(options *retryOptions) {
+		options.backoff = backoff
+	}
+}
+
+// WithMaxElapsed stops retrying if the next retry would start after maxElapsed since the first call.
+func WithMaxElapsed(maxElapsed time.Duration) RetryOption {
+	return func(options *retryOptions) {
+		options.maxElapsed = maxElapsed
+	}
+}
+
+// WithRetryContext stops retrying when ctx is done.
+func WithRetryContext(ctx context.Context) RetryOption {
+	return func(options *retryOptions) {
+		options.ctx = ctx
+	}
+}
+
+// WithAttemptTimeout limits each call with DoWithTimeout.
+func WithAttemptTimeout(timeout time.Duration) RetryOption {
+	return func(options *retryOptions) {
+		options.timeout = timeout
+	}
+}
+
+// WithRetryable stops retrying on the errors that retryable returns false.
+func WithRetryable(retryable func(err error) bool) RetryOption {
+	return func(options *retryOptions) {
+		options.retryable = retryable
+	}
+}
+
+// WithOnRetry sets the hook that is called before each retry, like logging or metrics.
+func WithOnRetry(fn func(retry int, err error, delay time.Duration)) RetryOption {
+	return func(options *retryOptions) {
+		options.onRetry = fn
+	}
+}
+
 func newRetryOptions() *retryOptions {
 	return &retryOptions{
-		times: defaultRetryTimes,
+		times:   defaultRetryTimes,
+		ctx:     context.Background(),
+		backoff: ConstantBackoff(0),
+	}
+}
+
+func (o *retryOptions) do(fn func() error) error {
+	if o.timeout > 0 {
+		return DoWithTimeout(fn, o.timeout, WithContext(o.ctx))
+	}
+
+	return fn()
+}
+
+func (o *retryOptions) wait(delay time.Duration) error {
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-o.ctx.Done():
+		return o.ctx.Err()
 	}
 }
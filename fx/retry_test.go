@@ -1,8 +1,12 @@
 package fx
 
 import (
+	"context"
 	"errors"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -40,3 +44,83 @@ func TestRetry(t *testing.T) {
 		return errors.New("any")
 	}, WithRetries(total)))
 }
+
+func TestRetryWithBackoff(t *testing.T) {
+	var delays []time.Duration
+	var retries []int
+	start := time.Now()
+	err := DoWithRetries(func() error {
+		return errors.New("any")
+	}, WithRetries(4), WithBackoff(ExponentialBackoff(time.Millisecond*5, time.Millisecond*15)),
+		WithOnRetry(func(retry int, err error, delay time.Duration) {
+			retries = append(retries, retry)
+			delays = append(delays, delay)
+		}))
+	assert.NotNil(t, err)
+	assert.Equal(t, []int{1, 2, 3}, retries)
+	assert.Equal(t, []time.Duration{time.Millisecond * 5, time.Millisecond * 10, time.Millisecond * 15}, delays)
+	assert.True(t, time.Since(start) >= time.Millisecond*30)
+}
+
+func TestRetryWithRetryable(t *testing.T) {
+	errFatal := errors.New("fatal")
+	var times int
+	err := DoWithRetries(func() error {
+		times++
+		return errFatal
+	}, WithRetries(5), WithRetryable(func(err error) bool {
+		return err != errFatal
+	}))
+	assert.Equal(t, errFatal, err)
+	assert.Equal(t, 1, times)
+}
+
+func TestRetryWithMaxElapsed(t *testing.T) {
+	var times int
+	assert.NotNil(t, DoWithRetries(func() error {
+		times++
+		return errors.New("any")
+	}, WithRetries(10), WithBackoff(ConstantBackoff(time.Millisecond*20)),
+		WithMaxElapsed(time.Millisecond*50)))
+	assert.Equal(t, 3, times)
+}
+
+func TestRetryWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var times int
+	err := DoWithRetries(func() error {
+		times++
+		cancel()
+		return errors.New("any")
+	}, WithRetries(10), WithBackoff(ConstantBackoff(time.Second)), WithRetryContext(ctx))
+	assert.NotNil(t, err)
+	assert.True(t, strings.Contains(err.Error(), context.Canceled.Error()))
+	assert.Equal(t, 1, times)
+}
+
+func TestRetryWithAttemptTimeout(t *testing.T) {
+	var times int32
+	assert.Nil(t, DoWithRetries(func() error {
+		if atomic.AddInt32(&times, 1) == 1 {
+			time.Sleep(time.Millisecond * 50)
+		}
+		return nil
+	}, WithAttemptTimeout(time.Millisecond*10)))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&times))
+}
+
+func TestBackoff(t *testing.T) {
+	exp := ExponentialBackoff(time.Millisecond, time.Second)
+	assert.Equal(t, time.Millisecond, exp(1, 0))
+	assert.Equal(t, time.Millisecond*8, exp(4, 0))
+	assert.Equal(t, time.Second, exp(20, 0))
+	assert.Equal(t, time.Second, exp(100, 0))
+
+	jitter := DecorrelatedJitterBackoff(time.Millisecond*10, time.Millisecond*100)
+	var prev time.Duration
+	for i := 1; i < 100; i++ {
+		delay := jitter(i, prev)
+		assert.True(t, delay >= time.Millisecond*10 && delay <= time.Millisecond*100)
+		prev = delay
+	}
+}
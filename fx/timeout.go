@@ -20,7 +20,8 @@ func DoWithTimeout(fn func() error, timeout time.Duration, opts ...FxOption) err
 	ctx, cancel := context.WithTimeout(parentCtx, timeout)
 	defer cancel()
 
-	done := make(chan error)
+	// buffered to not block the goroutine forever on timeout
+	done := make(chan error, 1)
 	panicChan := make(chan interface{}, 1)
 	go func() {
 		defer func() {
@@ -0,0 +1,225 @@
+package fx
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+type (
+	// FlatMapFunc converts an item to a Stream, whose items are emitted downstream.
+	FlatMapFunc func(item interface{}) Stream
+
+	timedItem struct {
+		item interface{}
+		at   time.Time
+	}
+)
+
+// Batch groups the items into slices of n items, a partial slice is emitted if maxWait has passed
+// since its first item, or the source is closed. Zero maxWait means waiting for n items.
+func (p Stream) Batch(n int, maxWait time.Duration) Stream {
+	if n < 1 {
+		n = 1
+	}
+
+	source := make(chan interface{})
+	go func() {
+		defer close(source)
+
+		var batch []interface{}
+		var timer *time.Timer
+		var timeout <-chan time.Time
+		flush := func() {
+			if timer != nil {
+				timer.Stop()
+				timer, timeout = nil, nil
+			}
+			if len(batch) > 0 {
+				source <- batch
+				batch = nil
+			}
+		}
+
+		for {
+			select {
+			case item, ok := <-p.source:
+				if !ok {
+					flush()
+					return
+				}
+
+				batch = append(batch, item)
+				if len(batch) >= n {
+					flush()
+				} else if len(batch) == 1 && maxWait > 0 {
+					timer = time.NewTimer(maxWait)
+					timeout = timer.C
+				}
+			case <-timeout:
+				timer, timeout = nil, nil
+				flush()
+			}
+		}
+	}()
+
+	return Range(source)
+}
+
+// TumblingWindow groups the items into slices by the fixed, non-overlapping windows of size,
+// empty windows are skipped.
+func (p Stream) TumblingWindow(size time.Duration) Stream {
+	source := make(chan interface{})
+	go func() {
+		defer close(source)
+
+		ticker := time.NewTicker(size)
+		defer ticker.Stop()
+
+		var window []interface{}
+		flush := func() {
+			if len(window) > 0 {
+				source <- window
+				window = nil
+			}
+		}
+
+		for {
+			select {
+			case item, ok := <-p.source:
+				if !ok {
+					flush()
+					return
+				}
+				window = append(window, item)
+			case <-ticker.C:
+				flush()
+			}
+		}
+	}()
+
+	return Range(source)
+}
+
+// SlidingWindow emits the items arrived in the last size every slide, empty windows are skipped.
+// The items are emitted in multiple overlapping windows if slide is less than size.
+func (p Stream) SlidingWindow(size, slide time.Duration) Stream {
+	source := make(chan interface{})
+	go func() {
+		defer close(source)
+
+		ticker := time.NewTicker(slide)
+		defer ticker.Stop()
+
+		var items []timedItem
+		// whether there are items not emitted yet, to flush them on closing
+		var pending bool
+		emit := func(now time.Time) {
+			var start int
+			for start < len(items) && now.Sub(items[start].at) > size {
+				start++
+			}
+			items = items[start:]
+			if len(items) == 0 {
+				return
+			}
+
+			window := make([]interface{}, len(items))
+			for i, item := range items {
+				window[i] = item.item
+			}
+			source <- window
+			pending = false
+		}
+
+		for {
+			select {
+			case item, ok := <-p.source:
+				if !ok {
+					if pending {
+						emit(time.Now())
+					}
+					return
+				}
+
+				items = append(items, timedItem{
+					item: item,
+					at:   time.Now(),
+				})
+				pending = true
+			case now := <-ticker.C:
+				emit(now)
+			}
+		}
+	}()
+
+	return Range(source)
+}
+
+// RateLimit lets at most rps items go through per second, no limit if rps is not positive.
+func (p Stream) RateLimit(rps int) Stream {
+	if rps <= 0 {
+		return p
+	}
+
+	limiter := rate.NewLimiter(rate.Limit(rps), 1)
+	return p.Walk(func(item interface{}, pipe chan<- interface{}) {
+		limiter.Wait(context.Background())
+		pipe <- item
+	}, WithWorkers(1))
+}
+
+// Concat emits the items of the Stream, and then the items of the others in order.
+func (p Stream) Concat(others ...Stream) Stream {
+	source := make(chan interface{})
+	go func() {
+		defer close(source)
+
+		for _, stream := range append([]Stream{p}, others...) {
+			for item := range stream.source {
+				source <- item
+			}
+		}
+	}()
+
+	return Range(source)
+}
+
+// FlatMap converts each item to a Stream and emits its items, which means it's a 1:n model.
+func (p Stream) FlatMap(fn FlatMapFunc, opts ...Option) Stream {
+	return p.Walk(func(item interface{}, pipe chan<- interface{}) {
+		for val := range fn(item).source {
+			pipe <- val
+		}
+	}, opts...)
+}
+
+// Zip emits the slices of the items at the same positions of the Stream and the others,
+// it stops at the shortest one, the rest items are drained.
+func (p Stream) Zip(others ...Stream) Stream {
+	streams := append([]Stream{p}, others...)
+	source := make(chan interface{})
+	go func() {
+		defer func() {
+			close(source)
+			for _, stream := range streams {
+				stream.Done()
+			}
+		}()
+
+		for {
+			tuple := make([]interface{}, len(streams))
+			for i, stream := range streams {
+				item, ok := <-stream.source
+				if !ok {
+					return
+				}
+				tuple[i] = item
+			}
+			source <- tuple
+		}
+	}()
+
+	return Range(source)
+}
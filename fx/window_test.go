@@ -0,0 +1,100 @@
+package fx
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatch(t *testing.T) {
+	var batches [][]interface{}
+	Just(1, 2, 3, 4, 5).Batch(2, 0).ForEach(func(item interface{}) {
+		batches = append(batches, item.([]interface{}))
+	})
+	assert.Equal(t, [][]interface{}{{1, 2}, {3, 4}, {5}}, batches)
+}
+
+func TestBatchMaxWait(t *testing.T) {
+	var batches [][]interface{}
+	From(func(source chan<- interface{}) {
+		source <- 1
+		source <- 2
+		time.Sleep(time.Millisecond * 50)
+		source <- 3
+	}).Batch(10, time.Millisecond*10).ForEach(func(item interface{}) {
+		batches = append(batches, item.([]interface{}))
+	})
+	assert.Equal(t, [][]interface{}{{1, 2}, {3}}, batches)
+}
+
+func TestTumblingWindow(t *testing.T) {
+	var windows [][]interface{}
+	From(func(source chan<- interface{}) {
+		source <- 1
+		source <- 2
+		time.Sleep(time.Millisecond * 80)
+		source <- 3
+	}).TumblingWindow(time.Millisecond * 30).ForEach(func(item interface{}) {
+		windows = append(windows, item.([]interface{}))
+	})
+	assert.Equal(t, [][]interface{}{{1, 2}, {3}}, windows)
+}
+
+func TestSlidingWindow(t *testing.T) {
+	var windows [][]interface{}
+	From(func(source chan<- interface{}) {
+		source <- 1
+		time.Sleep(time.Millisecond * 30)
+		source <- 2
+		time.Sleep(time.Millisecond * 100)
+	}).SlidingWindow(time.Millisecond*50, time.Millisecond*20).ForEach(func(item interface{}) {
+		windows = append(windows, item.([]interface{}))
+	})
+	assert.True(t, len(windows) >= 3)
+	assert.Equal(t, []interface{}{1}, windows[0])
+	assert.Contains(t, windows, []interface{}{1, 2})
+	// emitted again after 1 slides out, without new items
+	assert.Contains(t, windows, []interface{}{2})
+}
+
+func TestRateLimit(t *testing.T) {
+	start := time.Now()
+	var count int
+	Just(1, 2, 3, 4, 5).RateLimit(100).ForEach(func(item interface{}) {
+		count++
+	})
+	assert.Equal(t, 5, count)
+	assert.True(t, time.Since(start) >= time.Millisecond*40)
+
+	Just(1, 2).RateLimit(0).ForEach(func(item interface{}) {
+		count++
+	})
+	assert.Equal(t, 7, count)
+}
+
+func TestConcat(t *testing.T) {
+	var items []interface{}
+	Just(1, 2).Concat(Just(3), Just(4, 5)).ForEach(func(item interface{}) {
+		items = append(items, item)
+	})
+	assert.Equal(t, []interface{}{1, 2, 3, 4, 5}, items)
+}
+
+func TestFlatMap(t *testing.T) {
+	var items []interface{}
+	Just(1, 2, 3).FlatMap(func(item interface{}) Stream {
+		return Just(item, item)
+	}, WithWorkers(1)).ForEach(func(item interface{}) {
+		items = append(items, item)
+	})
+	assert.Equal(t, []interface{}{1, 1, 2, 2, 3, 3}, items)
+}
+
+func TestZip(t *testing.T) {
+	var tuples [][]interface{}
+	Just(1, 2, 3).Zip(Just("a", "b"), Just(true, false, true)).ForEach(func(item interface{}) {
+		tuples = append(tuples, item.([]interface{}))
+	})
+	assert.Equal(t, [][]interface{}{{1, "a", true}, {2, "b", false}}, tuples)
+}
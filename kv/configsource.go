@@ -0,0 +1,232 @@
+package kv
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/tal-tech/go-zero/core/logx"
+	"github.com/tx991020/utils/etcdv3"
+)
+
+const configRetryInterval = time.Second * 3
+
+// ConfigSource keeps a typed config loaded from the keys under a prefix in a Store up to date,
+// the values are decoded the same as etcdv3.ConfigSource.
+type ConfigSource struct {
+	store   Store
+	prefix  string
+	decoder *etcdv3.ConfigDecoder
+
+	lock     sync.RWMutex
+	value    interface{}
+	raw      map[string]string
+	previous map[string]string
+	// latest is the latest values in the store, which might not be valid to apply
+	latest      map[string]string
+	subscribers []func(old, new interface{})
+	mirror      *mirror
+	done        chan struct{}
+	once        sync.Once
+}
+
+// NewConfigSource returns a ConfigSource that loads the keys under prefix into the type of v,
+// which must be a pointer to struct, v is filled with the initial config.
+// If the store is unavailable on start, the cached values are loaded and the store is retried
+// in background.
+func NewConfigSource(ctx context.Context, store Store, prefix string, v interface{},
+	opts ...etcdv3.ConfigOption) (*ConfigSource, error) {
+	decoder, err := etcdv3.NewConfigDecoder(v, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	cs := &ConfigSource{
+		store:   store,
+		prefix:  prefix,
+		decoder: decoder,
+		done:    make(chan struct{}),
+	}
+
+	m, err := newMirror(ctx, store, prefix)
+	if err != nil {
+		raw, cerr := decoder.LoadCache()
+		if cerr != nil {
+			return nil, err
+		}
+
+		logx.Errorf("store unavailable, config %s loaded from cache: %v", prefix, err)
+		if err := cs.apply(raw); err != nil {
+			return nil, err
+		}
+		go cs.rewatch()
+	} else {
+		if err := cs.apply(toRaw(m.listed)); err != nil {
+			m.close()
+			return nil, err
+		}
+		cs.mirror = m
+		go cs.run(m)
+	}
+
+	reflect.ValueOf(v).Elem().Set(reflect.ValueOf(cs.Value()).Elem())
+	return cs, nil
+}
+
+// Close stops watching the changes.
+func (cs *ConfigSource) Close() error {
+	cs.once.Do(func() {
+		close(cs.done)
+	})
+
+	cs.lock.Lock()
+	defer cs.lock.Unlock()
+	if cs.mirror != nil {
+		cs.mirror.close()
+	}
+
+	return nil
+}
+
+// Rollback puts the values of the previous config back to the store, which are then applied.
+// If the latest values in the store are not applied because they are invalid,
+// the values of the current config are put back instead.
+func (cs *ConfigSource) Rollback(ctx context.Context) error {
+	cs.lock.RLock()
+	previous, latest := cs.previous, cs.latest
+	if !reflect.DeepEqual(cs.raw, latest) {
+		previous = cs.raw
+	}
+	cs.lock.RUnlock()
+
+	if previous == nil {
+		return etcdv3.ErrNoPreviousConfig
+	}
+
+	var ops []Op
+	for key, value := range previous {
+		ops = append(ops, OpPut(key, value))
+	}
+	for key := range latest {
+		if _, ok := previous[key]; !ok {
+			ops = append(ops, OpDelete(key))
+		}
+	}
+
+	_, err := cs.store.Commit(ctx, ops...)
+	return err
+}
+
+// Subscribe adds fn to be called with the old and new configs after every change is applied.
+func (cs *ConfigSource) Subscribe(fn func(old, new interface{})) {
+	cs.lock.Lock()
+	cs.subscribers = append(cs.subscribers, fn)
+	cs.lock.Unlock()
+}
+
+// Value returns the current config, a pointer to the struct that should not be modified.
+func (cs *ConfigSource) Value() interface{} {
+	cs.lock.RLock()
+	defer cs.lock.RUnlock()
+	return cs.value
+}
+
+// apply decodes and validates raw, and applies it if ok.
+func (cs *ConfigSource) apply(raw map[string]string) error {
+	cs.lock.Lock()
+	cs.latest = raw
+	unchanged := cs.raw != nil && reflect.DeepEqual(cs.raw, raw)
+	cs.lock.Unlock()
+	if unchanged {
+		return nil
+	}
+
+	value, err := cs.decoder.Decode(raw)
+	if err != nil {
+		return err
+	}
+
+	cs.lock.Lock()
+	old := cs.value
+	cs.value = value
+	if cs.raw != nil {
+		cs.previous = cs.raw
+	}
+	cs.raw = raw
+	subscribers := cs.subscribers
+	cs.lock.Unlock()
+
+	if err := cs.decoder.SaveCache(raw); err != nil {
+		logx.Errorf("save config cache of %s failed: %v", cs.prefix, err)
+	}
+	for _, subscriber := range subscribers {
+		subscriber(old, value)
+	}
+
+	return nil
+}
+
+// rewatch retries watching until done, the current values are applied once watched.
+func (cs *ConfigSource) rewatch() {
+	for {
+		select {
+		case <-cs.done:
+			return
+		case <-time.After(configRetryInterval):
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), configRetryInterval)
+		m, err := newMirror(ctx, cs.store, cs.prefix)
+		cancel()
+		if err != nil {
+			continue
+		}
+
+		cs.lock.Lock()
+		select {
+		case <-cs.done:
+			cs.lock.Unlock()
+			m.close()
+			return
+		default:
+			cs.mirror = m
+		}
+		cs.lock.Unlock()
+
+		logx.Infof("store available, watching config %s", cs.prefix)
+		if err := cs.apply(toRaw(m.listed)); err != nil {
+			logx.Errorf("bad config %s, ignored: %v", cs.prefix, err)
+		}
+		cs.run(m)
+		return
+	}
+}
+
+// run applies the values once per watch response, so the changes of a revision, like the ones
+// of a Commit, are validated and applied together instead of in intermediate states.
+func (cs *ConfigSource) run(m *mirror) {
+	for {
+		select {
+		case kvs, ok := <-m.C():
+			if !ok {
+				return
+			}
+
+			if err := cs.apply(toRaw(kvs)); err != nil {
+				logx.Errorf("bad config %s, ignored: %v", cs.prefix, err)
+			}
+		case <-cs.done:
+			return
+		}
+	}
+}
+
+func toRaw(kvs map[string]KeyValue) map[string]string {
+	raw := make(map[string]string, len(kvs))
+	for key, kv := range kvs {
+		raw[key] = kv.Value
+	}
+
+	return raw
+}
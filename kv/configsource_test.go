@@ -0,0 +1,239 @@
+package kv
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tx991020/utils/etcdv3"
+)
+
+var errUnavailable = errors.New("unavailable")
+
+type (
+	testConfig struct {
+		Name string `json:"name" yaml:"name" toml:"name"`
+		Port int    `json:"port" yaml:"port" toml:"port"`
+	}
+
+	// unavailableStore fails to list the keys until available.
+	unavailableStore struct {
+		Store
+		available int32
+	}
+)
+
+func (c *testConfig) Validate() error {
+	if c.Port <= 0 {
+		return errors.New("bad port")
+	}
+
+	return nil
+}
+
+func (s *unavailableStore) GetPrefix(ctx context.Context, prefix string) ([]KeyValue, int64, error) {
+	if atomic.LoadInt32(&s.available) == 0 {
+		return nil, 0, errUnavailable
+	}
+
+	return s.Store.GetPrefix(ctx, prefix)
+}
+
+func TestConfigSource(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+	_, err := store.Put(ctx, "/test/config/app/a.yaml", "name: app\nport: 80")
+	assert.Nil(t, err)
+	_, err = store.Put(ctx, "/test/config/app/b", `{"port": 81}`)
+	assert.Nil(t, err)
+
+	var c testConfig
+	cs, err := NewConfigSource(ctx, store, "/test/config/app/", &c)
+	assert.Nil(t, err)
+	defer cs.Close()
+	assert.Equal(t, testConfig{Name: "app", Port: 81}, c)
+	assert.Equal(t, etcdv3.ErrNoPreviousConfig, cs.Rollback(ctx))
+
+	changes := make(chan *testConfig, 10)
+	cs.Subscribe(func(old, new interface{}) {
+		changes <- new.(*testConfig)
+	})
+
+	_, err = store.Put(ctx, "/test/config/app/b", `{"port": 82}`)
+	assert.Nil(t, err)
+	assert.Equal(t, &testConfig{Name: "app", Port: 82}, waitConfig(t, changes))
+
+	// invalid configs are not applied
+	_, err = store.Put(ctx, "/test/config/app/b", `{"port": 0}`)
+	assert.Nil(t, err)
+	_, err = store.Put(ctx, "/test/config/app/c.toml", `name = "toml"`)
+	assert.Nil(t, err)
+	waitLatest(t, cs, "/test/config/app/c.toml", true)
+	assert.Equal(t, &testConfig{Name: "app", Port: 82}, cs.Value())
+
+	// roll back to the current config
+	assert.Nil(t, cs.Rollback(ctx))
+	assert.Equal(t, &testConfig{Name: "app", Port: 82}, waitLatest(t, cs, "/test/config/app/c.toml", false))
+
+	_, err = store.Put(ctx, "/test/config/app/c.toml", `name = "toml"`)
+	assert.Nil(t, err)
+	assert.Equal(t, &testConfig{Name: "toml", Port: 82}, waitConfig(t, changes))
+
+	// roll back to the previous config
+	assert.Nil(t, cs.Rollback(ctx))
+	assert.Equal(t, &testConfig{Name: "app", Port: 82}, waitConfig(t, changes))
+}
+
+func TestConfigSourceCommit(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+	_, err := store.Put(ctx, "/test/config/txn/a.json", `{"name": "80"}`)
+	assert.Nil(t, err)
+	_, err = store.Put(ctx, "/test/config/txn/b.json", `{"port": 80}`)
+	assert.Nil(t, err)
+
+	// the name and the port must be changed together
+	var c testConfig
+	cs, err := NewConfigSource(ctx, store, "/test/config/txn/", &c,
+		etcdv3.WithConfigValidator(func(v interface{}) error {
+			if c := v.(*testConfig); c.Name != strconv.Itoa(c.Port) {
+				return errors.New("inconsistent name and port")
+			}
+			return nil
+		}))
+	assert.Nil(t, err)
+	defer cs.Close()
+
+	changes := make(chan *testConfig, 10)
+	cs.Subscribe(func(old, new interface{}) {
+		changes <- new.(*testConfig)
+	})
+
+	_, err = store.Commit(ctx,
+		OpPut("/test/config/txn/a.json", `{"name": "81"}`),
+		OpPut("/test/config/txn/b.json", `{"port": 81}`),
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, &testConfig{Name: "81", Port: 81}, waitConfig(t, changes))
+
+	// rolled back in one commit too
+	assert.Nil(t, cs.Rollback(ctx))
+	assert.Equal(t, &testConfig{Name: "80", Port: 80}, waitConfig(t, changes))
+	time.Sleep(time.Millisecond * 100)
+	assert.Len(t, changes, 0)
+}
+
+func TestConfigSourceBadConfig(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+	_, err := store.Put(ctx, "/test/config/bad/a.json", `{"port": 0}`)
+	assert.Nil(t, err)
+
+	var c testConfig
+	_, err = NewConfigSource(ctx, store, "/test/config/bad/", &c)
+	assert.NotNil(t, err)
+	_, err = NewConfigSource(ctx, store, "/test/config/bad/", c)
+	assert.NotNil(t, err)
+
+	_, err = NewConfigSource(ctx, store, "/test/config/bad/", &c,
+		etcdv3.WithConfigValidator(func(v interface{}) error {
+			return nil
+		}))
+	assert.NotNil(t, err)
+	_, err = NewConfigSource(ctx, store, "/test/config/bad/", &c, etcdv3.WithConfigFormat("xml"))
+	assert.NotNil(t, err)
+}
+
+func TestConfigSourceCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	cacheFile := filepath.Join(dir, "cache.json")
+
+	store := &unavailableStore{Store: NewMemoryStore(), available: 1}
+	ctx := context.Background()
+	_, err = store.Put(ctx, "/test/config/cached/a.json", `{"name": "cached", "port": 80}`)
+	assert.Nil(t, err)
+
+	var c testConfig
+	cs, err := NewConfigSource(ctx, store, "/test/config/cached/", &c, etcdv3.WithConfigCache(cacheFile))
+	assert.Nil(t, err)
+	assert.Nil(t, cs.Close())
+	_, err = store.Put(ctx, "/test/config/cached/a.json", `{"name": "cached", "port": 81}`)
+	assert.Nil(t, err)
+
+	// the store is unavailable, loaded from cache
+	atomic.StoreInt32(&store.available, 0)
+	_, err = NewConfigSource(ctx, store, "/test/config/cached/", &c)
+	assert.Equal(t, errUnavailable, err)
+	cs, err = NewConfigSource(ctx, store, "/test/config/cached/", &c, etcdv3.WithConfigCache(cacheFile))
+	assert.Nil(t, err)
+	defer cs.Close()
+	assert.Equal(t, testConfig{Name: "cached", Port: 80}, c)
+
+	// reloaded from the store in background
+	changes := make(chan *testConfig, 10)
+	cs.Subscribe(func(old, new interface{}) {
+		changes <- new.(*testConfig)
+	})
+	atomic.StoreInt32(&store.available, 1)
+	assert.Equal(t, &testConfig{Name: "cached", Port: 81}, waitConfig(t, changes))
+}
+
+func TestConfigSourceEtcd(t *testing.T) {
+	store := NewEtcdStore(newTestClient())
+	ctx := context.Background()
+	_, err := store.DeletePrefix(ctx, "/test/config/etcd/")
+	assert.Nil(t, err)
+	_, err = store.Put(ctx, "/test/config/etcd/a.json", `{"name": "etcd", "port": 80}`)
+	assert.Nil(t, err)
+
+	var c testConfig
+	cs, err := NewConfigSource(ctx, store, "/test/config/etcd/", &c)
+	assert.Nil(t, err)
+	defer cs.Close()
+	assert.Equal(t, testConfig{Name: "etcd", Port: 80}, c)
+
+	changes := make(chan *testConfig, 10)
+	cs.Subscribe(func(old, new interface{}) {
+		changes <- new.(*testConfig)
+	})
+	_, err = store.Put(ctx, "/test/config/etcd/a.json", `{"name": "etcd", "port": 81}`)
+	assert.Nil(t, err)
+	assert.Equal(t, &testConfig{Name: "etcd", Port: 81}, waitConfig(t, changes))
+	assert.Nil(t, cs.Rollback(ctx))
+	assert.Equal(t, &testConfig{Name: "etcd", Port: 80}, waitConfig(t, changes))
+}
+
+func waitConfig(t *testing.T, changes <-chan *testConfig) *testConfig {
+	select {
+	case c := <-changes:
+		return c
+	case <-time.After(time.Second * 5):
+		t.Fatal("config not changed")
+		return nil
+	}
+}
+
+func waitLatest(t *testing.T, cs *ConfigSource, key string, exists bool) interface{} {
+	deadline := time.Now().Add(time.Second * 5)
+	for time.Now().Before(deadline) {
+		cs.lock.RLock()
+		_, ok := cs.latest[key]
+		cs.lock.RUnlock()
+		if ok == exists {
+			return cs.Value()
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	t.Fatal("config not changed")
+	return nil
+}
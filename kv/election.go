@@ -0,0 +1,279 @@
+package kv
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/tal-tech/go-zero/core/logx"
+	"github.com/tx991020/utils/etcdv3"
+)
+
+const (
+	defaultElectionTTL    = time.Second * 10
+	campaignRetryInterval = time.Second
+	resignTimeout         = time.Second * 3
+)
+
+// Election is a leader election on a prefix in a Store, the candidate that campaigns first is elected.
+// The leader holds the lock of the prefix, and puts its value with a lease that is kept alive.
+// An Election campaigns once at a time, use one Election for each candidate.
+type Election struct {
+	store  Store
+	prefix string
+	ttl    time.Duration
+	lock   sync.Mutex
+	// the leadership, unlock is nil if not the leader
+	unlock func() error
+	lease  LeaseID
+	lost   chan struct{}
+	stop   chan struct{}
+}
+
+// NewElection returns an Election on prefix, the lease of the leader is of ttl.
+func NewElection(store Store, prefix string, ttl time.Duration) *Election {
+	if ttl <= 0 {
+		ttl = defaultElectionTTL
+	}
+
+	return &Election{
+		store:  store,
+		prefix: prefix,
+		ttl:    ttl,
+	}
+}
+
+// Campaign puts val as a candidate and waits until elected as leader.
+func (election *Election) Campaign(ctx context.Context, val string) error {
+	unlock, err := election.store.Lock(ctx, election.prefix+"/lock")
+	if err != nil {
+		return err
+	}
+
+	lease, err := election.store.Grant(ctx, election.ttl)
+	if err != nil {
+		unlock()
+		return err
+	}
+	if _, err = election.store.Put(ctx, election.leaderKey(), val, WithLease(lease)); err != nil {
+		election.store.Revoke(context.Background(), lease)
+		unlock()
+		return err
+	}
+
+	lost := make(chan struct{})
+	stop := make(chan struct{})
+	election.lock.Lock()
+	election.unlock = unlock
+	election.lease = lease
+	election.lost = lost
+	election.stop = stop
+	election.lock.Unlock()
+	go election.keepAlive(lease, lost, stop)
+
+	return nil
+}
+
+// Resign gives up the leadership to start a new election, does nothing if not the leader.
+func (election *Election) Resign(ctx context.Context) error {
+	election.lock.Lock()
+	unlock, lease := election.unlock, election.lease
+	if unlock != nil {
+		close(election.stop)
+		election.unlock = nil
+	}
+	election.lock.Unlock()
+
+	if unlock == nil {
+		return nil
+	}
+
+	// revoking the lease deletes the value of the leader as well
+	err := election.store.Revoke(ctx, lease)
+	if err == ErrLeaseNotFound {
+		err = nil
+	}
+	if uerr := unlock(); err == nil {
+		err = uerr
+	}
+	return err
+}
+
+// IsLeader checks if it's the leader, the leadership might be lost without being notified yet.
+func (election *Election) IsLeader() bool {
+	return election.OnLost() != nil
+}
+
+// OnLost returns a channel that is closed if the lease is lost while being the leader,
+// nil if not the leader.
+func (election *Election) OnLost() <-chan struct{} {
+	election.lock.Lock()
+	defer election.lock.Unlock()
+
+	if election.unlock == nil {
+		return nil
+	}
+
+	return election.lost
+}
+
+// Leader returns the value of the current leader, or etcdv3.ErrNoLeader if no leader elected.
+func (election *Election) Leader(ctx context.Context) (string, error) {
+	kv, ok, err := election.store.Get(ctx, election.leaderKey())
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", etcdv3.ErrNoLeader
+	}
+
+	return kv.Value, nil
+}
+
+// Observe returns a channel that receives the value of the leader on every change,
+// the channel is closed after ctx is done.
+func (election *Election) Observe(ctx context.Context) <-chan string {
+	leaders := make(chan string)
+	go func() {
+		defer close(leaders)
+
+		key := election.leaderKey()
+		m, err := newMirror(ctx, election.store, key)
+		if err != nil {
+			logx.Errorf("observe election %s failed: %v", election.prefix, err)
+			return
+		}
+		defer m.close()
+
+		var last int64
+		kvs := m.listed
+		for {
+			// the mirror of the key might have the keys that the key is the prefix of
+			if kv, ok := kvs[key]; ok && kv.ModRevision != last {
+				last = kv.ModRevision
+				select {
+				case leaders <- kv.Value:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			select {
+			case kvs = <-m.C():
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return leaders
+}
+
+// RunAsLeader campaigns with val and runs fn once elected. The ctx of fn is canceled if the
+// leadership is lost, then it campaigns again. The leadership is resigned after fn returns.
+// It returns after fn returns while still the leader, or ctx is done.
+func (election *Election) RunAsLeader(ctx context.Context, val string, fn func(ctx context.Context)) error {
+	for {
+		if err := election.Campaign(ctx, val); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			logx.Errorf("campaign %s failed: %v", election.prefix, err)
+			select {
+			case <-time.After(campaignRetryInterval):
+				continue
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		logx.Infof("elected as leader of %s", election.prefix)
+		if election.runAsLeader(ctx, fn) {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			logx.Infof("leadership of %s lost, campaign again", election.prefix)
+			election.Resign(ctx)
+			continue
+		}
+
+		resignCtx, cancel := context.WithTimeout(context.Background(), resignTimeout)
+		if err := election.Resign(resignCtx); err != nil {
+			logx.Errorf("resign %s failed: %v", election.prefix, err)
+		}
+		cancel()
+
+		return ctx.Err()
+	}
+}
+
+// Close resigns the leadership if it's the leader.
+func (election *Election) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), resignTimeout)
+	defer cancel()
+
+	return election.Resign(ctx)
+}
+
+// keepAlive keeps lease alive until stopped, lost is closed and the lock is released
+// if the lease is lost.
+func (election *Election) keepAlive(lease LeaseID, lost, stop chan struct{}) {
+	ticker := time.NewTicker(election.ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), election.ttl/3)
+		err := election.store.KeepAliveOnce(ctx, lease)
+		cancel()
+		if err == nil {
+			continue
+		}
+		if err != ErrLeaseNotFound {
+			logx.Errorf("keep leadership of %s alive failed: %v", election.prefix, err)
+			continue
+		}
+
+		close(lost)
+		// release the lock for the others to be elected
+		election.Resign(context.Background())
+		return
+	}
+}
+
+func (election *Election) leaderKey() string {
+	return election.prefix + "/leader"
+}
+
+// runAsLeader runs fn until it returns, returns true if the leadership is lost during running.
+func (election *Election) runAsLeader(ctx context.Context, fn func(ctx context.Context)) bool {
+	lost := election.OnLost()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-lost:
+			cancel()
+		case <-done:
+		}
+	}()
+
+	fn(ctx)
+
+	select {
+	case <-lost:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,128 @@
+package kv
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tx991020/utils/etcdv3"
+)
+
+func TestElection(t *testing.T) {
+	store := NewMemoryStore()
+	e1 := NewElection(store, "/test/election/basic", time.Second*10)
+	defer e1.Close()
+	e2 := NewElection(store, "/test/election/basic", time.Second*10)
+	defer e2.Close()
+
+	_, err := e1.Leader(context.Background())
+	assert.Equal(t, etcdv3.ErrNoLeader, err)
+
+	assert.Nil(t, e1.Campaign(context.Background(), "a"))
+	assert.True(t, e1.IsLeader())
+	leader, err := e2.Leader(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, "a", leader)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*200)
+	assert.Equal(t, context.DeadlineExceeded, e2.Campaign(ctx, "b"))
+	cancel()
+	assert.False(t, e2.IsLeader())
+
+	elected := make(chan error)
+	go func() {
+		elected <- e2.Campaign(context.Background(), "b")
+	}()
+	assert.Nil(t, e1.Resign(context.Background()))
+	assert.False(t, e1.IsLeader())
+	assert.Nil(t, <-elected)
+	leader, err = e1.Leader(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, "b", leader)
+	assert.Nil(t, e2.Resign(context.Background()))
+	_, err = e1.Leader(context.Background())
+	assert.Equal(t, etcdv3.ErrNoLeader, err)
+}
+
+func TestElectionObserve(t *testing.T) {
+	store := NewMemoryStore()
+	e1 := NewElection(store, "/test/election/observe", time.Second*10)
+	defer e1.Close()
+	e2 := NewElection(store, "/test/election/observe", time.Second*10)
+	defer e2.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	leaders := e1.Observe(ctx)
+	assert.Nil(t, e1.Campaign(context.Background(), "a"))
+	assert.Equal(t, "a", waitLeader(t, leaders))
+
+	go e2.Campaign(context.Background(), "b")
+	assert.Nil(t, e1.Resign(context.Background()))
+	assert.Equal(t, "b", waitLeader(t, leaders))
+
+	cancel()
+	for range leaders {
+	}
+}
+
+func TestElectionLeaseLost(t *testing.T) {
+	store := NewMemoryStore()
+	e1 := NewElection(store, "/test/election/lost", time.Second*3)
+	defer e1.Close()
+	e2 := NewElection(store, "/test/election/lost", time.Second*3)
+	defer e2.Close()
+
+	assert.Nil(t, e1.Campaign(context.Background(), "a"))
+	lost := e1.OnLost()
+	e1.lock.Lock()
+	lease := e1.lease
+	e1.lock.Unlock()
+	assert.Nil(t, store.Revoke(context.Background(), lease))
+
+	select {
+	case <-lost:
+	case <-time.After(time.Second * 5):
+		t.Fatal("leadership not lost")
+	}
+	assert.False(t, e1.IsLeader())
+
+	// the lock is released for the others
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	assert.Nil(t, e2.Campaign(ctx, "b"))
+}
+
+func TestRunAsLeader(t *testing.T) {
+	election := NewElection(NewMemoryStore(), "/test/election/run", time.Second*10)
+	defer election.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	stopped := make(chan struct{})
+	errs := make(chan error)
+	go func() {
+		errs <- election.RunAsLeader(ctx, "a", func(ctx context.Context) {
+			close(started)
+			<-ctx.Done()
+			close(stopped)
+		})
+	}()
+
+	<-started
+	assert.True(t, election.IsLeader())
+	cancel()
+	<-stopped
+	assert.Equal(t, context.Canceled, <-errs)
+	assert.False(t, election.IsLeader())
+}
+
+func waitLeader(t *testing.T, leaders <-chan string) string {
+	select {
+	case leader := <-leaders:
+		return leader
+	case <-time.After(time.Second * 5):
+		t.Fatal("leader not changed")
+		return ""
+	}
+}
@@ -0,0 +1,222 @@
+package kv
+
+import (
+	"context"
+	"time"
+
+	"github.com/tx991020/utils/etcdv3"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
+)
+
+const unlockTimeout = time.Second * 3
+
+type etcdStore struct {
+	client *etcdv3.Client
+}
+
+// NewEtcdStore returns a Store backed by etcd.
+func NewEtcdStore(client *etcdv3.Client) Store {
+	return &etcdStore{
+		client: client,
+	}
+}
+
+func (s *etcdStore) Get(ctx context.Context, key string) (KeyValue, bool, error) {
+	resp, err := s.client.Get(ctx, key)
+	if err != nil {
+		return KeyValue{}, false, toError(err)
+	}
+	if len(resp.Kvs) == 0 {
+		return KeyValue{}, false, nil
+	}
+
+	return toKeyValue(resp.Kvs[0]), true, nil
+}
+
+func (s *etcdStore) GetPrefix(ctx context.Context, prefix string) ([]KeyValue, int64, error) {
+	resp, err := s.client.Get(ctx, prefix, clientv3.WithPrefix(),
+		clientv3.WithSort(clientv3.SortByKey, clientv3.SortAscend))
+	if err != nil {
+		return nil, 0, toError(err)
+	}
+
+	kvs := make([]KeyValue, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		kvs = append(kvs, toKeyValue(kv))
+	}
+
+	return kvs, resp.Header.Revision, nil
+}
+
+func (s *etcdStore) Put(ctx context.Context, key, value string, opts ...PutOption) (int64, error) {
+	var ops []clientv3.OpOption
+	if options := buildPutOptions(opts); options.lease != 0 {
+		ops = append(ops, clientv3.WithLease(clientv3.LeaseID(options.lease)))
+	}
+
+	resp, err := s.client.Put(ctx, key, value, ops...)
+	if err != nil {
+		return 0, toError(err)
+	}
+
+	return resp.Header.Revision, nil
+}
+
+func (s *etcdStore) Delete(ctx context.Context, key string) (bool, error) {
+	resp, err := s.client.Delete(ctx, key)
+	if err != nil {
+		return false, toError(err)
+	}
+
+	return resp.Deleted > 0, nil
+}
+
+func (s *etcdStore) DeletePrefix(ctx context.Context, prefix string) (int64, error) {
+	resp, err := s.client.Delete(ctx, prefix, clientv3.WithPrefix())
+	if err != nil {
+		return 0, toError(err)
+	}
+
+	return resp.Deleted, nil
+}
+
+func (s *etcdStore) Commit(ctx context.Context, ops ...Op) (int64, error) {
+	txnOps := make([]clientv3.Op, 0, len(ops))
+	for _, op := range ops {
+		if op.Delete {
+			txnOps = append(txnOps, clientv3.OpDelete(op.Key))
+		} else {
+			txnOps = append(txnOps, clientv3.OpPut(op.Key, op.Value))
+		}
+	}
+
+	resp, err := s.client.Txn(ctx).Then(txnOps...).Commit()
+	if err != nil {
+		return 0, toError(err)
+	}
+
+	return resp.Header.Revision, nil
+}
+
+func (s *etcdStore) Watch(ctx context.Context, prefix string, rev int64) <-chan WatchResponse {
+	opts := []clientv3.OpOption{clientv3.WithPrefix()}
+	if rev > 0 {
+		opts = append(opts, clientv3.WithRev(rev))
+	}
+	wch := s.client.Watch(clientv3.WithRequireLeader(ctx), prefix, opts...)
+
+	ch := make(chan WatchResponse)
+	go func() {
+		defer close(ch)
+
+		for resp := range wch {
+			if ctx.Err() != nil {
+				return
+			}
+			if resp.IsProgressNotify() {
+				continue
+			}
+
+			r := WatchResponse{
+				Err: toError(resp.Err()),
+			}
+			for _, ev := range resp.Events {
+				r.Events = append(r.Events, toEvent(ev))
+			}
+
+			select {
+			case ch <- r:
+			case <-ctx.Done():
+				return
+			}
+			if r.Err != nil {
+				return
+			}
+		}
+	}()
+
+	return ch
+}
+
+func (s *etcdStore) Grant(ctx context.Context, ttl time.Duration) (LeaseID, error) {
+	resp, err := s.client.Grant(ctx, leaseSeconds(ttl))
+	if err != nil {
+		return 0, toError(err)
+	}
+
+	return LeaseID(resp.ID), nil
+}
+
+func (s *etcdStore) KeepAliveOnce(ctx context.Context, id LeaseID) error {
+	_, err := s.client.KeepAliveOnce(ctx, clientv3.LeaseID(id))
+	return toError(err)
+}
+
+func (s *etcdStore) Revoke(ctx context.Context, id LeaseID) error {
+	_, err := s.client.Revoke(ctx, clientv3.LeaseID(id))
+	return toError(err)
+}
+
+func (s *etcdStore) Lock(ctx context.Context, key string) (func() error, error) {
+	session, err := concurrency.NewSession(s.client.Client)
+	if err != nil {
+		return nil, toError(err)
+	}
+
+	mutex := concurrency.NewMutex(session, key)
+	if err := mutex.Lock(ctx); err != nil {
+		session.Close()
+		return nil, toError(err)
+	}
+
+	return func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), unlockTimeout)
+		defer cancel()
+
+		err := mutex.Unlock(ctx)
+		// closing the session revokes the lease, which deletes the key as well
+		if cerr := session.Close(); err == nil {
+			err = cerr
+		}
+		return toError(err)
+	}, nil
+}
+
+func toError(err error) error {
+	switch err {
+	case rpctypes.ErrCompacted:
+		return ErrCompacted
+	case rpctypes.ErrLeaseNotFound:
+		return ErrLeaseNotFound
+	default:
+		return err
+	}
+}
+
+func toEvent(ev *clientv3.Event) Event {
+	if ev.Type == clientv3.EventTypeDelete {
+		return Event{
+			Type: EventDelete,
+			Kv:   toKeyValue(ev.Kv),
+		}
+	}
+
+	return Event{
+		Type: EventPut,
+		Kv:   toKeyValue(ev.Kv),
+	}
+}
+
+func toKeyValue(kv *mvccpb.KeyValue) KeyValue {
+	return KeyValue{
+		Key:            string(kv.Key),
+		Value:          string(kv.Value),
+		CreateRevision: kv.CreateRevision,
+		ModRevision:    kv.ModRevision,
+		Version:        kv.Version,
+		Lease:          LeaseID(kv.Lease),
+	}
+}
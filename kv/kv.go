@@ -0,0 +1,140 @@
+// Package kv is a key value store abstraction with an etcd and an in-memory implementation.
+// The registry, the config source and the election built on Store run on etcd in production,
+// and on the in-memory Store in tests.
+package kv
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+)
+
+const (
+	// EventPut is the type of the events that put keys.
+	EventPut EventType = iota
+	// EventDelete is the type of the events that delete keys.
+	EventDelete
+)
+
+var (
+	// ErrCompacted is sent on watching from a revision that is compacted.
+	ErrCompacted = errors.New("kv: revision compacted")
+	// ErrLeaseNotFound is returned on using a lease that is revoked or expired.
+	ErrLeaseNotFound = errors.New("kv: lease not found")
+)
+
+type (
+	// A Store is a key value store with revisions, watches, leases and locks, like etcd.
+	// Every change increases the revision of the store by one, the changes of a call are in the same revision.
+	Store interface {
+		// Get returns the key value of key, false if it doesn't exist.
+		Get(ctx context.Context, key string) (KeyValue, bool, error)
+		// GetPrefix returns the key values under prefix sorted by keys, and the revision they are read at.
+		GetPrefix(ctx context.Context, prefix string) ([]KeyValue, int64, error)
+		// Put puts value on key, returns the revision of the change.
+		Put(ctx context.Context, key, value string, opts ...PutOption) (int64, error)
+		// Delete deletes key, returns false if it doesn't exist.
+		Delete(ctx context.Context, key string) (bool, error)
+		// DeletePrefix deletes the keys under prefix, returns the number of the deleted keys.
+		DeletePrefix(ctx context.Context, prefix string) (int64, error)
+		// Commit puts and deletes the keys of ops in the same revision, returns the revision.
+		Commit(ctx context.Context, ops ...Op) (int64, error)
+		// Watch watches the changes under prefix from rev, 0 means from now on.
+		// The channel is closed when ctx is done, or after a response with an error.
+		Watch(ctx context.Context, prefix string, rev int64) <-chan WatchResponse
+		// Grant grants a lease of ttl, the keys put with the lease are deleted when it expires.
+		Grant(ctx context.Context, ttl time.Duration) (LeaseID, error)
+		// KeepAliveOnce renews the lease once.
+		KeepAliveOnce(ctx context.Context, id LeaseID) error
+		// Revoke revokes the lease and deletes the keys put with it.
+		Revoke(ctx context.Context, id LeaseID) error
+		// Lock acquires the lock of key, blocks until acquired or ctx is done.
+		Lock(ctx context.Context, key string) (unlock func() error, err error)
+	}
+
+	// KeyValue is a key value with its revisions.
+	KeyValue struct {
+		Key            string
+		Value          string
+		CreateRevision int64
+		ModRevision    int64
+		// Version is the times that the key is put since created.
+		Version int64
+		Lease   LeaseID
+	}
+
+	// EventType is the type of events.
+	EventType int
+
+	// An Event is a change of a key, Kv.ModRevision is the revision of the change.
+	Event struct {
+		Type EventType
+		Kv   KeyValue
+	}
+
+	// WatchResponse is the events in the order of revisions, or an error.
+	WatchResponse struct {
+		Events []Event
+		Err    error
+	}
+
+	// An Op is a put or a delete of a key in a Commit.
+	Op struct {
+		Key    string
+		Value  string
+		Delete bool
+	}
+
+	// LeaseID is the id of a lease, 0 means no lease.
+	LeaseID int64
+
+	// PutOption customizes a put.
+	PutOption func(opts *putOptions)
+
+	putOptions struct {
+		lease LeaseID
+	}
+)
+
+// OpPut returns an Op that puts value on key.
+func OpPut(key, value string) Op {
+	return Op{
+		Key:   key,
+		Value: value,
+	}
+}
+
+// OpDelete returns an Op that deletes key.
+func OpDelete(key string) Op {
+	return Op{
+		Key:    key,
+		Delete: true,
+	}
+}
+
+// WithLease puts the key with the lease.
+func WithLease(id LeaseID) PutOption {
+	return func(opts *putOptions) {
+		opts.lease = id
+	}
+}
+
+func buildPutOptions(opts []PutOption) putOptions {
+	var options putOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return options
+}
+
+// leaseSeconds returns ttl in seconds, rounded up, at least a second.
+func leaseSeconds(ttl time.Duration) int64 {
+	seconds := int64(math.Ceil(ttl.Seconds()))
+	if seconds < 1 {
+		return 1
+	}
+
+	return seconds
+}
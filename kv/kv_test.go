@@ -0,0 +1,297 @@
+package kv
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testPrefixes int64
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, NewMemoryStore())
+}
+
+func TestEtcdStore(t *testing.T) {
+	testStore(t, NewEtcdStore(newTestClient()))
+}
+
+func TestMemoryStoreCompacted(t *testing.T) {
+	s := NewMemoryStore(WithHistorySize(2))
+	ctx := context.Background()
+	rev, err := s.Put(ctx, "/a", "1")
+	assert.Nil(t, err)
+	s.Put(ctx, "/b", "2")
+	s.Put(ctx, "/a", "3")
+
+	ch := s.Watch(ctx, "/", rev)
+	resp, ok := <-ch
+	assert.True(t, ok)
+	assert.Equal(t, ErrCompacted, resp.Err)
+	_, ok = <-ch
+	assert.False(t, ok)
+
+	events := waitEvents(t, s.Watch(ctx, "/a", rev+1), 1)
+	assert.Equal(t, "3", events[0].Kv.Value)
+}
+
+func TestEtcdStoreCompacted(t *testing.T) {
+	client := newTestClient()
+	s := NewEtcdStore(client)
+	ctx := context.Background()
+	rev, err := s.Put(ctx, "/compacted/a", "1")
+	assert.Nil(t, err)
+	last, err := s.Put(ctx, "/compacted/a", "2")
+	assert.Nil(t, err)
+	_, err = client.Compact(ctx, last)
+	assert.Nil(t, err)
+
+	ch := s.Watch(ctx, "/compacted/", rev)
+	resp, ok := <-ch
+	assert.True(t, ok)
+	assert.Equal(t, ErrCompacted, resp.Err)
+	_, ok = <-ch
+	assert.False(t, ok)
+}
+
+func testStore(t *testing.T, s Store) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s Store, prefix string)
+	}{
+		{"put", testPut},
+		{"delete", testDelete},
+		{"commit", testCommit},
+		{"watch", testWatch},
+		{"lease", testLease},
+		{"lock", testLock},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			prefix := fmt.Sprintf("/test/kv/%d/", atomic.AddInt64(&testPrefixes, 1))
+			test.fn(t, s, prefix)
+		})
+	}
+}
+
+func testPut(t *testing.T, s Store, prefix string) {
+	ctx := context.Background()
+	_, ok, err := s.Get(ctx, prefix+"a")
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	rev1, err := s.Put(ctx, prefix+"a", "1")
+	assert.Nil(t, err)
+	rev2, err := s.Put(ctx, prefix+"a", "2")
+	assert.Nil(t, err)
+	assert.Equal(t, rev1+1, rev2)
+
+	kv, ok, err := s.Get(ctx, prefix+"a")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, KeyValue{
+		Key:            prefix + "a",
+		Value:          "2",
+		CreateRevision: rev1,
+		ModRevision:    rev2,
+		Version:        2,
+	}, kv)
+
+	rev3, err := s.Put(ctx, prefix+"b", "3")
+	assert.Nil(t, err)
+	s.Put(ctx, prefix+"0", "0")
+	s.Put(ctx, "/test/kv/other", "x")
+	kvs, rev, err := s.GetPrefix(ctx, prefix)
+	assert.Nil(t, err)
+	assert.True(t, rev > rev3)
+	assert.Equal(t, []string{prefix + "0", prefix + "a", prefix + "b"}, keysOf(kvs))
+}
+
+func testCommit(t *testing.T, s Store, prefix string) {
+	ctx := context.Background()
+	rev0, err := s.Put(ctx, prefix+"a", "1")
+	assert.Nil(t, err)
+	ch := s.Watch(ctx, prefix, rev0+1)
+
+	rev, err := s.Commit(ctx, OpPut(prefix+"b", "2"), OpDelete(prefix+"a"), OpDelete(prefix+"c"))
+	assert.Nil(t, err)
+	kvs, rev2, err := s.GetPrefix(ctx, prefix)
+	assert.Nil(t, err)
+	assert.Equal(t, rev, rev2)
+	assert.Equal(t, []string{prefix + "b"}, keysOf(kvs))
+
+	// the changes of a commit are in the same response
+	events := waitEvents(t, ch, 2)
+	assert.Equal(t, EventPut, events[0].Type)
+	assert.Equal(t, rev, events[0].Kv.ModRevision)
+	assert.Equal(t, EventDelete, events[1].Type)
+	assert.Equal(t, rev, events[1].Kv.ModRevision)
+
+	rev3, err := s.Commit(ctx, OpDelete(prefix+"c"))
+	assert.Nil(t, err)
+	assert.Equal(t, rev, rev3, "deleting nothing doesn't change the revision")
+}
+
+func testDelete(t *testing.T, s Store, prefix string) {
+	ctx := context.Background()
+	s.Put(ctx, prefix+"a", "1")
+	s.Put(ctx, prefix+"b", "2")
+	s.Put(ctx, prefix+"c", "3")
+
+	ok, err := s.Delete(ctx, prefix+"a")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	_, rev1, _ := s.GetPrefix(ctx, prefix)
+	ok, err = s.Delete(ctx, prefix+"a")
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	_, rev2, _ := s.GetPrefix(ctx, prefix)
+	assert.Equal(t, rev1, rev2, "deleting nothing doesn't change the revision")
+
+	deleted, err := s.DeletePrefix(ctx, prefix)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), deleted)
+	kvs, rev3, _ := s.GetPrefix(ctx, prefix)
+	assert.Empty(t, kvs)
+	assert.Equal(t, rev2+1, rev3, "deleting a prefix is a revision")
+
+	rev4, err := s.Put(ctx, prefix+"a", "4")
+	assert.Nil(t, err)
+	kv, _, _ := s.Get(ctx, prefix+"a")
+	assert.Equal(t, rev4, kv.CreateRevision)
+	assert.Equal(t, int64(1), kv.Version)
+}
+
+func testWatch(t *testing.T, s Store, prefix string) {
+	ctx, cancel := context.WithCancel(context.Background())
+	rev, err := s.Put(ctx, prefix+"a", "1")
+	assert.Nil(t, err)
+
+	ch := s.Watch(ctx, prefix, 0)
+	// make sure the watch is set up on etcd
+	time.Sleep(time.Millisecond * 100)
+	s.Put(ctx, prefix+"b", "2")
+	s.Put(ctx, "/test/kv/other", "x")
+	s.DeletePrefix(ctx, prefix)
+
+	events := waitEvents(t, ch, 3)
+	assert.Equal(t, EventPut, events[0].Type)
+	assert.Equal(t, prefix+"b", events[0].Kv.Key)
+	assert.Equal(t, "2", events[0].Kv.Value)
+	assert.Equal(t, Event{
+		Type: EventDelete,
+		Kv: KeyValue{
+			Key:         prefix + "a",
+			ModRevision: events[0].Kv.ModRevision + 2,
+		},
+	}, events[1])
+	assert.Equal(t, EventDelete, events[2].Type)
+	assert.Equal(t, prefix+"b", events[2].Kv.Key)
+	assert.Equal(t, events[1].Kv.ModRevision, events[2].Kv.ModRevision)
+
+	replayed := waitEvents(t, s.Watch(ctx, prefix, rev), 4)
+	assert.Equal(t, prefix+"a", replayed[0].Kv.Key)
+	assert.Equal(t, rev, replayed[0].Kv.ModRevision)
+	assert.Equal(t, events, replayed[1:])
+
+	cancel()
+	for range ch {
+	}
+}
+
+func testLease(t *testing.T, s Store, prefix string) {
+	ctx := context.Background()
+	lease, err := s.Grant(ctx, time.Second)
+	assert.Nil(t, err)
+	_, err = s.Put(ctx, prefix+"a", "1", WithLease(lease))
+	assert.Nil(t, err)
+	_, err = s.Put(ctx, prefix+"b", "2", WithLease(lease))
+	assert.Nil(t, err)
+	kv, _, _ := s.Get(ctx, prefix+"a")
+	assert.Equal(t, lease, kv.Lease)
+	// detached from the lease
+	s.Put(ctx, prefix+"b", "3")
+
+	assert.Nil(t, s.KeepAliveOnce(ctx, lease))
+	assert.Nil(t, s.Revoke(ctx, lease))
+	kvs, _, _ := s.GetPrefix(ctx, prefix)
+	assert.Equal(t, []string{prefix + "b"}, keysOf(kvs))
+
+	assert.Equal(t, ErrLeaseNotFound, s.KeepAliveOnce(ctx, lease))
+	assert.Equal(t, ErrLeaseNotFound, s.Revoke(ctx, lease))
+	_, err = s.Put(ctx, prefix+"a", "1", WithLease(lease))
+	assert.Equal(t, ErrLeaseNotFound, err)
+
+	lease, err = s.Grant(ctx, time.Second)
+	assert.Nil(t, err)
+	_, err = s.Put(ctx, prefix+"c", "1", WithLease(lease))
+	assert.Nil(t, err)
+	assert.Eventually(t, func() bool {
+		_, ok, _ := s.Get(ctx, prefix+"c")
+		return !ok
+	}, time.Second*10, time.Millisecond*100)
+}
+
+func testLock(t *testing.T, s Store, prefix string) {
+	ctx := context.Background()
+	unlock, err := s.Lock(ctx, prefix+"lock")
+	assert.Nil(t, err)
+
+	timeout, cancel := context.WithTimeout(ctx, time.Millisecond*100)
+	defer cancel()
+	_, err = s.Lock(timeout, prefix+"lock")
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	locked := make(chan func() error)
+	go func() {
+		unlock, err := s.Lock(ctx, prefix+"lock")
+		assert.Nil(t, err)
+		locked <- unlock
+	}()
+	select {
+	case <-locked:
+		t.Fatal("locked twice")
+	case <-time.After(time.Millisecond * 100):
+	}
+
+	assert.Nil(t, unlock())
+	select {
+	case unlock = <-locked:
+		assert.Nil(t, unlock())
+	case <-time.After(time.Second * 3):
+		t.Fatal("not locked after unlocked")
+	}
+}
+
+func waitEvents(t *testing.T, ch <-chan WatchResponse, n int) []Event {
+	var events []Event
+	for len(events) < n {
+		select {
+		case resp, ok := <-ch:
+			if !ok {
+				t.Fatal("watch closed")
+			}
+			assert.Nil(t, resp.Err)
+			events = append(events, resp.Events...)
+		case <-time.After(time.Second * 3):
+			t.Fatalf("got %d events, expected %d", len(events), n)
+		}
+	}
+
+	return events
+}
+
+func keysOf(kvs []KeyValue) []string {
+	keys := make([]string, 0, len(kvs))
+	for _, kv := range kvs {
+		keys = append(keys, kv.Key)
+	}
+
+	return keys
+}
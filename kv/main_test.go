@@ -0,0 +1,29 @@
+package kv
+
+import (
+	"testing"
+
+	"github.com/tx991020/utils/etcdv3"
+	"github.com/tx991020/utils/etcdv3/etcdv3test"
+)
+
+// testEndpoints are the endpoints of the embedded etcd server that the tests run against.
+var testEndpoints []string
+
+func TestMain(m *testing.M) {
+	etcdv3test.Run(func(endpoints []string) int {
+		testEndpoints = endpoints
+		return m.Run()
+	})
+}
+
+func newTestClient() *etcdv3.Client {
+	config := etcdv3.DefaultConfig()
+	config.Endpoints = testEndpoints
+	client, err := etcdv3.NewClient(config)
+	if err != nil {
+		panic(err)
+	}
+
+	return client
+}
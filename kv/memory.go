@@ -0,0 +1,441 @@
+package kv
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tal-tech/go-zero/core/lang"
+)
+
+const defaultHistorySize = 1000
+
+type (
+	// MemoryOption customizes a memory Store.
+	MemoryOption func(s *memoryStore)
+
+	memoryStore struct {
+		lock sync.Mutex
+		rev  int64
+		kvs  map[string]KeyValue
+		// history is the changes after compactRev, one revision an item
+		history     []revisionEvents
+		historySize int
+		compactRev  int64
+		watchers    map[*memoryWatcher]lang.PlaceholderType
+		leases      map[LeaseID]*memoryLease
+		lastLease   LeaseID
+		locks       map[string]chan lang.PlaceholderType
+	}
+
+	revisionEvents struct {
+		rev    int64
+		events []Event
+	}
+
+	memoryLease struct {
+		ttl      time.Duration
+		deadline time.Time
+		keys     map[string]lang.PlaceholderType
+		timer    *time.Timer
+	}
+
+	memoryWatcher struct {
+		prefix  string
+		lock    sync.Mutex
+		pending []WatchResponse
+		notify  chan lang.PlaceholderType
+	}
+)
+
+// WithHistorySize keeps the changes of the latest size revisions to watch from,
+// the older revisions are compacted.
+func WithHistorySize(size int) MemoryOption {
+	return func(s *memoryStore) {
+		s.historySize = size
+	}
+}
+
+// NewMemoryStore returns a Store in memory, with the same revision and watch semantics as etcd.
+func NewMemoryStore(opts ...MemoryOption) Store {
+	s := &memoryStore{
+		// etcd starts from revision 1 as well
+		rev:         1,
+		kvs:         make(map[string]KeyValue),
+		historySize: defaultHistorySize,
+		watchers:    make(map[*memoryWatcher]lang.PlaceholderType),
+		leases:      make(map[LeaseID]*memoryLease),
+		locks:       make(map[string]chan lang.PlaceholderType),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+func (s *memoryStore) Get(_ context.Context, key string) (KeyValue, bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	kv, ok := s.kvs[key]
+	return kv, ok, nil
+}
+
+func (s *memoryStore) GetPrefix(_ context.Context, prefix string) ([]KeyValue, int64, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var kvs []KeyValue
+	for key, kv := range s.kvs {
+		if strings.HasPrefix(key, prefix) {
+			kvs = append(kvs, kv)
+		}
+	}
+	sort.Slice(kvs, func(i, j int) bool {
+		return kvs[i].Key < kvs[j].Key
+	})
+
+	return kvs, s.rev, nil
+}
+
+func (s *memoryStore) Put(_ context.Context, key, value string, opts ...PutOption) (int64, error) {
+	options := buildPutOptions(opts)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if options.lease != 0 {
+		if _, ok := s.leases[options.lease]; !ok {
+			return 0, ErrLeaseNotFound
+		}
+	}
+
+	s.rev++
+	s.commit([]Event{s.put(key, value, options.lease)})
+
+	return s.rev, nil
+}
+
+func (s *memoryStore) Delete(_ context.Context, key string) (bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.deleteKeys([]string{key}) > 0, nil
+}
+
+func (s *memoryStore) DeletePrefix(_ context.Context, prefix string) (int64, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var keys []string
+	for key := range s.kvs {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+
+	return s.deleteKeys(keys), nil
+}
+
+func (s *memoryStore) Commit(_ context.Context, ops ...Op) (int64, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	// like etcd, the revision is not increased if nothing is changed
+	changed := false
+	for _, op := range ops {
+		if _, ok := s.kvs[op.Key]; ok || !op.Delete {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return s.rev, nil
+	}
+
+	s.rev++
+	events := make([]Event, 0, len(ops))
+	for _, op := range ops {
+		if !op.Delete {
+			events = append(events, s.put(op.Key, op.Value, 0))
+			continue
+		}
+
+		if kv, ok := s.kvs[op.Key]; ok {
+			delete(s.kvs, kv.Key)
+			s.detach(kv)
+			events = append(events, Event{
+				Type: EventDelete,
+				Kv: KeyValue{
+					Key:         kv.Key,
+					ModRevision: s.rev,
+				},
+			})
+		}
+	}
+	s.commit(events)
+
+	return s.rev, nil
+}
+
+func (s *memoryStore) Watch(ctx context.Context, prefix string, rev int64) <-chan WatchResponse {
+	w := &memoryWatcher{
+		prefix: prefix,
+		notify: make(chan lang.PlaceholderType, 1),
+	}
+
+	s.lock.Lock()
+	if rev > 0 && rev <= s.compactRev {
+		w.push(WatchResponse{Err: ErrCompacted})
+	} else if rev > 0 {
+		for _, item := range s.history {
+			if item.rev >= rev {
+				w.push(WatchResponse{Events: item.events})
+			}
+		}
+	}
+	s.watchers[w] = lang.Placeholder
+	s.lock.Unlock()
+
+	ch := make(chan WatchResponse)
+	go func() {
+		defer func() {
+			s.lock.Lock()
+			delete(s.watchers, w)
+			s.lock.Unlock()
+			close(ch)
+		}()
+		w.run(ctx, ch)
+	}()
+
+	return ch
+}
+
+func (s *memoryStore) Grant(_ context.Context, ttl time.Duration) (LeaseID, error) {
+	ttl = time.Duration(leaseSeconds(ttl)) * time.Second
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.lastLease++
+	id := s.lastLease
+	s.leases[id] = &memoryLease{
+		ttl:      ttl,
+		deadline: time.Now().Add(ttl),
+		keys:     make(map[string]lang.PlaceholderType),
+		timer: time.AfterFunc(ttl, func() {
+			s.expire(id)
+		}),
+	}
+
+	return id, nil
+}
+
+func (s *memoryStore) KeepAliveOnce(_ context.Context, id LeaseID) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	l, ok := s.leases[id]
+	if !ok {
+		return ErrLeaseNotFound
+	}
+
+	l.deadline = time.Now().Add(l.ttl)
+	l.timer.Reset(l.ttl)
+	return nil
+}
+
+func (s *memoryStore) Revoke(_ context.Context, id LeaseID) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.leases[id]; !ok {
+		return ErrLeaseNotFound
+	}
+
+	s.revoke(id)
+	return nil
+}
+
+func (s *memoryStore) Lock(ctx context.Context, key string) (func() error, error) {
+	s.lock.Lock()
+	sem, ok := s.locks[key]
+	if !ok {
+		sem = make(chan lang.PlaceholderType, 1)
+		s.locks[key] = sem
+	}
+	s.lock.Unlock()
+
+	select {
+	case sem <- lang.Placeholder:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	var once sync.Once
+	return func() error {
+		once.Do(func() {
+			<-sem
+		})
+		return nil
+	}, nil
+}
+
+// commit adds the events of the current revision to the history, and sends them to the watchers.
+func (s *memoryStore) commit(events []Event) {
+	s.history = append(s.history, revisionEvents{
+		rev:    s.rev,
+		events: events,
+	})
+	if len(s.history) > s.historySize {
+		n := len(s.history) - s.historySize
+		s.compactRev = s.history[n-1].rev
+		s.history = append([]revisionEvents(nil), s.history[n:]...)
+	}
+
+	for w := range s.watchers {
+		w.push(WatchResponse{Events: events})
+	}
+}
+
+// deleteKeys deletes the existing keys in a revision, returns the number of the deleted keys.
+func (s *memoryStore) deleteKeys(keys []string) int64 {
+	var kvs []KeyValue
+	for _, key := range keys {
+		if kv, ok := s.kvs[key]; ok {
+			kvs = append(kvs, kv)
+		}
+	}
+	if len(kvs) == 0 {
+		return 0
+	}
+
+	sort.Slice(kvs, func(i, j int) bool {
+		return kvs[i].Key < kvs[j].Key
+	})
+	s.rev++
+	events := make([]Event, 0, len(kvs))
+	for _, kv := range kvs {
+		delete(s.kvs, kv.Key)
+		s.detach(kv)
+		events = append(events, Event{
+			Type: EventDelete,
+			Kv: KeyValue{
+				Key:         kv.Key,
+				ModRevision: s.rev,
+			},
+		})
+	}
+	s.commit(events)
+
+	return int64(len(kvs))
+}
+
+// put puts value on key in the current revision, returns the event of it.
+func (s *memoryStore) put(key, value string, lease LeaseID) Event {
+	kv := KeyValue{
+		Key:            key,
+		Value:          value,
+		CreateRevision: s.rev,
+		ModRevision:    s.rev,
+		Version:        1,
+		Lease:          lease,
+	}
+	if old, ok := s.kvs[key]; ok {
+		kv.CreateRevision = old.CreateRevision
+		kv.Version = old.Version + 1
+		s.detach(old)
+	}
+	s.kvs[key] = kv
+	if l, ok := s.leases[lease]; ok {
+		l.keys[key] = lang.Placeholder
+	}
+
+	return Event{
+		Type: EventPut,
+		Kv:   kv,
+	}
+}
+
+func (s *memoryStore) detach(kv KeyValue) {
+	if l, ok := s.leases[kv.Lease]; ok {
+		delete(l.keys, kv.Key)
+	}
+}
+
+func (s *memoryStore) expire(id LeaseID) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	// kept alive after the timer fired
+	if l, ok := s.leases[id]; ok && !time.Now().Before(l.deadline) {
+		s.revoke(id)
+	}
+}
+
+func (s *memoryStore) revoke(id LeaseID) {
+	l := s.leases[id]
+	delete(s.leases, id)
+	l.timer.Stop()
+
+	keys := make([]string, 0, len(l.keys))
+	for key := range l.keys {
+		keys = append(keys, key)
+	}
+	s.deleteKeys(keys)
+}
+
+// push queues the events under the prefix of w, or the error.
+func (w *memoryWatcher) push(resp WatchResponse) {
+	if resp.Err == nil {
+		var events []Event
+		for _, ev := range resp.Events {
+			if strings.HasPrefix(ev.Kv.Key, w.prefix) {
+				events = append(events, ev)
+			}
+		}
+		if len(events) == 0 {
+			return
+		}
+		resp.Events = events
+	}
+
+	w.lock.Lock()
+	w.pending = append(w.pending, resp)
+	w.lock.Unlock()
+
+	select {
+	case w.notify <- lang.Placeholder:
+	default:
+	}
+}
+
+// run sends the queued responses to ch until ctx is done or an error is sent.
+func (w *memoryWatcher) run(ctx context.Context, ch chan<- WatchResponse) {
+	for {
+		w.lock.Lock()
+		pending := w.pending
+		w.pending = nil
+		w.lock.Unlock()
+
+		for _, resp := range pending {
+			select {
+			case ch <- resp:
+			case <-ctx.Done():
+				return
+			}
+			if resp.Err != nil {
+				return
+			}
+		}
+
+		select {
+		case <-w.notify:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package kv
+
+import (
+	"context"
+	"time"
+
+	"github.com/tal-tech/go-zero/core/logx"
+)
+
+const mirrorRetryDelay = time.Second
+
+// A mirror keeps a copy of the key values under a prefix in sync with a store, for the ones that
+// need all of them on every change. The watch is resumed from the last revision on disconnection,
+// and the keys are listed again if the revision is compacted.
+type mirror struct {
+	store  Store
+	prefix string
+	rev    int64
+	kvs    map[string]KeyValue
+	// listed is the key values listed on start
+	listed  map[string]KeyValue
+	changes chan map[string]KeyValue
+	ctx     context.Context
+	cancel  context.CancelFunc
+}
+
+// newMirror lists the keys under prefix, and syncs the changes after that in background.
+func newMirror(ctx context.Context, store Store, prefix string) (*mirror, error) {
+	kvs, rev, err := store.GetPrefix(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &mirror{
+		store:   store,
+		prefix:  prefix,
+		changes: make(chan map[string]KeyValue),
+	}
+	m.ctx, m.cancel = context.WithCancel(context.Background())
+	m.reset(kvs, rev)
+	m.listed = m.snapshot()
+	go m.run()
+
+	return m, nil
+}
+
+// C returns the channel of all the key values after every watch response,
+// which is closed after the mirror is closed.
+func (m *mirror) C() <-chan map[string]KeyValue {
+	return m.changes
+}
+
+func (m *mirror) close() {
+	m.cancel()
+}
+
+func (m *mirror) relist() error {
+	kvs, rev, err := m.store.GetPrefix(m.ctx, m.prefix)
+	if err != nil {
+		return err
+	}
+
+	m.reset(kvs, rev)
+	m.send()
+	return nil
+}
+
+func (m *mirror) reset(kvs []KeyValue, rev int64) {
+	m.rev = rev
+	m.kvs = make(map[string]KeyValue, len(kvs))
+	for _, kv := range kvs {
+		m.kvs[kv.Key] = kv
+	}
+}
+
+func (m *mirror) run() {
+	defer close(m.changes)
+
+	for {
+		err := m.watch()
+		if m.ctx.Err() != nil {
+			return
+		}
+
+		if err == ErrCompacted {
+			if err = m.relist(); err == nil {
+				continue
+			}
+		}
+		if err != nil {
+			logx.Errorf("watch %s failed: %v", m.prefix, err)
+		}
+
+		select {
+		case <-time.After(mirrorRetryDelay):
+		case <-m.ctx.Done():
+			return
+		}
+	}
+}
+
+// send sends a copy of the key values, returns false if the mirror is closed.
+func (m *mirror) send() bool {
+	select {
+	case m.changes <- m.snapshot():
+		return true
+	case <-m.ctx.Done():
+		return false
+	}
+}
+
+func (m *mirror) snapshot() map[string]KeyValue {
+	kvs := make(map[string]KeyValue, len(m.kvs))
+	for key, kv := range m.kvs {
+		kvs[key] = kv
+	}
+
+	return kvs
+}
+
+// watch watches from the next revision until the watch channel is closed.
+func (m *mirror) watch() error {
+	for resp := range m.store.Watch(m.ctx, m.prefix, m.rev+1) {
+		if resp.Err != nil {
+			return resp.Err
+		}
+
+		for _, ev := range resp.Events {
+			if ev.Type == EventDelete {
+				delete(m.kvs, ev.Kv.Key)
+			} else {
+				m.kvs[ev.Kv.Key] = ev.Kv
+			}
+			if ev.Kv.ModRevision > m.rev {
+				m.rev = ev.Kv.ModRevision
+			}
+		}
+		if !m.send() {
+			return nil
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,213 @@
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tal-tech/go-zero/core/logx"
+	"github.com/tx991020/utils/etcdv3"
+)
+
+const (
+	defaultRegisterTTL = time.Second * 10
+	registerTimeout    = time.Second * 3
+)
+
+type (
+	// Registration is an instance registered in a Store, which is kept registered until deregistered.
+	// The instances are registered with the same keys and values as etcdv3.Client.Register,
+	// so they can be discovered by either.
+	Registration struct {
+		store  Store
+		key    string
+		value  string
+		ttl    time.Duration
+		lock   sync.Mutex
+		lease  LeaseID
+		done   chan struct{}
+		closed bool
+	}
+
+	// Discovery keeps the instances of a service in a Store up to date.
+	Discovery struct {
+		mirror    *mirror
+		lock      sync.RWMutex
+		instances []etcdv3.Instance
+		listeners []func(instances []etcdv3.Instance)
+	}
+)
+
+// Register registers inst under prefix with a lease of ttl that is kept alive,
+// it's registered again if the lease is lost, until deregistered.
+func Register(store Store, prefix string, inst etcdv3.Instance, ttl time.Duration) (*Registration, error) {
+	value, err := json.Marshal(inst)
+	if err != nil {
+		return nil, err
+	}
+
+	if ttl <= 0 {
+		ttl = defaultRegisterTTL
+	}
+	r := &Registration{
+		store: store,
+		key:   etcdv3.InstanceKey(prefix, inst.Name, inst.Addr),
+		value: string(value),
+		ttl:   ttl,
+		done:  make(chan struct{}),
+	}
+	if err := r.register(); err != nil {
+		return nil, err
+	}
+
+	go r.keepRegistered()
+	return r, nil
+}
+
+// Deregister deletes the instance and stops keeping it registered.
+func (r *Registration) Deregister(ctx context.Context) error {
+	r.lock.Lock()
+	if r.closed {
+		r.lock.Unlock()
+		return nil
+	}
+	r.closed = true
+	close(r.done)
+	lease := r.lease
+	r.lock.Unlock()
+
+	_, err := r.store.Delete(ctx, r.key)
+	if rerr := r.store.Revoke(ctx, lease); err == nil && rerr != ErrLeaseNotFound {
+		err = rerr
+	}
+	return err
+}
+
+// Key returns the key that the instance is registered with.
+func (r *Registration) Key() string {
+	return r.key
+}
+
+func (r *Registration) keepRegistered() {
+	ticker := time.NewTicker(r.ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.done:
+			return
+		case <-ticker.C:
+		}
+
+		r.lock.Lock()
+		lease := r.lease
+		r.lock.Unlock()
+
+		ctx, cancel := context.WithTimeout(context.Background(), registerTimeout)
+		err := r.store.KeepAliveOnce(ctx, lease)
+		cancel()
+		switch err {
+		case nil:
+		case ErrLeaseNotFound:
+			logx.Infof("registration lease of %s lost, register again", r.key)
+			// retried on the next tick if failed
+			if err := r.register(); err != nil {
+				logx.Errorf("register %s failed: %v", r.key, err)
+			}
+		default:
+			logx.Errorf("keep registration %s alive failed: %v", r.key, err)
+		}
+	}
+}
+
+func (r *Registration) register() error {
+	ctx, cancel := context.WithTimeout(context.Background(), registerTimeout)
+	defer cancel()
+
+	lease, err := r.store.Grant(ctx, r.ttl)
+	if err != nil {
+		return err
+	}
+	if _, err = r.store.Put(ctx, r.key, r.value, WithLease(lease)); err != nil {
+		r.store.Revoke(ctx, lease)
+		return err
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.closed {
+		// deregistered during registering
+		return r.store.Revoke(ctx, lease)
+	}
+	r.lease = lease
+	return nil
+}
+
+// Discover returns a Discovery of the instances of name registered under prefix.
+func Discover(ctx context.Context, store Store, prefix, name string) (*Discovery, error) {
+	m, err := newMirror(ctx, store, etcdv3.InstanceKey(prefix, name, ""))
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Discovery{
+		mirror:    m,
+		instances: toInstances(m.listed),
+	}
+	go d.run()
+
+	return d, nil
+}
+
+// AddListener adds fn to be called with the instances on every change.
+func (d *Discovery) AddListener(fn func(instances []etcdv3.Instance)) {
+	d.lock.Lock()
+	d.listeners = append(d.listeners, fn)
+	d.lock.Unlock()
+}
+
+// Close stops watching the changes.
+func (d *Discovery) Close() error {
+	d.mirror.close()
+	return nil
+}
+
+// Instances returns the instances sorted by addr.
+func (d *Discovery) Instances() []etcdv3.Instance {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+	return d.instances
+}
+
+func (d *Discovery) run() {
+	for kvs := range d.mirror.C() {
+		instances := toInstances(kvs)
+		d.lock.Lock()
+		d.instances = instances
+		listeners := d.listeners
+		d.lock.Unlock()
+
+		for _, listener := range listeners {
+			listener(instances)
+		}
+	}
+}
+
+func toInstances(kvs map[string]KeyValue) []etcdv3.Instance {
+	instances := make([]etcdv3.Instance, 0, len(kvs))
+	for _, kv := range kvs {
+		var inst etcdv3.Instance
+		if err := json.Unmarshal([]byte(kv.Value), &inst); err != nil {
+			logx.Errorf("bad instance %s: %v", kv.Key, err)
+			continue
+		}
+		instances = append(instances, inst)
+	}
+	sort.Slice(instances, func(i, j int) bool {
+		return instances[i].Addr < instances[j].Addr
+	})
+
+	return instances
+}
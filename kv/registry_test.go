@@ -0,0 +1,94 @@
+package kv
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tx991020/utils/etcdv3"
+)
+
+func TestRegistryDiscover(t *testing.T) {
+	store := NewMemoryStore()
+	d, err := Discover(context.Background(), store, "/test/services", "svc")
+	assert.Nil(t, err)
+	defer d.Close()
+	assert.Empty(t, d.Instances())
+
+	changes := make(chan []etcdv3.Instance, 10)
+	d.AddListener(func(instances []etcdv3.Instance) {
+		changes <- instances
+	})
+
+	r1, err := Register(store, "/test/services", etcdv3.Instance{Name: "svc", Addr: "10.0.0.1:80", Zone: "z1"}, time.Second*5)
+	assert.Nil(t, err)
+	assert.Equal(t, "/test/services/svc/10.0.0.1:80", r1.Key())
+	r2, err := Register(store, "/test/services", etcdv3.Instance{Name: "svc", Addr: "10.0.0.2:80", Weight: 10}, time.Second*5)
+	assert.Nil(t, err)
+	other, err := Register(store, "/test/services", etcdv3.Instance{Name: "other", Addr: "10.0.0.3:80"}, time.Second*5)
+	assert.Nil(t, err)
+	defer other.Deregister(context.Background())
+
+	assert.Equal(t, []etcdv3.Instance{
+		{Name: "svc", Addr: "10.0.0.1:80", Zone: "z1"},
+		{Name: "svc", Addr: "10.0.0.2:80", Weight: 10},
+	}, waitInstances(t, changes, 2))
+
+	assert.Nil(t, r1.Deregister(context.Background()))
+	assert.Nil(t, r1.Deregister(context.Background()))
+	assert.Equal(t, []etcdv3.Instance{{Name: "svc", Addr: "10.0.0.2:80", Weight: 10}}, waitInstances(t, changes, 1))
+	assert.Equal(t, []etcdv3.Instance{{Name: "svc", Addr: "10.0.0.2:80", Weight: 10}}, d.Instances())
+
+	// discovered on start
+	d2, err := Discover(context.Background(), store, "/test/services", "svc")
+	assert.Nil(t, err)
+	defer d2.Close()
+	assert.Equal(t, d.Instances(), d2.Instances())
+	assert.Nil(t, r2.Deregister(context.Background()))
+	assert.Empty(t, waitInstances(t, changes, 0))
+}
+
+func TestRegistrationLeaseLost(t *testing.T) {
+	store := NewMemoryStore()
+	r, err := Register(store, "/test/services", etcdv3.Instance{Name: "lost", Addr: "10.0.0.1:80"}, time.Second*3)
+	assert.Nil(t, err)
+	defer r.Deregister(context.Background())
+
+	r.lock.Lock()
+	lease := r.lease
+	r.lock.Unlock()
+	assert.Nil(t, store.Revoke(context.Background(), lease))
+
+	assert.Eventually(t, func() bool {
+		kv, ok, err := store.Get(context.Background(), r.Key())
+		return err == nil && ok && kv.Lease != lease
+	}, time.Second*5, time.Millisecond*50)
+}
+
+func TestRegistrationDeregistered(t *testing.T) {
+	store := NewMemoryStore()
+	r, err := Register(store, "/test/services", etcdv3.Instance{Name: "gone", Addr: "10.0.0.1:80"}, time.Second*3)
+	assert.Nil(t, err)
+	assert.Nil(t, r.Deregister(context.Background()))
+
+	_, ok, err := store.Get(context.Background(), r.Key())
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, ErrLeaseNotFound, store.KeepAliveOnce(context.Background(), r.lease))
+}
+
+func waitInstances(t *testing.T, changes <-chan []etcdv3.Instance, n int) []etcdv3.Instance {
+	timeout := time.After(time.Second * 5)
+	for {
+		select {
+		case instances := <-changes:
+			if len(instances) == n {
+				return instances
+			}
+		case <-timeout:
+			t.Fatal("instances not changed")
+			return nil
+		}
+	}
+}
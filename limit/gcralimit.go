@@ -0,0 +1,82 @@
+package limit
+
+import (
+	"time"
+
+	"github.com/tal-tech/go-zero/core/stores/redis"
+)
+
+// A GCRALimit limits the hits of a key with the generic cell rate algorithm.
+// The hits are spread evenly at quota per period, with at most burst hits at once.
+type GCRALimit struct {
+	interval  time.Duration
+	tolerance time.Duration
+	burst     int
+	store     Store
+	keyPrefix string
+}
+
+// NewGCRALimit returns a GCRALimit that keeps the theoretical arrival times in redis.
+func NewGCRALimit(period, quota, burst int, store *redis.Redis, keyPrefix string) (*GCRALimit, error) {
+	return NewGCRALimitWithStore(period, quota, burst, NewRedisStore(store), keyPrefix)
+}
+
+// NewGCRALimitWithStore returns a GCRALimit that keeps the theoretical arrival times in store,
+// period and quota must be positive.
+func NewGCRALimitWithStore(period, quota, burst int, store Store, keyPrefix string) (*GCRALimit, error) {
+	if err := checkPeriodQuota(period, quota); err != nil {
+		return nil, err
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	interval := time.Duration(period) * time.Second / time.Duration(quota)
+
+	return &GCRALimit{
+		interval:  interval,
+		tolerance: interval * time.Duration(burst),
+		burst:     burst,
+		store:     store,
+		keyPrefix: keyPrefix,
+	}, nil
+}
+
+// Allow reports whether a hit of key is allowed, the errors of the store are treated as allowed.
+func (l *GCRALimit) Allow(key string) bool {
+	code, err := l.Take(key)
+	return err != nil || code != OverQuota
+}
+
+func (l *GCRALimit) Take(key string) (int, error) {
+	state, err := l.TakeState(key)
+	return state.Code, err
+}
+
+// TakeState is like Take, but also returns the burst as quota, the remaining burst
+// and the time to wait before the next hit is allowed as reset.
+func (l *GCRALimit) TakeState(key string) (PeriodState, error) {
+	allowed, ahead, err := l.store.TakeGCRA(l.keyPrefix+key, l.interval, l.tolerance, time.Now())
+	if err != nil {
+		return PeriodState{Code: Unknown}, err
+	}
+
+	state := PeriodState{
+		Quota: l.burst,
+	}
+	if wait := ahead + l.interval - l.tolerance; wait > 0 {
+		state.Reset = wait
+	}
+	if !allowed {
+		state.Code = OverQuota
+		return state, nil
+	}
+
+	state.Remaining = int((l.tolerance - ahead) / l.interval)
+	if state.Remaining > 0 {
+		state.Code = Allowed
+	} else {
+		state.Code = HitQuota
+	}
+
+	return state, nil
+}
@@ -0,0 +1,124 @@
+package limit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis"
+	"github.com/stretchr/testify/assert"
+	"github.com/tal-tech/go-zero/core/stores/redis"
+)
+
+func TestGCRALimit_Take(t *testing.T) {
+	s, err := miniredis.Run()
+	assert.Nil(t, err)
+	defer s.Close()
+
+	testGCRALimit(t, NewRedisStore(redis.NewRedis(s.Addr(), redis.NodeType)))
+}
+
+func TestGCRALimit_TakeInMemory(t *testing.T) {
+	testGCRALimit(t, NewMemoryStore())
+}
+
+func TestGCRALimit_RedisUnavailable(t *testing.T) {
+	s, err := miniredis.Run()
+	assert.Nil(t, err)
+
+	l, err := NewGCRALimit(1, 5, 5, redis.NewRedis(s.Addr(), redis.NodeType), "gcralimit")
+	assert.Nil(t, err)
+	s.Close()
+	val, err := l.Take("first")
+	assert.NotNil(t, err)
+	assert.Equal(t, Unknown, val)
+	assert.True(t, l.Allow("first"))
+}
+
+func TestGCRALimit_Refill(t *testing.T) {
+	const (
+		quota = 50
+		burst = 2
+	)
+	l, err := NewGCRALimitWithStore(1, quota, burst, NewMemoryStore(), "gcralimit")
+	assert.Nil(t, err)
+	assert.True(t, l.Allow("first"))
+	assert.True(t, l.Allow("first"))
+	state, err := l.TakeState("first")
+	assert.Nil(t, err)
+	assert.Equal(t, OverQuota, state.Code)
+	assert.True(t, state.Reset > 0 && state.Reset <= time.Second/quota)
+
+	time.Sleep(time.Second / quota)
+	assert.True(t, l.Allow("first"))
+}
+
+func TestGCRALimit_HighQuota(t *testing.T) {
+	s, err := miniredis.Run()
+	assert.Nil(t, err)
+	defer s.Close()
+
+	// the interval of 5000 hits per second is less than a millisecond
+	const burst = 10
+	l, err := NewGCRALimit(1, 5000, burst, redis.NewRedis(s.Addr(), redis.NodeType), "gcralimit")
+	assert.Nil(t, err)
+	store := NewRedisStore(redis.NewRedis(s.Addr(), redis.NodeType))
+	now := time.Now()
+	for i := 0; i < burst; i++ {
+		allowed, ahead, err := store.TakeGCRA("gcralimit", l.interval, l.tolerance, now)
+		assert.Nil(t, err)
+		assert.True(t, allowed)
+		assert.Equal(t, l.interval*time.Duration(i+1), ahead)
+	}
+	allowed, ahead, err := store.TakeGCRA("gcralimit", l.interval, l.tolerance, now)
+	assert.Nil(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, l.tolerance, ahead)
+
+	state, err := l.TakeState("second")
+	assert.Nil(t, err)
+	assert.Equal(t, Allowed, state.Code)
+}
+
+func testGCRALimit(t *testing.T, store Store) {
+	const (
+		seconds = 100
+		total   = 100
+		quota   = 10
+		burst   = 5
+	)
+	l, err := NewGCRALimitWithStore(seconds, quota, burst, store, "gcralimit")
+	assert.Nil(t, err)
+	var allowed, hitQuota, overQuota int
+	for i := 0; i < total; i++ {
+		val, err := l.Take("first")
+		assert.Nil(t, err)
+		switch val {
+		case Allowed:
+			allowed++
+		case HitQuota:
+			hitQuota++
+		case OverQuota:
+			overQuota++
+		default:
+			t.Error("unknown status")
+		}
+	}
+
+	assert.Equal(t, burst-1, allowed)
+	assert.Equal(t, 1, hitQuota)
+	assert.Equal(t, total-burst, overQuota)
+
+	state, err := l.TakeState("second")
+	assert.Nil(t, err)
+	assert.Equal(t, Allowed, state.Code)
+	assert.Equal(t, burst, state.Quota)
+	assert.Equal(t, burst-1, state.Remaining)
+	assert.Equal(t, time.Duration(0), state.Reset)
+}
+
+func TestNewGCRALimitInvalid(t *testing.T) {
+	_, err := NewGCRALimitWithStore(1, 0, 1, NewMemoryStore(), "gcralimit")
+	assert.Equal(t, ErrInvalidQuota, err)
+	_, err = NewGCRALimitWithStore(0, 1, 1, NewMemoryStore(), "gcralimit")
+	assert.Equal(t, ErrInvalidPeriod, err)
+}
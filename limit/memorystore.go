@@ -0,0 +1,210 @@
+package limit
+
+import (
+	"hash/fnv"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/tal-tech/go-zero/core/timex"
+)
+
+const (
+	memoryShards  = 32
+	sweepInterval = time.Minute
+)
+
+type (
+	memoryEntry struct {
+		count     int64
+		previous  int64
+		index     int64
+		tokens    float64
+		refreshed int64
+		tat       int64
+		expireAt  time.Duration
+	}
+
+	memoryShard struct {
+		lock      sync.Mutex
+		entries   map[string]*memoryEntry
+		lastSweep time.Duration
+	}
+
+	// memoryStore keeps the states in sharded maps, the expired entries are swept lazily.
+	memoryStore struct {
+		shards [memoryShards]*memoryShard
+	}
+)
+
+// NewMemoryStore returns a Store in the process, for single node services and unit tests.
+func NewMemoryStore() Store {
+	s := new(memoryStore)
+	now := timex.Now()
+	for i := range s.shards {
+		s.shards[i] = &memoryShard{
+			entries:   make(map[string]*memoryEntry),
+			lastSweep: now,
+		}
+	}
+
+	return s
+}
+
+func (s *memoryStore) IncrWindow(key string, window time.Duration) (int64, time.Duration, error) {
+	shard := s.shard(key)
+	shard.lock.Lock()
+	defer shard.lock.Unlock()
+
+	now := timex.Now()
+	entry := shard.get(key, now)
+	if entry == nil {
+		entry = &memoryEntry{
+			expireAt: now + window,
+		}
+		shard.entries[key] = entry
+	}
+	entry.count++
+
+	return entry.count, entry.expireAt - now, nil
+}
+
+// TakeTokens does the same as the token script in redis.
+func (s *memoryStore) TakeTokens(tokenKey, timestampKey string, rate, burst int, now time.Time,
+	n int) (bool, error) {
+	// the timestamp key is kept in the same entry as the token key
+	shard := s.shard(tokenKey)
+	shard.lock.Lock()
+	defer shard.lock.Unlock()
+
+	current := timex.Now()
+	entry := shard.get(tokenKey, current)
+	if entry == nil {
+		entry = &memoryEntry{
+			tokens: float64(burst),
+		}
+		shard.entries[tokenKey] = entry
+	}
+
+	delta := math.Max(0, float64(now.Unix()-entry.refreshed))
+	filled := math.Min(float64(burst), entry.tokens+delta*float64(rate))
+	allowed := filled >= float64(n)
+	if allowed {
+		filled -= float64(n)
+	}
+
+	ttl := time.Duration(math.Floor(float64(burst)/float64(rate)*2)) * time.Second
+	if ttl < time.Second {
+		ttl = time.Second
+	}
+	entry.tokens = filled
+	entry.refreshed = now.Unix()
+	entry.expireAt = current + ttl
+
+	return allowed, nil
+}
+
+// TakeSlidingWindow does the same as the sliding script in redis.
+func (s *memoryStore) TakeSlidingWindow(key string, window time.Duration, quota int,
+	now time.Time) (int64, error) {
+	shard := s.shard(key)
+	shard.lock.Lock()
+	defer shard.lock.Unlock()
+
+	current := timex.Now()
+	index := now.UnixNano() / int64(window)
+	elapsed := now.UnixNano() - index*int64(window)
+	entry := shard.get(key, current)
+	if entry == nil {
+		entry = &memoryEntry{
+			index: index,
+		}
+		shard.entries[key] = entry
+	}
+
+	switch {
+	case entry.index+1 == index:
+		entry.previous = entry.count
+		entry.count = 0
+		entry.index = index
+	case entry.index < index:
+		entry.previous = 0
+		entry.count = 0
+		entry.index = index
+	}
+
+	count := entry.previous*(int64(window)-elapsed)/int64(window) + entry.count + 1
+	if count <= int64(quota) {
+		entry.count++
+	}
+	entry.expireAt = current + window*2
+
+	return count, nil
+}
+
+// TakeGCRA does the same as the gcra script in redis.
+func (s *memoryStore) TakeGCRA(key string, interval, tolerance time.Duration, now time.Time) (
+	bool, time.Duration, error) {
+	shard := s.shard(key)
+	shard.lock.Lock()
+	defer shard.lock.Unlock()
+
+	current := timex.Now()
+	unix := now.UnixNano()
+	entry := shard.get(key, current)
+	if entry == nil {
+		entry = new(memoryEntry)
+		shard.entries[key] = entry
+	}
+
+	tat := entry.tat
+	if tat < unix {
+		tat = unix
+	}
+	diff := time.Duration(tat + int64(interval) - unix)
+	if diff > tolerance {
+		return false, time.Duration(tat - unix), nil
+	}
+
+	entry.tat = tat + int64(interval)
+	entry.expireAt = current + diff
+
+	return true, diff, nil
+}
+
+func (s *memoryStore) Ping() bool {
+	return true
+}
+
+func (s *memoryStore) shard(key string) *memoryShard {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return s.shards[h.Sum32()%memoryShards]
+}
+
+// get returns the live entry of key, must be called with the lock held.
+func (s *memoryShard) get(key string, now time.Duration) *memoryEntry {
+	if now-s.lastSweep > sweepInterval {
+		s.sweep(now)
+	}
+
+	entry, ok := s.entries[key]
+	if !ok {
+		return nil
+	}
+	if entry.expireAt <= now {
+		delete(s.entries, key)
+		return nil
+	}
+
+	return entry
+}
+
+func (s *memoryShard) sweep(now time.Duration) {
+	for key, entry := range s.entries {
+		if entry.expireAt <= now {
+			delete(s.entries, key)
+		}
+	}
+	s.lastSweep = now
+}
@@ -0,0 +1,151 @@
+package limit
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tal-tech/go-zero/core/timex"
+)
+
+func TestMemoryStore_PeriodLimit(t *testing.T) {
+	const (
+		seconds = 10
+		total   = 100
+		quota   = 5
+	)
+	l := NewPeriodLimitWithStore(seconds, quota, NewMemoryStore(), "periodlimit")
+	var allowed, hitQuota, overQuota int
+	for i := 0; i < total; i++ {
+		val, err := l.Take("first")
+		assert.Nil(t, err)
+		switch val {
+		case Allowed:
+			allowed++
+		case HitQuota:
+			hitQuota++
+		case OverQuota:
+			overQuota++
+		default:
+			t.Error("unknown status")
+		}
+	}
+
+	assert.Equal(t, quota-1, allowed)
+	assert.Equal(t, 1, hitQuota)
+	assert.Equal(t, total-quota, overQuota)
+
+	state, err := l.TakeState("second")
+	assert.Nil(t, err)
+	assert.Equal(t, Allowed, state.Code)
+	assert.Equal(t, quota-1, state.Remaining)
+	assert.True(t, state.Reset > 0 && state.Reset <= time.Second*seconds)
+}
+
+func TestMemoryStore_PeriodLimitConcurrent(t *testing.T) {
+	const (
+		workers = 10
+		total   = 100
+		quota   = 50
+	)
+	l := NewPeriodLimitWithStore(10, quota, NewMemoryStore(), "periodlimit")
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	var passed int
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < total; j++ {
+				code, err := l.Take("first")
+				assert.Nil(t, err)
+				if code == Allowed || code == HitQuota {
+					lock.Lock()
+					passed++
+					lock.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, quota, passed)
+}
+
+func TestMemoryStore_WindowExpire(t *testing.T) {
+	store := NewMemoryStore()
+	current, ttl, err := store.IncrWindow("key", time.Millisecond*10)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), current)
+	assert.True(t, ttl > 0)
+
+	current, _, err = store.IncrWindow("key", time.Millisecond*10)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), current)
+
+	time.Sleep(time.Millisecond * 20)
+	current, _, err = store.IncrWindow("key", time.Millisecond*10)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), current)
+}
+
+func TestMemoryStore_Sweep(t *testing.T) {
+	store := NewMemoryStore().(*memoryStore)
+	for i := 0; i < 100; i++ {
+		_, _, err := store.IncrWindow(strconv.Itoa(i), time.Millisecond)
+		assert.Nil(t, err)
+	}
+
+	now := timex.Now() + sweepInterval*2
+	for _, shard := range store.shards {
+		shard.lock.Lock()
+		shard.get("any", now)
+		assert.Equal(t, 0, len(shard.entries))
+		shard.lock.Unlock()
+	}
+}
+
+func TestMemoryStore_TokenLimit(t *testing.T) {
+	const (
+		total = 100
+		rate  = 5
+		burst = 10
+	)
+	l := NewTokenLimiterWithStore(rate, burst, NewMemoryStore(), "tokenlimit")
+	var allowed int
+	now := time.Now()
+	for i := 0; i < total; i++ {
+		if l.AllowN(now, 1) {
+			allowed++
+		}
+	}
+	assert.Equal(t, burst, allowed)
+
+	assert.False(t, l.AllowN(now, 1))
+	assert.True(t, l.AllowN(now.Add(time.Second), rate))
+	assert.False(t, l.AllowN(now.Add(time.Second), 1))
+	assert.True(t, l.AllowN(now.Add(time.Second*10), burst))
+}
+
+func TestMemoryStore_SlidingWindow(t *testing.T) {
+	const window = time.Second
+	store := NewMemoryStore()
+	start := time.Unix(1000, 0)
+	for i := 0; i < 10; i++ {
+		count, err := store.TakeSlidingWindow("key", window, 10, start)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(i+1), count)
+	}
+
+	// half of the previous window is weighted in
+	count, err := store.TakeSlidingWindow("key", window, 10, start.Add(window+window/2))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(6), count)
+
+	// the previous window is too old
+	count, err = store.TakeSlidingWindow("key", window, 10, start.Add(window*3))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), count)
+}
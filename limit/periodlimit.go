@@ -2,27 +2,12 @@ package limit
 
 import (
 	"errors"
-	"strconv"
 	"time"
 
 	"github.com/tal-tech/go-zero/core/stores/redis"
 )
 
 const (
-	// to be compatible with aliyun redis, we cannot use `local key = KEYS[1]` to reuse the key
-	periodScript = `local limit = tonumber(ARGV[1])
-local window = tonumber(ARGV[2])
-local current = redis.call("INCRBY", KEYS[1], 1)
-if current == 1 then
-    redis.call("expire", KEYS[1], window)
-    return 1
-elseif current < limit then
-    return 1
-elseif current == limit then
-    return 2
-else
-    return 0
-end`
 	zoneDiff = 3600 * 8 // GMT+8 for our services
 )
 
@@ -37,27 +22,58 @@ const (
 	internalHitQuota  = 2
 )
 
-var ErrUnknownCode = errors.New("unknown status code")
+var (
+	ErrUnknownCode = errors.New("unknown status code")
+	// ErrInvalidPeriod is returned on creating a limit with a period that is not positive.
+	ErrInvalidPeriod = errors.New("limit period must be positive")
+	// ErrInvalidQuota is returned on creating a limit with a quota that is not positive.
+	ErrInvalidQuota = errors.New("limit quota must be positive")
+)
+
+func checkPeriodQuota(period, quota int) error {
+	if period <= 0 {
+		return ErrInvalidPeriod
+	}
+	if quota <= 0 {
+		return ErrInvalidQuota
+	}
+
+	return nil
+}
 
 type (
 	LimitOption func(l *PeriodLimit)
 
+	// PeriodState is the state of the window after a Take.
+	PeriodState struct {
+		Code      int
+		Quota     int
+		Remaining int
+		Reset     time.Duration
+	}
+
 	PeriodLimit struct {
-		period     int
-		quota      int
-		limitStore *redis.Redis
-		keyPrefix  string
-		align      bool
+		period    int
+		quota     int
+		store     Store
+		keyPrefix string
+		align     bool
 	}
 )
 
 func NewPeriodLimit(period, quota int, limitStore *redis.Redis, keyPrefix string,
+	opts ...LimitOption) *PeriodLimit {
+	return NewPeriodLimitWithStore(period, quota, NewRedisStore(limitStore), keyPrefix, opts...)
+}
+
+// NewPeriodLimitWithStore returns a PeriodLimit that keeps the windows in store.
+func NewPeriodLimitWithStore(period, quota int, store Store, keyPrefix string,
 	opts ...LimitOption) *PeriodLimit {
 	limiter := &PeriodLimit{
-		period:     period,
-		quota:      quota,
-		limitStore: limitStore,
-		keyPrefix:  keyPrefix,
+		period:    period,
+		quota:     quota,
+		store:     store,
+		keyPrefix: keyPrefix,
 	}
 
 	for _, opt := range opts {
@@ -68,19 +84,51 @@ func NewPeriodLimit(period, quota int, limitStore *redis.Redis, keyPrefix string
 }
 
 func (h *PeriodLimit) Take(key string) (int, error) {
-	resp, err := h.limitStore.Eval(periodScript, []string{h.keyPrefix + key}, []string{
-		strconv.Itoa(h.quota),
-		strconv.Itoa(h.calcExpireSeconds()),
-	})
+	state, err := h.TakeState(key)
+	return state.Code, err
+}
+
+// TakeState is like Take, but also returns the quota, the remaining count and the reset time of the window.
+func (h *PeriodLimit) TakeState(key string) (PeriodState, error) {
+	window := time.Duration(h.calcExpireSeconds()) * time.Second
+	current, ttl, err := h.store.IncrWindow(h.keyPrefix+key, window)
 	if err != nil {
-		return Unknown, err
+		return PeriodState{Code: Unknown}, err
 	}
 
-	code, ok := resp.(int64)
-	if !ok {
-		return Unknown, ErrUnknownCode
+	state := PeriodState{
+		Quota: h.quota,
+		Reset: ttl,
+	}
+	if remaining := h.quota - int(current); remaining > 0 {
+		state.Remaining = remaining
 	}
+	state.Code, err = convertCode(h.internalCode(current))
 
+	return state, err
+}
+
+func (h *PeriodLimit) calcExpireSeconds() int {
+	if h.align {
+		unix := time.Now().Unix() + zoneDiff
+		return h.period - int(unix%int64(h.period))
+	} else {
+		return h.period
+	}
+}
+
+func (h *PeriodLimit) internalCode(current int64) int64 {
+	quota := int64(h.quota)
+	if current == 1 || current < quota {
+		return internalAllowed
+	} else if current == quota {
+		return internalHitQuota
+	} else {
+		return internalOverQuota
+	}
+}
+
+func convertCode(code int64) (int, error) {
 	switch code {
 	case internalOverQuota:
 		return OverQuota, nil
@@ -93,15 +141,6 @@ func (h *PeriodLimit) Take(key string) (int, error) {
 	}
 }
 
-func (h *PeriodLimit) calcExpireSeconds() int {
-	if h.align {
-		unix := time.Now().Unix() + zoneDiff
-		return h.period - int(unix%int64(h.period))
-	} else {
-		return h.period
-	}
-}
-
 func Align() LimitOption {
 	return func(l *PeriodLimit) {
 		l.align = true
@@ -2,6 +2,7 @@ package limit
 
 import (
 	"testing"
+	"time"
 
 	"github.com/alicebob/miniredis"
 	"github.com/stretchr/testify/assert"
@@ -65,3 +66,27 @@ func testPeriodLimit(t *testing.T, opts ...LimitOption) {
 	assert.Equal(t, 1, hitQuota)
 	assert.Equal(t, total-quota, overQuota)
 }
+
+func TestPeriodLimit_TakeState(t *testing.T) {
+	s, err := miniredis.Run()
+	assert.Nil(t, err)
+	defer s.Close()
+
+	const (
+		seconds = 10
+		quota   = 3
+	)
+	l := NewPeriodLimit(seconds, quota, redis.NewRedis(s.Addr(), redis.NodeType), "periodlimit")
+	var codes, remainings []int
+	for i := 0; i < quota+1; i++ {
+		state, err := l.TakeState("first")
+		assert.Nil(t, err)
+		assert.Equal(t, quota, state.Quota)
+		assert.Equal(t, time.Second*seconds, state.Reset)
+		codes = append(codes, state.Code)
+		remainings = append(remainings, state.Remaining)
+	}
+
+	assert.Equal(t, []int{Allowed, Allowed, HitQuota, OverQuota}, codes)
+	assert.Equal(t, []int{2, 1, 0, 0}, remainings)
+}
@@ -0,0 +1,74 @@
+package limit
+
+import (
+	"time"
+
+	"github.com/tal-tech/go-zero/core/stores/redis"
+)
+
+// A SlidingLimit limits the hits of a key in a sliding window of period seconds.
+// The count of the window is weighted from the counters of the current and the previous fixed windows,
+// which avoids the bursts on the edges of the fixed windows.
+type SlidingLimit struct {
+	period    time.Duration
+	quota     int
+	store     Store
+	keyPrefix string
+}
+
+// NewSlidingLimit returns a SlidingLimit that keeps the counters in redis.
+func NewSlidingLimit(period, quota int, store *redis.Redis, keyPrefix string) (*SlidingLimit, error) {
+	return NewSlidingLimitWithStore(period, quota, NewRedisStore(store), keyPrefix)
+}
+
+// NewSlidingLimitWithStore returns a SlidingLimit that keeps the counters in store,
+// period and quota must be positive.
+func NewSlidingLimitWithStore(period, quota int, store Store, keyPrefix string) (*SlidingLimit, error) {
+	if err := checkPeriodQuota(period, quota); err != nil {
+		return nil, err
+	}
+
+	return &SlidingLimit{
+		period:    time.Duration(period) * time.Second,
+		quota:     quota,
+		store:     store,
+		keyPrefix: keyPrefix,
+	}, nil
+}
+
+// Allow reports whether a hit of key is allowed, the errors of the store are treated as allowed.
+func (l *SlidingLimit) Allow(key string) bool {
+	code, err := l.Take(key)
+	return err != nil || code != OverQuota
+}
+
+func (l *SlidingLimit) Take(key string) (int, error) {
+	state, err := l.TakeState(key)
+	return state.Code, err
+}
+
+// TakeState is like Take, but also returns the quota, the remaining count and the time to the next window.
+func (l *SlidingLimit) TakeState(key string) (PeriodState, error) {
+	now := time.Now()
+	count, err := l.store.TakeSlidingWindow(l.keyPrefix+key, l.period, l.quota, now)
+	if err != nil {
+		return PeriodState{Code: Unknown}, err
+	}
+
+	state := PeriodState{
+		Quota: l.quota,
+		Reset: l.period - time.Duration(now.UnixNano()%int64(l.period)),
+	}
+	quota := int64(l.quota)
+	switch {
+	case count < quota:
+		state.Code = Allowed
+		state.Remaining = int(quota - count)
+	case count == quota:
+		state.Code = HitQuota
+	default:
+		state.Code = OverQuota
+	}
+
+	return state, nil
+}
@@ -0,0 +1,77 @@
+package limit
+
+import (
+	"testing"
+
+	"github.com/alicebob/miniredis"
+	"github.com/stretchr/testify/assert"
+	"github.com/tal-tech/go-zero/core/stores/redis"
+)
+
+func TestSlidingLimit_Take(t *testing.T) {
+	s, err := miniredis.Run()
+	assert.Nil(t, err)
+	defer s.Close()
+
+	testSlidingLimit(t, NewRedisStore(redis.NewRedis(s.Addr(), redis.NodeType)))
+}
+
+func TestSlidingLimit_TakeInMemory(t *testing.T) {
+	testSlidingLimit(t, NewMemoryStore())
+}
+
+func TestSlidingLimit_RedisUnavailable(t *testing.T) {
+	s, err := miniredis.Run()
+	assert.Nil(t, err)
+
+	l, err := NewSlidingLimit(10, 5, redis.NewRedis(s.Addr(), redis.NodeType), "slidinglimit")
+	assert.Nil(t, err)
+	s.Close()
+	val, err := l.Take("first")
+	assert.NotNil(t, err)
+	assert.Equal(t, Unknown, val)
+	assert.True(t, l.Allow("first"))
+}
+
+func testSlidingLimit(t *testing.T, store Store) {
+	const (
+		seconds = 100
+		total   = 100
+		quota   = 5
+	)
+	l, err := NewSlidingLimitWithStore(seconds, quota, store, "slidinglimit")
+	assert.Nil(t, err)
+	var allowed, hitQuota, overQuota int
+	for i := 0; i < total; i++ {
+		val, err := l.Take("first")
+		assert.Nil(t, err)
+		switch val {
+		case Allowed:
+			allowed++
+		case HitQuota:
+			hitQuota++
+		case OverQuota:
+			overQuota++
+		default:
+			t.Error("unknown status")
+		}
+	}
+
+	assert.Equal(t, quota-1, allowed)
+	assert.Equal(t, 1, hitQuota)
+	assert.Equal(t, total-quota, overQuota)
+	assert.False(t, l.Allow("first"))
+
+	state, err := l.TakeState("second")
+	assert.Nil(t, err)
+	assert.Equal(t, Allowed, state.Code)
+	assert.Equal(t, quota-1, state.Remaining)
+	assert.True(t, state.Reset > 0)
+}
+
+func TestNewSlidingLimitInvalid(t *testing.T) {
+	_, err := NewSlidingLimitWithStore(1, 0, NewMemoryStore(), "slidinglimit")
+	assert.Equal(t, ErrInvalidQuota, err)
+	_, err = NewSlidingLimitWithStore(-1, 1, NewMemoryStore(), "slidinglimit")
+	assert.Equal(t, ErrInvalidPeriod, err)
+}
@@ -0,0 +1,194 @@
+package limit
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/tal-tech/go-zero/core/stores/redis"
+)
+
+const (
+	// to be compatible with aliyun redis, we cannot use `local key = KEYS[1]` to reuse the key
+	windowScript = `local window = tonumber(ARGV[1])
+local current = redis.call("INCRBY", KEYS[1], 1)
+if current == 1 then
+    redis.call("expire", KEYS[1], window)
+end
+return {current, redis.call("ttl", KEYS[1])}`
+	// KEYS[1] as the counter of current window
+	// KEYS[2] as the counter of previous window
+	slidingScript = `local quota = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local elapsed = tonumber(ARGV[3])
+local previous = tonumber(redis.call("get", KEYS[2]) or "0")
+local current = tonumber(redis.call("get", KEYS[1]) or "0")
+local count = math.floor(previous*(window-elapsed)/window) + current + 1
+if count <= quota then
+    redis.call("incrby", KEYS[1], 1)
+    redis.call("pexpire", KEYS[1], window*2)
+end
+return count`
+	// KEYS[1] as the theoretical arrival time, the times are in microseconds,
+	// which keeps the interval of high rates from being rounded to zero
+	gcraScript = `local interval = tonumber(ARGV[1])
+local tolerance = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local tat = tonumber(redis.call("get", KEYS[1]))
+if tat == nil or tat < now then
+    tat = now
+end
+local diff = tat + interval - now
+if diff > tolerance then
+    return {0, tat - now}
+end
+redis.call("set", KEYS[1], tat + interval, "PX", math.max(math.ceil(diff/1000), 1))
+return {1, diff}`
+	slidingFormat = "{%s}.%d"
+)
+
+type (
+	// A Store keeps the states of the limiters, the operations must be atomic across the limiter instances
+	// that share the store.
+	Store interface {
+		// IncrWindow increments the counter of key, the counter expires after window since it's created.
+		// Returns the current count and the time to live of the counter.
+		IncrWindow(key string, window time.Duration) (int64, time.Duration, error)
+		// TakeTokens takes n tokens from the bucket of tokenKey and timestampKey that is filled by rate
+		// tokens per second up to burst tokens, returns whether the tokens are taken.
+		TakeTokens(tokenKey, timestampKey string, rate, burst int, now time.Time, n int) (bool, error)
+		// TakeSlidingWindow counts a hit in the sliding window of key if the weighted count of the
+		// current and previous windows stays in quota, returns the weighted count including the hit.
+		TakeSlidingWindow(key string, window time.Duration, quota int, now time.Time) (int64, error)
+		// TakeGCRA takes a hit with the emission interval and the burst tolerance, returns whether
+		// the hit is allowed and how far the theoretical arrival time is ahead of now.
+		TakeGCRA(key string, interval, tolerance time.Duration, now time.Time) (bool, time.Duration, error)
+		// Ping reports whether the store is available.
+		Ping() bool
+	}
+
+	redisStore struct {
+		store *redis.Redis
+	}
+)
+
+// NewRedisStore returns a Store backed by redis, shared by all the limiters on the same redis.
+func NewRedisStore(store *redis.Redis) Store {
+	return redisStore{
+		store: store,
+	}
+}
+
+func (s redisStore) IncrWindow(key string, window time.Duration) (int64, time.Duration, error) {
+	resp, err := s.store.Eval(windowScript, []string{key}, []string{
+		strconv.Itoa(int(window / time.Second)),
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	vals, ok := resp.([]interface{})
+	if !ok || len(vals) != 2 {
+		return 0, 0, ErrUnknownCode
+	}
+
+	current, ok1 := vals[0].(int64)
+	ttl, ok2 := vals[1].(int64)
+	if !ok1 || !ok2 {
+		return 0, 0, ErrUnknownCode
+	}
+
+	return current, time.Duration(ttl) * time.Second, nil
+}
+
+func (s redisStore) TakeTokens(tokenKey, timestampKey string, rate, burst int, now time.Time, n int) (bool, error) {
+	resp, err := s.store.Eval(
+		script,
+		[]string{
+			tokenKey,
+			timestampKey,
+		},
+		[]string{
+			strconv.Itoa(rate),
+			strconv.Itoa(burst),
+			strconv.FormatInt(now.Unix(), 10),
+			strconv.Itoa(n),
+		})
+	// redis allowed == false
+	// Lua boolean false -> r Nil bulk reply
+	if err == redis.Nil {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	code, ok := resp.(int64)
+	if !ok {
+		return false, ErrUnknownCode
+	}
+
+	// redis allowed == true
+	// Lua boolean true -> r integer reply with value of 1
+	return code == 1, nil
+}
+
+func (s redisStore) TakeSlidingWindow(key string, window time.Duration, quota int,
+	now time.Time) (int64, error) {
+	index := now.UnixNano() / int64(window)
+	elapsed := time.Duration(now.UnixNano() - index*int64(window))
+	resp, err := s.store.Eval(slidingScript, []string{
+		fmt.Sprintf(slidingFormat, key, index),
+		fmt.Sprintf(slidingFormat, key, index-1),
+	}, []string{
+		strconv.Itoa(quota),
+		strconv.FormatInt(toMillis(window), 10),
+		strconv.FormatInt(toMillis(elapsed), 10),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	count, ok := resp.(int64)
+	if !ok {
+		return 0, ErrUnknownCode
+	}
+
+	return count, nil
+}
+
+func (s redisStore) TakeGCRA(key string, interval, tolerance time.Duration, now time.Time) (
+	bool, time.Duration, error) {
+	resp, err := s.store.Eval(gcraScript, []string{key}, []string{
+		strconv.FormatInt(toMicros(interval), 10),
+		strconv.FormatInt(toMicros(tolerance), 10),
+		strconv.FormatInt(toMicros(time.Duration(now.UnixNano())), 10),
+	})
+	if err != nil {
+		return false, 0, err
+	}
+
+	vals, ok := resp.([]interface{})
+	if !ok || len(vals) != 2 {
+		return false, 0, ErrUnknownCode
+	}
+
+	allowed, ok1 := vals[0].(int64)
+	ahead, ok2 := vals[1].(int64)
+	if !ok1 || !ok2 {
+		return false, 0, ErrUnknownCode
+	}
+
+	return allowed == 1, time.Duration(ahead) * time.Microsecond, nil
+}
+
+func (s redisStore) Ping() bool {
+	return s.store.Ping()
+}
+
+func toMicros(d time.Duration) int64 {
+	return int64(d / time.Microsecond)
+}
+
+func toMillis(d time.Duration) int64 {
+	return int64(d / time.Millisecond)
+}
@@ -2,7 +2,6 @@ package limit
 
 import (
 	"fmt"
-	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -53,7 +52,7 @@ return allowed`
 type TokenLimiter struct {
 	rate           int
 	burst          int
-	store          *redis.Redis
+	store          Store
 	tokenKey       string
 	timestampKey   string
 	rescueLock     sync.Mutex
@@ -65,6 +64,11 @@ type TokenLimiter struct {
 // NewTokenLimiter returns a new TokenLimiter that allows events up to rate and permits
 // bursts of at most burst tokens.
 func NewTokenLimiter(rate, burst int, store *redis.Redis, key string) *TokenLimiter {
+	return NewTokenLimiterWithStore(rate, burst, NewRedisStore(store), key)
+}
+
+// NewTokenLimiterWithStore returns a TokenLimiter that keeps the tokens in store.
+func NewTokenLimiterWithStore(rate, burst int, store Store, key string) *TokenLimiter {
 	tokenKey := fmt.Sprintf(tokenFormat, key)
 	timestampKey := fmt.Sprintf(timestampFormat, key)
 
@@ -96,38 +100,14 @@ func (lim *TokenLimiter) reserveN(now time.Time, n int) bool {
 		return lim.rescueLimiter.AllowN(now, n)
 	}
 
-	resp, err := lim.store.Eval(
-		script,
-		[]string{
-			lim.tokenKey,
-			lim.timestampKey,
-		},
-		[]string{
-			strconv.Itoa(lim.rate),
-			strconv.Itoa(lim.burst),
-			strconv.FormatInt(now.Unix(), 10),
-			strconv.Itoa(n),
-		})
-	// redis allowed == false
-	// Lua boolean false -> r Nil bulk reply
-	if err == redis.Nil {
-		return false
-	} else if err != nil {
+	allowed, err := lim.store.TakeTokens(lim.tokenKey, lim.timestampKey, lim.rate, lim.burst, now, n)
+	if err != nil {
 		logx.Errorf("fail to use rate limiter: %s, use in-process limiter for rescue", err)
 		lim.startMonitor()
 		return lim.rescueLimiter.AllowN(now, n)
 	}
 
-	code, ok := resp.(int64)
-	if !ok {
-		logx.Errorf("fail to eval redis script: %v, use in-process limiter for rescue", resp)
-		lim.startMonitor()
-		return lim.rescueLimiter.AllowN(now, n)
-	}
-
-	// redis allowed == true
-	// Lua boolean true -> r integer reply with value of 1
-	return code == 1
+	return allowed
 }
 
 func (lim *TokenLimiter) startMonitor() {
@@ -0,0 +1,112 @@
+package load
+
+import (
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tal-tech/go-zero/core/logx"
+	"github.com/tal-tech/go-zero/core/mathx"
+	"github.com/tal-tech/go-zero/core/stat"
+	"github.com/tx991020/utils/collection"
+	"github.com/tx991020/utils/syncx"
+)
+
+const (
+	buckets        = 10
+	bucketDuration = time.Millisecond * 50
+)
+
+func init() {
+	logx.Disable()
+	stat.SetReporter(nil)
+}
+
+func TestAdaptiveShedder(t *testing.T) {
+	shedder := NewAdaptiveShedder(WithWindow(bucketDuration), WithBuckets(buckets), WithCpuThreshold(100))
+	var wg sync.WaitGroup
+	var drop int64
+	proba := mathx.NewProba()
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 30; i++ {
+				promise, err := shedder.Allow()
+				if err != nil {
+					atomic.AddInt64(&drop, 1)
+				} else {
+					count := rand.Intn(5)
+					time.Sleep(time.Millisecond * time.Duration(count))
+					if proba.TrueOnProba(0.01) {
+						promise.Fail()
+					} else {
+						promise.Pass()
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestAdaptiveShedderMaxFlight(t *testing.T) {
+	shedder := newFilledShedder()
+	assert.Equal(t, int64(54), shedder.maxFlight())
+}
+
+func TestAdaptiveShedderShouldDrop(t *testing.T) {
+	shedder := newFilledShedder()
+	defer func(checker func(int64) bool) {
+		systemOverloadChecker = checker
+	}(systemOverloadChecker)
+
+	systemOverloadChecker = func(int64) bool {
+		return true
+	}
+	shedder.avgFlying = 50
+	assert.False(t, shedder.shouldDrop())
+
+	shedder.avgFlying = 80
+	shedder.flying = 50
+	assert.False(t, shedder.shouldDrop())
+
+	shedder.avgFlying = 80
+	shedder.flying = 80
+	assert.True(t, shedder.shouldDrop())
+
+	systemOverloadChecker = func(int64) bool {
+		return false
+	}
+	shedder.droppedRecently.Set(false)
+	assert.False(t, shedder.shouldDrop())
+}
+
+func newFilledShedder() *adaptiveShedder {
+	passCounter := newRollingWindow()
+	rtCounter := newRollingWindow()
+	for i := 0; i < 10; i++ {
+		if i > 0 {
+			time.Sleep(bucketDuration)
+		}
+		passCounter.Add(float64((i + 1) * 100))
+		for j := i*10 + 1; j <= i*10+10; j++ {
+			rtCounter.Add(float64(j))
+		}
+	}
+
+	return &adaptiveShedder{
+		passCounter:     passCounter,
+		rtCounter:       rtCounter,
+		windows:         buckets,
+		dropTime:        syncx.NewAtomicDuration(),
+		droppedRecently: syncx.NewAtomicBool(),
+	}
+}
+
+func newRollingWindow() *collection.RollingWindow {
+	return collection.NewRollingWindow(buckets, bucketDuration, collection.IgnoreCurrentBucket())
+}
@@ -0,0 +1,209 @@
+package load
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/tal-tech/go-zero/core/timex"
+	"github.com/tx991020/utils/collection"
+)
+
+const (
+	defaultInitialLimit = 20
+	defaultMinLimit     = 1
+	defaultMaxLimit     = 1000
+	// the average rt over minRt*tolerance means queueing
+	defaultTolerance = 2
+	defaultBackoff   = 0.9
+)
+
+var ErrLimitExceeded = errors.New("concurrency limit exceeded")
+
+type (
+	LimiterOption func(opts *limiterOptions)
+
+	limiterOptions struct {
+		window    time.Duration
+		buckets   int
+		initial   int
+		min       int
+		max       int
+		tolerance float64
+		backoff   float64
+	}
+
+	// A ConcurrencyLimiter limits the concurrent calls with an AIMD limit that follows the observed latency.
+	// The limit is increased by one when the calls are fast and use up half of the limit,
+	// and is multiplied by the backoff ratio when the calls are failed or slower than minRt*tolerance,
+	// at most once per bucket.
+	ConcurrencyLimiter struct {
+		lock          sync.Mutex
+		limit         float64
+		min           float64
+		max           float64
+		tolerance     float64
+		backoff       float64
+		flying        int64
+		bucket        time.Duration
+		lastDecreased time.Duration
+		rtCounter     *collection.RollingWindow
+	}
+
+	limiterPromise struct {
+		start   time.Duration
+		limiter *ConcurrencyLimiter
+	}
+)
+
+// NewConcurrencyLimiter returns a ConcurrencyLimiter, which is also a Shedder.
+func NewConcurrencyLimiter(opts ...LimiterOption) *ConcurrencyLimiter {
+	options := limiterOptions{
+		window:    defaultWindow,
+		buckets:   defaultBuckets,
+		initial:   defaultInitialLimit,
+		min:       defaultMinLimit,
+		max:       defaultMaxLimit,
+		tolerance: defaultTolerance,
+		backoff:   defaultBackoff,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.initial < options.min {
+		options.initial = options.min
+	} else if options.initial > options.max {
+		options.initial = options.max
+	}
+
+	bucket := options.window / time.Duration(options.buckets)
+	return &ConcurrencyLimiter{
+		limit:         float64(options.initial),
+		min:           float64(options.min),
+		max:           float64(options.max),
+		tolerance:     options.tolerance,
+		backoff:       options.backoff,
+		bucket:        bucket,
+		lastDecreased: -bucket,
+		rtCounter:     collection.NewRollingWindow(options.buckets, bucket),
+	}
+}
+
+// Allow acquires a slot, the returned Promise must be resolved by Pass or Fail when the call is done.
+func (l *ConcurrencyLimiter) Allow() (Promise, error) {
+	limit := int64(l.Limit())
+	for {
+		flying := atomic.LoadInt64(&l.flying)
+		if flying >= limit {
+			return nil, ErrLimitExceeded
+		}
+		if atomic.CompareAndSwapInt64(&l.flying, flying, flying+1) {
+			break
+		}
+	}
+
+	return &limiterPromise{
+		start:   timex.Now(),
+		limiter: l,
+	}, nil
+}
+
+// Inflight returns the count of the calls in flight.
+func (l *ConcurrencyLimiter) Inflight() int {
+	return int(atomic.LoadInt64(&l.flying))
+}
+
+// Limit returns the current limit.
+func (l *ConcurrencyLimiter) Limit() int {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return int(l.limit)
+}
+
+func (l *ConcurrencyLimiter) decrease() {
+	now := timex.Now()
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if now-l.lastDecreased < l.bucket {
+		return
+	}
+
+	l.lastDecreased = now
+	l.limit = math.Max(l.min, math.Floor(l.limit*l.backoff))
+}
+
+func (l *ConcurrencyLimiter) increase(flying int64) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if float64(flying)*2 >= l.limit {
+		l.limit = math.Min(l.max, l.limit+1)
+	}
+}
+
+func (l *ConcurrencyLimiter) minRt() float64 {
+	result := math.MaxFloat64
+	l.rtCounter.Reduce(func(b *collection.Bucket) {
+		if b.Count <= 0 {
+			return
+		}
+
+		if avg := b.Sum / float64(b.Count); avg < result {
+			result = avg
+		}
+	})
+
+	return result
+}
+
+func (p *limiterPromise) Fail() {
+	atomic.AddInt64(&p.limiter.flying, -1)
+	p.limiter.decrease()
+}
+
+func (p *limiterPromise) Pass() {
+	rt := float64(timex.Since(p.start)) / float64(time.Millisecond)
+	// the flying before this call is done is what the limit is checked against
+	flying := atomic.AddInt64(&p.limiter.flying, -1) + 1
+	minRt := p.limiter.minRt()
+	p.limiter.rtCounter.Add(rt)
+	if minRt != math.MaxFloat64 && rt > minRt*p.limiter.tolerance {
+		p.limiter.decrease()
+	} else {
+		p.limiter.increase(flying)
+	}
+}
+
+// WithLimits sets the initial, the minimum and the maximum limits.
+func WithLimits(initial, min, max int) LimiterOption {
+	return func(opts *limiterOptions) {
+		opts.initial = initial
+		opts.min = min
+		opts.max = max
+	}
+}
+
+// WithTolerance sets how many times of the minimum rt are treated as slow.
+func WithTolerance(tolerance float64) LimiterOption {
+	return func(opts *limiterOptions) {
+		opts.tolerance = tolerance
+	}
+}
+
+// WithBackoff sets the ratio the limit is multiplied by on slow or failed calls.
+func WithBackoff(backoff float64) LimiterOption {
+	return func(opts *limiterOptions) {
+		opts.backoff = backoff
+	}
+}
+
+// WithLimiterWindow sets the window and the buckets of the rt statistics.
+func WithLimiterWindow(window time.Duration, buckets int) LimiterOption {
+	return func(opts *limiterOptions) {
+		opts.window = window
+		opts.buckets = buckets
+	}
+}
@@ -0,0 +1,95 @@
+package load
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConcurrencyLimiter_Allow(t *testing.T) {
+	limiter := NewConcurrencyLimiter(WithLimits(2, 1, 10))
+	p1, err := limiter.Allow()
+	assert.Nil(t, err)
+	p2, err := limiter.Allow()
+	assert.Nil(t, err)
+	_, err = limiter.Allow()
+	assert.Equal(t, ErrLimitExceeded, err)
+	assert.Equal(t, 2, limiter.Inflight())
+
+	p1.Pass()
+	p2.Pass()
+	assert.Equal(t, 0, limiter.Inflight())
+	assert.True(t, limiter.Limit() > 2)
+}
+
+func TestConcurrencyLimiter_Increase(t *testing.T) {
+	limiter := NewConcurrencyLimiter(WithLimits(4, 1, 6))
+	for i := 0; i < 10; i++ {
+		var promises []Promise
+		for j := 0; j < limiter.Limit(); j++ {
+			p, err := limiter.Allow()
+			assert.Nil(t, err)
+			promises = append(promises, p)
+		}
+		for _, p := range promises {
+			p.Pass()
+		}
+	}
+
+	assert.Equal(t, 6, limiter.Limit())
+}
+
+func TestConcurrencyLimiter_DecreaseOnFail(t *testing.T) {
+	limiter := NewConcurrencyLimiter(WithLimits(10, 2, 10), WithBackoff(0.5),
+		WithLimiterWindow(time.Millisecond*100, 10))
+	p, err := limiter.Allow()
+	assert.Nil(t, err)
+	p.Fail()
+	assert.Equal(t, 5, limiter.Limit())
+
+	// decreased at most once per bucket
+	p, err = limiter.Allow()
+	assert.Nil(t, err)
+	p.Fail()
+	assert.Equal(t, 5, limiter.Limit())
+
+	time.Sleep(time.Millisecond * 10)
+	p, err = limiter.Allow()
+	assert.Nil(t, err)
+	p.Fail()
+	assert.Equal(t, 2, limiter.Limit())
+}
+
+func TestConcurrencyLimiter_DecreaseOnSlow(t *testing.T) {
+	limiter := NewConcurrencyLimiter(WithLimits(10, 1, 10), WithBackoff(0.5), WithTolerance(2))
+	p, err := limiter.Allow()
+	assert.Nil(t, err)
+	p.Pass()
+
+	p, err = limiter.Allow()
+	assert.Nil(t, err)
+	time.Sleep(time.Millisecond * 20)
+	p.Pass()
+	assert.Equal(t, 5, limiter.Limit())
+}
+
+func TestConcurrencyLimiter_Concurrent(t *testing.T) {
+	limiter := NewConcurrencyLimiter(WithLimits(5, 1, 5))
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if p, err := limiter.Allow(); err == nil {
+					assert.True(t, limiter.Inflight() <= 5)
+					p.Pass()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 0, limiter.Inflight())
+}
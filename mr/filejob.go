@@ -0,0 +1,457 @@
+package mr
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+
+	"github.com/tal-tech/go-zero/core/errorx"
+	"github.com/tal-tech/go-zero/core/logx"
+	"github.com/tx991020/utils/filex"
+)
+
+const (
+	defaultSpillSize  = 100000
+	defaultJobRetries = 3
+	maxLineSize       = 1 << 20
+	outputFormat      = "part-%05d"
+	spillFormat       = "spill-%05d-part-%05d"
+)
+
+var ErrNoJobFunc = errors.New("map and reduce funcs are required")
+
+type (
+	// KeyValue is the intermediate pair written by the mappers.
+	KeyValue struct {
+		Key   string `json:"k"`
+		Value string `json:"v"`
+	}
+
+	// FileMapFunc maps a line of the inputs into key value pairs by emit.
+	FileMapFunc func(line string, emit func(key, value string)) error
+
+	// FileReduceFunc reduces the values of a key, the lines written by emit go to the output files.
+	// The values of a key are held in memory, while the whole dataset doesn't need to be.
+	FileReduceFunc func(key string, values []string, emit func(line string)) error
+
+	// FileJob is a MapReduce job on files, the inputs are split into chunks by lines,
+	// the mappers spill sorted partitions to disk, and the reducers merge sort the partitions.
+	// A failed map or reduce task is retried from scratch.
+	FileJob struct {
+		Inputs    []string
+		OutputDir string
+		// TempDir keeps the spill files, defaults to a temporary directory that is removed after the job.
+		TempDir string
+		// Chunks is how many chunks each input is split into, defaults to Workers.
+		Chunks int
+		// Workers is how many tasks run concurrently, defaults to the number of cpus.
+		Workers int
+		// Reducers is how many partitions and output files, defaults to 1.
+		Reducers int
+		// SpillSize is how many pairs a mapper holds in memory before spilling to disk.
+		SpillSize int
+		// Retries is how many times a task is tried, defaults to 3.
+		Retries   int
+		Map       FileMapFunc
+		Reduce    FileReduceFunc
+		Partition func(key string, reducers int) int
+	}
+
+	mapTask struct {
+		id    int
+		chunk filex.OffsetRange
+	}
+
+	spillReader struct {
+		scanner *bufio.Scanner
+		file    *os.File
+		current KeyValue
+	}
+
+	spillHeap []*spillReader
+)
+
+// RunFileJob runs the job and returns the output files, one per reducer.
+func RunFileJob(job FileJob) ([]string, error) {
+	if job.Map == nil || job.Reduce == nil {
+		return nil, ErrNoJobFunc
+	}
+
+	job.fillDefaults()
+	if len(job.TempDir) == 0 {
+		dir, err := ioutil.TempDir("", "mr-")
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(dir)
+		job.TempDir = dir
+	}
+	if err := os.MkdirAll(job.OutputDir, os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	tasks, err := job.splitInputs()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := job.runTasks(len(tasks), func(i int) error {
+		return job.runMapTask(tasks[i])
+	}); err != nil {
+		return nil, err
+	}
+
+	outputs := make([]string, job.Reducers)
+	for i := range outputs {
+		outputs[i] = filepath.Join(job.OutputDir, fmt.Sprintf(outputFormat, i))
+	}
+	if err := job.runTasks(job.Reducers, func(i int) error {
+		return job.runReduceTask(i, len(tasks), outputs[i])
+	}); err != nil {
+		return nil, err
+	}
+
+	return outputs, nil
+}
+
+func (job *FileJob) fillDefaults() {
+	if job.Workers <= 0 {
+		job.Workers = runtime.NumCPU()
+	}
+	if job.Chunks <= 0 {
+		job.Chunks = job.Workers
+	}
+	if job.Reducers <= 0 {
+		job.Reducers = 1
+	}
+	if job.SpillSize <= 0 {
+		job.SpillSize = defaultSpillSize
+	}
+	if job.Retries <= 0 {
+		job.Retries = defaultJobRetries
+	}
+	if job.Partition == nil {
+		job.Partition = hashPartition
+	}
+}
+
+func (job *FileJob) splitInputs() ([]mapTask, error) {
+	var tasks []mapTask
+	for _, input := range job.Inputs {
+		chunks, err := filex.SplitLineChunks(input, job.Chunks)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, chunk := range chunks {
+			tasks = append(tasks, mapTask{
+				id:    len(tasks),
+				chunk: chunk,
+			})
+		}
+	}
+
+	return tasks, nil
+}
+
+// runTasks runs the tasks with the workers, each task is retried on failure.
+func (job *FileJob) runTasks(n int, run func(i int) error) error {
+	if n == 0 {
+		return nil
+	}
+
+	return MapReduceVoid(func(source chan<- interface{}) {
+		for i := 0; i < n; i++ {
+			source <- i
+		}
+	}, func(item interface{}, writer Writer, cancel func(error)) {
+		i := item.(int)
+		var berr errorx.BatchError
+		for attempt := 0; attempt < job.Retries; attempt++ {
+			err := safeRun(func() error {
+				return run(i)
+			})
+			if err == nil {
+				return
+			}
+
+			logx.Errorf("mr task %d failed on attempt %d: %v", i, attempt+1, err)
+			berr.Add(err)
+		}
+		cancel(berr.Err())
+	}, func(pipe <-chan interface{}, cancel func(error)) {
+		drain(pipe)
+	}, WithWorkers(job.Workers))
+}
+
+// runMapTask writes the spill files into a task directory, which is renamed on success,
+// so that a retried task never sees the files of a failed attempt.
+func (job *FileJob) runMapTask(task mapTask) error {
+	dir := job.mapDir(task.id)
+	tmpDir := dir + ".tmp"
+	if err := os.RemoveAll(tmpDir); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(tmpDir, os.ModePerm); err != nil {
+		return err
+	}
+
+	file, err := os.Open(task.chunk.File)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	partitions := make([][]KeyValue, job.Reducers)
+	var buffered, spills int
+	spill := func() error {
+		if buffered == 0 {
+			return nil
+		}
+
+		for i, pairs := range partitions {
+			name := filepath.Join(tmpDir, fmt.Sprintf(spillFormat, spills, i))
+			if err := writeSpill(name, pairs); err != nil {
+				return err
+			}
+			partitions[i] = partitions[i][:0]
+		}
+		buffered = 0
+		spills++
+		return nil
+	}
+
+	reader := io.NewSectionReader(file, task.chunk.Start, task.chunk.Stop-task.chunk.Start)
+	scanner := newScanner(reader)
+	var emitErr error
+	emit := func(key, value string) {
+		i := job.Partition(key, job.Reducers)
+		partitions[i] = append(partitions[i], KeyValue{
+			Key:   key,
+			Value: value,
+		})
+		buffered++
+		if buffered >= job.SpillSize && emitErr == nil {
+			emitErr = spill()
+		}
+	}
+	for scanner.Scan() {
+		if err := job.Map(scanner.Text(), emit); err != nil {
+			return err
+		}
+		if emitErr != nil {
+			return emitErr
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if err := spill(); err != nil {
+		return err
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpDir, dir)
+}
+
+// runReduceTask merge sorts the spill files of the partition, and reduces the values by keys.
+func (job *FileJob) runReduceTask(partition, mapTasks int, output string) (err error) {
+	var files []string
+	for i := 0; i < mapTasks; i++ {
+		names, err := filepath.Glob(filepath.Join(job.mapDir(i), fmt.Sprintf("spill-*-part-%05d", partition)))
+		if err != nil {
+			return err
+		}
+		files = append(files, names...)
+	}
+
+	var readers spillHeap
+	defer func() {
+		for _, reader := range readers {
+			reader.file.Close()
+		}
+	}()
+	for _, name := range files {
+		reader, ok, err := newSpillReader(name)
+		if err != nil {
+			return err
+		}
+		if ok {
+			readers = append(readers, reader)
+		} else {
+			reader.file.Close()
+		}
+	}
+
+	tmpFile := output + ".tmp"
+	out, err := os.Create(tmpFile)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if out != nil {
+			out.Close()
+			os.Remove(tmpFile)
+		}
+	}()
+
+	writer := bufio.NewWriter(out)
+	emit := func(line string) {
+		writer.WriteString(line)
+		writer.WriteByte('\n')
+	}
+	h := &readers
+	heap.Init(h)
+	for h.Len() > 0 {
+		key := readers[0].current.Key
+		var values []string
+		for h.Len() > 0 && readers[0].current.Key == key {
+			reader := readers[0]
+			values = append(values, reader.current.Value)
+			ok, err := reader.next()
+			if err != nil {
+				return err
+			}
+			if ok {
+				heap.Fix(h, 0)
+			} else {
+				heap.Pop(h)
+				reader.file.Close()
+			}
+		}
+
+		if err := job.Reduce(key, values, emit); err != nil {
+			return err
+		}
+	}
+
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	out = nil
+
+	return os.Rename(tmpFile, output)
+}
+
+func (job *FileJob) mapDir(task int) string {
+	return filepath.Join(job.TempDir, fmt.Sprintf("map-%05d", task))
+}
+
+func hashPartition(key string, reducers int) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(reducers))
+}
+
+func newScanner(reader io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxLineSize)
+	return scanner
+}
+
+func safeRun(fn func() error) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("%v", p)
+		}
+	}()
+
+	return fn()
+}
+
+func writeSpill(name string, pairs []KeyValue) error {
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return pairs[i].Key < pairs[j].Key
+	})
+
+	file, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	for _, pair := range pairs {
+		if err := encoder.Encode(pair); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+func newSpillReader(name string) (*spillReader, bool, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, false, err
+	}
+
+	reader := &spillReader{
+		scanner: newScanner(file),
+		file:    file,
+	}
+	ok, err := reader.next()
+	if err != nil {
+		file.Close()
+		return nil, false, err
+	}
+
+	return reader, ok, nil
+}
+
+func (sr *spillReader) next() (bool, error) {
+	if !sr.scanner.Scan() {
+		return false, sr.scanner.Err()
+	}
+
+	sr.current = KeyValue{}
+	if err := json.Unmarshal(sr.scanner.Bytes(), &sr.current); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (h spillHeap) Len() int {
+	return len(h)
+}
+
+func (h spillHeap) Less(i, j int) bool {
+	return h[i].current.Key < h[j].current.Key
+}
+
+func (h spillHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+}
+
+func (h *spillHeap) Push(x interface{}) {
+	*h = append(*h, x.(*spillReader))
+}
+
+func (h *spillHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
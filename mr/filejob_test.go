@@ -0,0 +1,138 @@
+package mr
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunFileJob(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mrtest-")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	expect := make(map[string]int)
+	var inputs []string
+	for i := 0; i < 2; i++ {
+		var lines []string
+		for j := 0; j < 500; j++ {
+			word := fmt.Sprintf("w%d", (i*7+j)%37)
+			lines = append(lines, word+" "+word)
+			expect[word] += 2
+		}
+		name := filepath.Join(dir, fmt.Sprintf("input-%d", i))
+		assert.Nil(t, ioutil.WriteFile(name, []byte(strings.Join(lines, "\n")), 0644))
+		inputs = append(inputs, name)
+	}
+
+	outputs, err := RunFileJob(FileJob{
+		Inputs:    inputs,
+		OutputDir: filepath.Join(dir, "output"),
+		Chunks:    4,
+		Workers:   3,
+		Reducers:  3,
+		SpillSize: 50,
+		Map: func(line string, emit func(key, value string)) error {
+			for _, word := range strings.Fields(line) {
+				emit(word, "1")
+			}
+			return nil
+		},
+		Reduce: func(key string, values []string, emit func(line string)) error {
+			emit(fmt.Sprintf("%s %d", key, len(values)))
+			return nil
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(outputs))
+
+	actual := make(map[string]int)
+	for _, output := range outputs {
+		content, err := ioutil.ReadFile(output)
+		assert.Nil(t, err)
+		var keys []string
+		for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+			if len(line) == 0 {
+				continue
+			}
+			fields := strings.Fields(line)
+			count, err := strconv.Atoi(fields[1])
+			assert.Nil(t, err)
+			actual[fields[0]] += count
+			keys = append(keys, fields[0])
+		}
+		assert.True(t, sort.StringsAreSorted(keys))
+	}
+	assert.Equal(t, expect, actual)
+}
+
+func TestRunFileJobRetry(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mrtest-")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	input := filepath.Join(dir, "input")
+	assert.Nil(t, ioutil.WriteFile(input, []byte("a\nb\na"), 0644))
+	var mapFailures, reduceFailures int32
+	outputs, err := RunFileJob(FileJob{
+		Inputs:    []string{input},
+		OutputDir: dir,
+		TempDir:   filepath.Join(dir, "tmp"),
+		Chunks:    1,
+		Map: func(line string, emit func(key, value string)) error {
+			emit(line, "1")
+			if line == "b" && atomic.AddInt32(&mapFailures, 1) == 1 {
+				return errors.New("map failed")
+			}
+			return nil
+		},
+		Reduce: func(key string, values []string, emit func(line string)) error {
+			if atomic.AddInt32(&reduceFailures, 1) == 1 {
+				panic("reduce failed")
+			}
+			emit(fmt.Sprintf("%s %d", key, len(values)))
+			return nil
+		},
+	})
+	assert.Nil(t, err)
+	content, err := ioutil.ReadFile(outputs[0])
+	assert.Nil(t, err)
+	assert.Equal(t, "a 2\nb 1\n", string(content))
+}
+
+func TestRunFileJobFailed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mrtest-")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	input := filepath.Join(dir, "input")
+	assert.Nil(t, ioutil.WriteFile(input, []byte("a\nb"), 0644))
+	var attempts int32
+	_, err = RunFileJob(FileJob{
+		Inputs:    []string{input},
+		OutputDir: dir,
+		Chunks:    1,
+		Retries:   2,
+		Map: func(line string, emit func(key, value string)) error {
+			atomic.AddInt32(&attempts, 1)
+			return errDummy
+		},
+		Reduce: func(key string, values []string, emit func(line string)) error {
+			return nil
+		},
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&attempts))
+
+	_, err = RunFileJob(FileJob{})
+	assert.Equal(t, ErrNoJobFunc, err)
+}
@@ -1,9 +1,11 @@
 package mr
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
 
 	"github.com/tal-tech/go-zero/core/errorx"
 	"github.com/tal-tech/go-zero/core/lang"
@@ -30,8 +32,26 @@ type (
 	VoidReducerFunc func(pipe <-chan interface{}, cancel func(error))
 	Option          func(opts *mapReduceOptions)
 
+	// Progress is the count of the items that are generated, mapped and reduced so far.
+	Progress struct {
+		Generated int64
+		Mapped    int64
+		Reduced   int64
+	}
+
 	mapReduceOptions struct {
-		workers int
+		workers  int
+		ordered  bool
+		buffer   int
+		ctx      context.Context
+		progress *progressTracker
+	}
+
+	progressTracker struct {
+		fn        func(Progress)
+		generated int64
+		mapped    int64
+		reduced   int64
 	}
 
 	Writer interface {
@@ -75,23 +95,61 @@ func FinishVoid(fns ...func()) {
 
 func Map(generate GenerateFunc, mapper MapFunc, opts ...Option) chan interface{} {
 	options := buildOptions(opts...)
-	source := buildSource(generate)
+	source := options.progress.countGenerated(buildSource(generate))
 	collector := make(chan interface{}, options.workers)
 	done := syncx.NewDoneChan()
+	finished := make(chan lang.PlaceholderType)
 
-	go executeMappers(mapper, source, collector, done.Done(), options.workers)
+	go func() {
+		defer close(finished)
+		runMappers(options.track(func(item interface{}, writer Writer) {
+			mapper(item, writer)
+		}, nil), source, collector, done.Done(), options)
+	}()
+	if ctx := options.ctx; ctx != nil {
+		go func() {
+			select {
+			case <-ctx.Done():
+				done.Close()
+				drain(source)
+			case <-finished:
+			}
+		}()
+	}
 
 	return collector
 }
 
+// MapReduce maps the items from generate with mapper, and reduces the mapped items with reducer.
+// The panics in generate, mapper and reducer are returned as errors.
 func MapReduce(generate GenerateFunc, mapper MapperFunc, reducer ReducerFunc, opts ...Option) (interface{}, error) {
-	source := buildSource(generate)
-	return MapReduceWithSource(source, mapper, reducer, opts...)
+	var genErr errorx.AtomicError
+	source := buildSource(func(source chan<- interface{}) {
+		defer func() {
+			if r := recover(); r != nil {
+				genErr.Set(fmt.Errorf("generate panic: %v", r))
+			}
+		}()
+
+		generate(source)
+	})
+
+	val, err := MapReduceWithSource(source, mapper, reducer, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err := genErr.Load(); err != nil {
+		return nil, err
+	}
+
+	return val, nil
 }
 
 func MapReduceWithSource(source <-chan interface{}, mapper MapperFunc, reducer ReducerFunc,
 	opts ...Option) (interface{}, error) {
 	options := buildOptions(opts...)
+	// cancel drains the counted source as well, to not leave the counting goroutine blocked
+	source = options.progress.countGenerated(source)
 	output := make(chan interface{})
 	collector := make(chan interface{}, options.workers)
 	done := syncx.NewDoneChan()
@@ -111,10 +169,23 @@ func MapReduceWithSource(source <-chan interface{}, mapper MapperFunc, reducer R
 			retErr.Set(ErrCancelWithNil)
 		}
 
-		drain(source)
+		// finish first to return at once, the source is drained in background
+		// to not leave the generator blocked, which might still take long to end
 		finish()
+		go drain(source)
 	})
 
+	if ctx := options.ctx; ctx != nil {
+		go func() {
+			select {
+			case <-ctx.Done():
+				cancel(ctx.Err())
+			case <-done.Done():
+			}
+		}()
+	}
+
+	pipe := options.progress.countReduced(collector)
 	go func() {
 		defer func() {
 			if r := recover(); r != nil {
@@ -123,13 +194,13 @@ func MapReduceWithSource(source <-chan interface{}, mapper MapperFunc, reducer R
 				finish()
 			}
 		}()
-		reducer(collector, writer, cancel)
-		drain(collector)
+		reducer(pipe, writer, cancel)
+		drain(pipe)
 	}()
 
-	go executeMappers(func(item interface{}, w Writer) {
+	go runMappers(options.track(func(item interface{}, w Writer) {
 		mapper(item, w, cancel)
-	}, source, collector, done.Done(), options.workers)
+	}, cancel), source, collector, done.Done(), options)
 
 	value, ok := <-output
 	if err := retErr.Load(); err != nil {
@@ -168,6 +239,32 @@ func WithWorkers(workers int) Option {
 	}
 }
 
+// WithContext stops the mapping and reducing when ctx is done, MapReduce returns the error of ctx.
+func WithContext(ctx context.Context) Option {
+	return func(opts *mapReduceOptions) {
+		opts.ctx = ctx
+	}
+}
+
+// WithProgress calls fn with the progress on each item generated, mapped or reduced,
+// fn is called concurrently.
+func WithProgress(fn func(Progress)) Option {
+	return func(opts *mapReduceOptions) {
+		opts.progress = &progressTracker{
+			fn: fn,
+		}
+	}
+}
+
+// WithOrdered keeps the mapped items in the order of the source, the slower items are waited with
+// at most buffer items in flight or done, buffer is at least the workers.
+func WithOrdered(buffer int) Option {
+	return func(opts *mapReduceOptions) {
+		opts.ordered = true
+		opts.buffer = buffer
+	}
+}
+
 func buildOptions(opts ...Option) *mapReduceOptions {
 	options := newOptions()
 	for _, opt := range opts {
@@ -194,6 +291,15 @@ func drain(channel <-chan interface{}) {
 	}
 }
 
+func runMappers(mapper MapFunc, input <-chan interface{}, collector chan<- interface{},
+	done <-chan lang.PlaceholderType, options *mapReduceOptions) {
+	if options.ordered {
+		executeOrderedMappers(mapper, input, collector, done, options.workers, options.buffer)
+	} else {
+		executeMappers(mapper, input, collector, done, options.workers)
+	}
+}
+
 func executeMappers(mapper MapFunc, input <-chan interface{}, collector chan<- interface{},
 	done <-chan lang.PlaceholderType, workers int) {
 	var wg sync.WaitGroup
@@ -229,6 +335,78 @@ func executeMappers(mapper MapFunc, input <-chan interface{}, collector chan<- i
 	}
 }
 
+// executeOrderedMappers is like executeMappers, but the outputs of each item are written into
+// the collector in the order of the input, with at most buffer items in flight or waiting.
+func executeOrderedMappers(mapper MapFunc, input <-chan interface{}, collector chan<- interface{},
+	done <-chan lang.PlaceholderType, workers, buffer int) {
+	if buffer < workers {
+		buffer = workers
+	}
+
+	var wg sync.WaitGroup
+	results := make(chan chan []interface{}, buffer)
+	slots := make(chan lang.PlaceholderType, buffer)
+	emitted := make(chan lang.PlaceholderType)
+	defer func() {
+		close(results)
+		wg.Wait()
+		<-emitted
+		close(collector)
+	}()
+
+	go func() {
+		defer close(emitted)
+
+		writer := newGuardedWriter(collector, done)
+		for result := range results {
+			select {
+			case <-done:
+				return
+			case items := <-result:
+				for _, item := range items {
+					writer.Write(item)
+				}
+				<-slots
+			}
+		}
+	}()
+
+	pool := make(chan lang.PlaceholderType, workers)
+	for {
+		select {
+		case <-done:
+			return
+		case slots <- lang.Placeholder:
+		}
+
+		select {
+		case <-done:
+			return
+		case pool <- lang.Placeholder:
+			item, ok := <-input
+			if !ok {
+				<-pool
+				return
+			}
+
+			result := make(chan []interface{}, 1)
+			results <- result
+			wg.Add(1)
+			// better to safely run caller defined method
+			threading.GoSafe(func() {
+				writer := new(sliceWriter)
+				defer func() {
+					result <- writer.items
+					wg.Done()
+					<-pool
+				}()
+
+				mapper(item, writer)
+			})
+		}
+	}
+}
+
 func newOptions() *mapReduceOptions {
 	return &mapReduceOptions{
 		workers: defaultWorkers,
@@ -244,6 +422,87 @@ func once(fn func(error)) func(error) {
 	}
 }
 
+// track counts the progress of the mapper, the panics of the mapper are sent to cancel if not nil,
+// otherwise they are logged and the item is skipped.
+func (opts *mapReduceOptions) track(mapper MapFunc, cancel func(error)) MapFunc {
+	return func(item interface{}, writer Writer) {
+		defer func() {
+			if r := recover(); r != nil {
+				if cancel == nil {
+					panic(r)
+				}
+				cancel(fmt.Errorf("mapper panic on item %v: %v", item, r))
+			}
+			opts.progress.addMapped()
+		}()
+
+		mapper(item, writer)
+	}
+}
+
+func (pt *progressTracker) addGenerated() {
+	if pt != nil {
+		pt.add(&pt.generated)
+	}
+}
+
+func (pt *progressTracker) addMapped() {
+	if pt != nil {
+		pt.add(&pt.mapped)
+	}
+}
+
+func (pt *progressTracker) addReduced() {
+	if pt != nil {
+		pt.add(&pt.reduced)
+	}
+}
+
+func (pt *progressTracker) add(counter *int64) {
+	atomic.AddInt64(counter, 1)
+	pt.fn(Progress{
+		Generated: atomic.LoadInt64(&pt.generated),
+		Mapped:    atomic.LoadInt64(&pt.mapped),
+		Reduced:   atomic.LoadInt64(&pt.reduced),
+	})
+}
+
+// countGenerated returns a channel that counts the items taken from source.
+func (pt *progressTracker) countGenerated(source <-chan interface{}) <-chan interface{} {
+	if pt == nil {
+		return source
+	}
+
+	pipe := make(chan interface{})
+	go func() {
+		defer close(pipe)
+		for item := range source {
+			pt.addGenerated()
+			pipe <- item
+		}
+	}()
+
+	return pipe
+}
+
+// countReduced returns a channel that counts the items taken from collector.
+func (pt *progressTracker) countReduced(collector <-chan interface{}) <-chan interface{} {
+	if pt == nil {
+		return collector
+	}
+
+	pipe := make(chan interface{})
+	go func() {
+		defer close(pipe)
+		for item := range collector {
+			pipe <- item
+			pt.addReduced()
+		}
+	}()
+
+	return pipe
+}
+
 type guardedWriter struct {
 	channel chan<- interface{}
 	done    <-chan lang.PlaceholderType
@@ -264,3 +523,14 @@ func (gw guardedWriter) Write(v interface{}) {
 		gw.channel <- v
 	}
 }
+
+type sliceWriter struct {
+	lock  sync.Mutex
+	items []interface{}
+}
+
+func (sw *sliceWriter) Write(v interface{}) {
+	sw.lock.Lock()
+	sw.items = append(sw.items, v)
+	sw.lock.Unlock()
+}
@@ -1,15 +1,20 @@
 package mr
 
 import (
+	"context"
 	"errors"
 	"io/ioutil"
 	"log"
+	"math/rand"
 	"runtime"
+	"sort"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/tal-tech/go-zero/core/lang"
 	"github.com/tal-tech/go-zero/core/stringx"
 	"github.com/tal-tech/go-zero/core/syncx"
 )
@@ -375,7 +380,8 @@ func TestMapReduceVoidCancelWithRemains(t *testing.T) {
 	})
 	assert.NotNil(t, err)
 	assert.Equal(t, "anything", err.Error())
-	assert.True(t, done.True())
+	// the remains are drained in background after cancelled
+	assert.Eventually(t, done.True, time.Second, time.Millisecond*10)
 }
 
 func TestMapReduceWithoutReducerWrite(t *testing.T) {
@@ -416,3 +422,227 @@ func BenchmarkMapReduce(b *testing.B) {
 		}, mapper, reducer)
 	}
 }
+
+func TestMapOrdered(t *testing.T) {
+	var items []interface{}
+	for item := range Map(func(source chan<- interface{}) {
+		for i := 0; i < 100; i++ {
+			source <- i
+		}
+	}, func(item interface{}, writer Writer) {
+		time.Sleep(time.Duration(rand.Intn(3)) * time.Millisecond)
+		writer.Write(item.(int) * 2)
+	}, WithWorkers(8), WithOrdered(16)) {
+		items = append(items, item)
+	}
+
+	assert.Equal(t, 100, len(items))
+	for i, item := range items {
+		assert.Equal(t, i*2, item)
+	}
+}
+
+func TestMapReduceOrdered(t *testing.T) {
+	var started int32
+	val, err := MapReduce(func(source chan<- interface{}) {
+		for i := 0; i < 20; i++ {
+			source <- i
+		}
+	}, func(item interface{}, writer Writer, cancel func(error)) {
+		atomic.AddInt32(&started, 1)
+		if item.(int) == 0 {
+			time.Sleep(time.Millisecond * 50)
+			// the buffer bounds how far the others go
+			assert.Equal(t, int32(4), atomic.LoadInt32(&started))
+		}
+		writer.Write(item)
+	}, func(pipe <-chan interface{}, writer Writer, cancel func(error)) {
+		var items []int
+		for item := range pipe {
+			items = append(items, item.(int))
+		}
+		writer.Write(items)
+	}, WithWorkers(2), WithOrdered(4))
+	assert.Nil(t, err)
+	items := val.([]int)
+	assert.Equal(t, 20, len(items))
+	assert.True(t, sort.IntsAreSorted(items))
+}
+
+func TestMapReduceOrderedCancel(t *testing.T) {
+	_, err := MapReduce(func(source chan<- interface{}) {
+		for i := 0; i < 100; i++ {
+			source <- i
+		}
+	}, func(item interface{}, writer Writer, cancel func(error)) {
+		if item.(int) == 10 {
+			cancel(errDummy)
+		}
+		writer.Write(item)
+	}, func(pipe <-chan interface{}, writer Writer, cancel func(error)) {
+		drain(pipe)
+	}, WithOrdered(4))
+	assert.Equal(t, errDummy, err)
+}
+
+func TestMapReduceWithContext(t *testing.T) {
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	var mapped int32
+	_, err := MapReduce(func(source chan<- interface{}) {
+		for i := 0; i < 1000; i++ {
+			source <- i
+		}
+	}, func(item interface{}, writer Writer, cancel func(error)) {
+		if atomic.AddInt32(&mapped, 1) == 10 {
+			stop()
+		}
+		writer.Write(item)
+	}, func(pipe <-chan interface{}, writer Writer, cancel func(error)) {
+		for range pipe {
+			time.Sleep(time.Millisecond)
+		}
+		writer.Write(lang.Placeholder)
+	}, WithContext(ctx))
+	assert.Equal(t, context.Canceled, err)
+	assert.True(t, atomic.LoadInt32(&mapped) < 1000)
+}
+
+func TestMapReduceWithContextSlowGenerator(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	generated := make(chan lang.PlaceholderType)
+	start := time.Now()
+	_, err := MapReduce(func(source chan<- interface{}) {
+		defer close(generated)
+		for i := 0; i < 30; i++ {
+			time.Sleep(time.Millisecond * 100)
+			source <- i
+		}
+	}, func(item interface{}, writer Writer, cancel func(error)) {
+		writer.Write(item)
+	}, func(pipe <-chan interface{}, writer Writer, cancel func(error)) {
+		for range pipe {
+		}
+		writer.Write(lang.Placeholder)
+	}, WithContext(ctx))
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.True(t, time.Since(start) < time.Second, "returns without waiting for the generator")
+
+	// the generator is not blocked after returned
+	select {
+	case <-generated:
+	case <-time.After(time.Second * 5):
+		t.Fatal("generator blocked")
+	}
+}
+
+func TestMapWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var count int
+	for range Map(func(source chan<- interface{}) {
+		for i := 0; i < 1000; i++ {
+			source <- i
+		}
+	}, func(item interface{}, writer Writer) {
+		writer.Write(item)
+	}, WithContext(ctx), WithWorkers(1)) {
+		count++
+		if count == 10 {
+			cancel()
+		}
+	}
+	assert.True(t, count < 1000)
+}
+
+func TestMapReduceWithProgress(t *testing.T) {
+	var lock sync.Mutex
+	var last Progress
+	val, err := MapReduce(func(source chan<- interface{}) {
+		for i := 0; i < 10; i++ {
+			source <- i
+		}
+	}, func(item interface{}, writer Writer, cancel func(error)) {
+		writer.Write(item)
+	}, func(pipe <-chan interface{}, writer Writer, cancel func(error)) {
+		var sum int
+		for item := range pipe {
+			sum += item.(int)
+		}
+		writer.Write(sum)
+	}, WithProgress(func(p Progress) {
+		lock.Lock()
+		defer lock.Unlock()
+		if p.Generated >= last.Generated && p.Mapped >= last.Mapped && p.Reduced >= last.Reduced {
+			last = p
+		}
+	}))
+	assert.Nil(t, err)
+	assert.Equal(t, 45, val)
+	lock.Lock()
+	assert.Equal(t, Progress{Generated: 10, Mapped: 10, Reduced: 10}, last)
+	lock.Unlock()
+}
+
+func TestMapReduceProgressGeneratedOnCancel(t *testing.T) {
+	var lock sync.Mutex
+	var last Progress
+	_, err := MapReduce(func(source chan<- interface{}) {
+		for i := 0; i < 100; i++ {
+			source <- i
+		}
+	}, func(item interface{}, writer Writer, cancel func(error)) {
+		cancel(errDummy)
+	}, func(pipe <-chan interface{}, writer Writer, cancel func(error)) {
+		drain(pipe)
+	}, WithWorkers(1), WithProgress(func(p Progress) {
+		lock.Lock()
+		defer lock.Unlock()
+		if p.Generated > last.Generated {
+			last = p
+		}
+	}))
+	assert.Equal(t, errDummy, err)
+	// the items drained in background on cancelling are generated but not mapped
+	assert.Eventually(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return last.Generated == 100
+	}, time.Second, time.Millisecond*10)
+	lock.Lock()
+	assert.True(t, last.Mapped < 100)
+	lock.Unlock()
+}
+
+func TestMapReduceMapperPanic(t *testing.T) {
+	_, err := MapReduce(func(source chan<- interface{}) {
+		for i := 0; i < 10; i++ {
+			source <- i
+		}
+	}, func(item interface{}, writer Writer, cancel func(error)) {
+		if item.(int) == 5 {
+			panic("boom")
+		}
+		writer.Write(item)
+	}, func(pipe <-chan interface{}, writer Writer, cancel func(error)) {
+		drain(pipe)
+		writer.Write(lang.Placeholder)
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, "mapper panic on item 5: boom", err.Error())
+}
+
+func TestMapReduceGeneratePanic(t *testing.T) {
+	_, err := MapReduce(func(source chan<- interface{}) {
+		source <- 1
+		panic("boom")
+	}, func(item interface{}, writer Writer, cancel func(error)) {
+		writer.Write(item)
+	}, func(pipe <-chan interface{}, writer Writer, cancel func(error)) {
+		drain(pipe)
+		writer.Write(lang.Placeholder)
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, "generate panic: boom", err.Error())
+}
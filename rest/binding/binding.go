@@ -0,0 +1,334 @@
+package binding
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/textproto"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// RequestKey is the context key that the bound request object is stored with.
+	RequestKey = "request"
+
+	inTag       = "in"
+	nameTag     = "name"
+	defaultTag  = "default"
+	validateTag = "validate"
+
+	InPath   = "path"
+	InQuery  = "query"
+	InHeader = "header"
+	InForm   = "form"
+	InBody   = "body"
+
+	defaultMaxMemory = 32 << 20
+)
+
+var (
+	ErrNotStructPointer    = errors.New("binding target must be a pointer to struct")
+	ErrUnsupportedType     = errors.New("unsupported field type")
+	ErrUnsupportedBodyType = errors.New("unsupported content type")
+
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+type (
+	// ParamFunc returns the path parameter with the given name, supplied by the framework adapters.
+	ParamFunc func(name string) string
+
+	// A FieldError describes why a single field failed to bind or validate.
+	FieldError struct {
+		Field   string `json:"field"`
+		In      string `json:"in,omitempty"`
+		Name    string `json:"name,omitempty"`
+		Message string `json:"message"`
+	}
+
+	// A ValidationError aggregates all the field errors of one binding.
+	ValidationError struct {
+		Fields []FieldError `json:"fields"`
+	}
+
+	binder struct {
+		r      *http.Request
+		params ParamFunc
+		errs   []FieldError
+	}
+)
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		msgs = append(msgs, fmt.Sprintf("%s: %s", field.Field, field.Message))
+	}
+
+	return strings.Join(msgs, "; ")
+}
+
+// Bind fills dst, a pointer to struct, from the request.
+// Fields are tagged like `in:"query" name:"page" default:"1" validate:"min=1,max=100"`,
+// in is one of path, query, header, form and body, the name defaults to the field name.
+// If the request carries a JSON or XML body, it's decoded into dst first, then the tagged fields are filled,
+// and the defaults are applied to the fields that are still unset.
+// All the failed fields are returned together as *ValidationError.
+func Bind(r *http.Request, params ParamFunc, dst interface{}) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return ErrNotStructPointer
+	}
+
+	b := &binder{
+		r:      r,
+		params: params,
+	}
+	if err := b.decodeBody(dst); err != nil {
+		return err
+	}
+
+	b.bindStruct(v.Elem())
+	if len(b.errs) > 0 {
+		return &ValidationError{Fields: b.errs}
+	}
+
+	return nil
+}
+
+// DecodeBody decodes the body into dst by the given idl, json or xml.
+func DecodeBody(body []byte, idl string, dst interface{}) error {
+	switch idl {
+	case "json":
+		return json.Unmarshal(body, dst)
+	case "xml":
+		return xml.Unmarshal(body, dst)
+	default:
+		return ErrUnsupportedBodyType
+	}
+}
+
+func (b *binder) addError(field reflect.StructField, in, name, msg string) {
+	b.errs = append(b.errs, FieldError{
+		Field:   field.Name,
+		In:      in,
+		Name:    name,
+		Message: msg,
+	})
+}
+
+func (b *binder) bindStruct(v reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fv := v.Field(i)
+		if len(field.PkgPath) > 0 && !field.Anonymous {
+			continue
+		}
+
+		in := field.Tag.Get(inTag)
+		if field.Anonymous && len(in) == 0 && fv.Kind() == reflect.Struct {
+			b.bindStruct(fv)
+			continue
+		}
+
+		name := field.Tag.Get(nameTag)
+		if len(name) == 0 {
+			name = field.Name
+		}
+
+		var present bool
+		sourced := len(in) > 0 && in != InBody
+		if sourced {
+			values, err := b.lookup(in, name)
+			if err != nil {
+				b.addError(field, in, name, err.Error())
+				continue
+			}
+
+			if len(values) > 0 {
+				present = true
+				if err := setValues(fv, values); err != nil {
+					b.addError(field, in, name, err.Error())
+					continue
+				}
+			}
+		}
+
+		// the values decoded from the body are kept, defaults only fill the unset fields
+		if !present && !fv.IsZero() {
+			present = true
+		}
+		if !present {
+			if def, ok := field.Tag.Lookup(defaultTag); ok {
+				present = true
+				if err := setValues(fv, []string{def}); err != nil {
+					b.addError(field, in, name, err.Error())
+					continue
+				}
+			} else if sourced && isRequired(field) {
+				b.addError(field, in, name, "required")
+				continue
+			}
+		}
+
+		if rules, ok := field.Tag.Lookup(validateTag); ok {
+			if err := validate(fv, rules, present); err != nil {
+				b.addError(field, in, name, err.Error())
+			}
+		}
+	}
+}
+
+func (b *binder) decodeBody(dst interface{}) error {
+	if b.r.Body == nil || b.r.ContentLength == 0 {
+		return nil
+	}
+
+	ct, _, _ := mime.ParseMediaType(b.r.Header.Get("Content-Type"))
+	var idl string
+	switch {
+	case ct == "application/json" || strings.HasSuffix(ct, "+json"):
+		idl = "json"
+	case ct == "application/xml" || ct == "text/xml" || strings.HasSuffix(ct, "+xml"):
+		idl = "xml"
+	default:
+		return nil
+	}
+
+	body, err := ioutil.ReadAll(b.r.Body)
+	if err != nil {
+		return err
+	}
+	if len(body) == 0 {
+		return nil
+	}
+
+	if err = DecodeBody(body, idl, dst); err != nil {
+		return &ValidationError{Fields: []FieldError{{
+			Field:   "body",
+			In:      InBody,
+			Message: err.Error(),
+		}}}
+	}
+
+	return nil
+}
+
+func (b *binder) lookup(in, name string) ([]string, error) {
+	switch in {
+	case InPath:
+		if b.params == nil {
+			return nil, nil
+		}
+		if val := b.params(name); len(val) > 0 {
+			return []string{val}, nil
+		}
+		return nil, nil
+	case InQuery:
+		return nonEmpty(b.r.URL.Query()[name]), nil
+	case InHeader:
+		return nonEmpty(b.r.Header[textproto.CanonicalMIMEHeaderKey(name)]), nil
+	case InForm:
+		if b.r.Form == nil {
+			if err := b.r.ParseMultipartForm(defaultMaxMemory); err != nil && err != http.ErrNotMultipart {
+				return nil, err
+			}
+		}
+		return nonEmpty(b.r.Form[name]), nil
+	default:
+		return nil, fmt.Errorf("unknown source %q", in)
+	}
+}
+
+func isRequired(field reflect.StructField) bool {
+	for _, rule := range strings.Split(field.Tag.Get(validateTag), ",") {
+		if strings.TrimSpace(rule) == "required" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func nonEmpty(values []string) []string {
+	if len(values) == 0 || len(values[0]) == 0 {
+		return nil
+	}
+
+	return values
+}
+
+func setValues(v reflect.Value, values []string) error {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return setValues(v.Elem(), values)
+	case reflect.Slice:
+		if len(values) == 1 {
+			values = strings.Split(values[0], ",")
+		}
+		slice := reflect.MakeSlice(v.Type(), len(values), len(values))
+		for i, val := range values {
+			if err := setValue(slice.Index(i), strings.TrimSpace(val)); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+		return nil
+	default:
+		return setValue(v, values[0])
+	}
+}
+
+func setValue(v reflect.Value, val string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(val)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(val)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(val, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(val, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(val, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return ErrUnsupportedType
+	}
+
+	return nil
+}
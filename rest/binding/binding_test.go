@@ -0,0 +1,136 @@
+package binding
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type (
+	Paging struct {
+		Page int `in:"query" name:"page" default:"1" validate:"min=1,max=100"`
+		Size int `in:"query" name:"size" default:"20" validate:"min=1,max=50"`
+	}
+
+	listRequest struct {
+		Paging
+		ID      int64         `in:"path" name:"id" validate:"required"`
+		Token   string        `in:"header" name:"X-Token" validate:"required"`
+		Order   string        `in:"query" name:"order" default:"asc" validate:"oneof=asc desc"`
+		Tags    []string      `in:"query" name:"tag"`
+		Timeout time.Duration `in:"query" name:"timeout" default:"1s"`
+		Verbose *bool         `in:"query" name:"verbose"`
+	}
+
+	bodyRequest struct {
+		ID   int64  `in:"path" name:"id" json:"-" xml:"-"`
+		Name string `json:"name" xml:"name" validate:"required,max=5"`
+		Age  int    `json:"age" xml:"age" validate:"min=0,max=150"`
+	}
+
+	defaultRequest struct {
+		Page  int    `json:"page" in:"query" name:"page" default:"1"`
+		Order string `json:"order" default:"asc"`
+		Name  string `json:"name" default:"anonymous"`
+	}
+
+	formRequest struct {
+		Name string `in:"form" name:"name" validate:"required"`
+	}
+)
+
+func params(kv map[string]string) ParamFunc {
+	return func(name string) string {
+		return kv[name]
+	}
+}
+
+func TestBindQueryPathHeader(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/items/7?size=10&tag=a&tag=b&verbose=true", nil)
+	r.Header.Set("X-Token", "abc")
+
+	var req listRequest
+	assert.Nil(t, Bind(r, params(map[string]string{"id": "7"}), &req))
+	assert.Equal(t, int64(7), req.ID)
+	assert.Equal(t, "abc", req.Token)
+	assert.Equal(t, 1, req.Page)
+	assert.Equal(t, 10, req.Size)
+	assert.Equal(t, "asc", req.Order)
+	assert.Equal(t, []string{"a", "b"}, req.Tags)
+	assert.Equal(t, time.Second, req.Timeout)
+	assert.True(t, *req.Verbose)
+}
+
+func TestBindAggregatesErrors(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/items?page=0&size=abc&order=random", nil)
+
+	var req listRequest
+	err := Bind(r, params(nil), &req)
+	verr, ok := err.(*ValidationError)
+	assert.True(t, ok)
+	var fields []string
+	for _, field := range verr.Fields {
+		fields = append(fields, field.Field)
+	}
+	assert.Equal(t, []string{"Page", "Size", "ID", "Token", "Order"}, fields)
+}
+
+func TestBindJsonAndXml(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/items/3", strings.NewReader(`{"name":"kevin","age":18}`))
+	r.Header.Set("Content-Type", "application/json; charset=utf-8")
+	var req bodyRequest
+	assert.Nil(t, Bind(r, params(map[string]string{"id": "3"}), &req))
+	assert.Equal(t, bodyRequest{ID: 3, Name: "kevin", Age: 18}, req)
+
+	r = httptest.NewRequest(http.MethodPost, "/items/3",
+		strings.NewReader(`<bodyRequest><name>kevin</name><age>18</age></bodyRequest>`))
+	r.Header.Set("Content-Type", "application/xml")
+	req = bodyRequest{}
+	assert.Nil(t, Bind(r, params(map[string]string{"id": "3"}), &req))
+	assert.Equal(t, bodyRequest{ID: 3, Name: "kevin", Age: 18}, req)
+
+	r = httptest.NewRequest(http.MethodPost, "/items/3", strings.NewReader(`{"name":"kevin-wan","age":200}`))
+	r.Header.Set("Content-Type", "application/json")
+	err := Bind(r, params(nil), &bodyRequest{})
+	assert.Equal(t, 2, len(err.(*ValidationError).Fields))
+
+	r = httptest.NewRequest(http.MethodPost, "/items/3", strings.NewReader(`{"name":`))
+	r.Header.Set("Content-Type", "application/json")
+	assert.NotNil(t, Bind(r, params(nil), &bodyRequest{}))
+}
+
+func TestBindDefaultsKeepDecoded(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/items", strings.NewReader(`{"page":3,"order":"desc"}`))
+	r.Header.Set("Content-Type", "application/json")
+	var req defaultRequest
+	assert.Nil(t, Bind(r, nil, &req))
+	assert.Equal(t, 3, req.Page)
+	assert.Equal(t, "desc", req.Order)
+	assert.Equal(t, "anonymous", req.Name)
+
+	r = httptest.NewRequest(http.MethodPost, "/items?page=5", strings.NewReader(`{"page":3}`))
+	r.Header.Set("Content-Type", "application/json")
+	req = defaultRequest{}
+	assert.Nil(t, Bind(r, nil, &req))
+	assert.Equal(t, 5, req.Page)
+	assert.Equal(t, "asc", req.Order)
+}
+
+func TestBindForm(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("name=kevin"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	var req formRequest
+	assert.Nil(t, Bind(r, nil, &req))
+	assert.Equal(t, "kevin", req.Name)
+}
+
+func TestBindNotStructPointer(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	var n int
+	assert.Equal(t, ErrNotStructPointer, Bind(r, nil, &n))
+	assert.Equal(t, ErrNotStructPointer, Bind(r, nil, formRequest{}))
+}
@@ -0,0 +1,102 @@
+package binding
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// validate checks v against rules like "required,min=1,max=100,oneof=asc desc".
+// min, max and len apply to the value of numbers, and to the length of strings, slices and maps.
+// If present is true, the value was given in the request, so required is satisfied even with a zero value.
+func validate(v reflect.Value, rules string, present bool) error {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			if strings.Contains(rules, "required") {
+				return errors.New("required")
+			}
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	for _, rule := range strings.Split(rules, ",") {
+		rule = strings.TrimSpace(rule)
+		if len(rule) == 0 {
+			continue
+		}
+
+		var name, arg string
+		if i := strings.IndexByte(rule, '='); i >= 0 {
+			name, arg = rule[:i], rule[i+1:]
+		} else {
+			name = rule
+		}
+
+		if name == "required" && present {
+			continue
+		}
+		if err := validateRule(v, name, arg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func validateRule(v reflect.Value, name, arg string) error {
+	switch name {
+	case "required":
+		if v.IsZero() {
+			return errors.New("required")
+		}
+	case "min", "max", "len":
+		limit, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return fmt.Errorf("bad rule %s=%s", name, arg)
+		}
+		n, ok := measure(v)
+		if !ok {
+			return fmt.Errorf("rule %s not applicable", name)
+		}
+		switch {
+		case name == "min" && n < limit:
+			return fmt.Errorf("must be at least %s", arg)
+		case name == "max" && n > limit:
+			return fmt.Errorf("must be at most %s", arg)
+		case name == "len" && n != limit:
+			return fmt.Errorf("length must be %s", arg)
+		}
+	case "oneof":
+		val := fmt.Sprint(v.Interface())
+		for _, option := range strings.Fields(arg) {
+			if val == option {
+				return nil
+			}
+		}
+		return fmt.Errorf("must be one of [%s]", arg)
+	default:
+		return fmt.Errorf("unknown rule %s", name)
+	}
+
+	return nil
+}
+
+func measure(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	case reflect.String:
+		return float64(len([]rune(v.String()))), true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(v.Len()), true
+	default:
+		return 0, false
+	}
+}
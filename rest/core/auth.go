@@ -0,0 +1,51 @@
+package core
+
+import (
+	"github.com/dgrijalva/jwt-go"
+	"github.com/tx991020/utils/rest/jwtauth"
+)
+
+var bearerSpec = Spec{
+	Security: []string{BearerAuth},
+}
+
+// JWTAuth verifies the bearer token of the request and stores the claims with key "claims".
+func JWTAuth(auth *jwtauth.Authenticator) Handler {
+	return Describe(func(c Context) {
+		claims, err := auth.Authenticate(c.Request())
+		if err != nil {
+			Error(c, err)
+		} else {
+			c.Set(jwtauth.ClaimsKey, claims)
+		}
+	}, bearerSpec)
+}
+
+// RequireScopes requires the claims stored by JWTAuth to grant all of the given scopes.
+func RequireScopes(scopes ...string) Handler {
+	return Describe(func(c Context) {
+		if err := jwtauth.RequireScopes(Claims(c), scopes...); err != nil {
+			Error(c, err)
+		}
+	}, bearerSpec)
+}
+
+// RequireRoles requires the claims stored by JWTAuth to have any of the given roles.
+func RequireRoles(roles ...string) Handler {
+	return Describe(func(c Context) {
+		if err := jwtauth.RequireRoles(Claims(c), roles...); err != nil {
+			Error(c, err)
+		}
+	}, bearerSpec)
+}
+
+// Claims returns the claims stored by JWTAuth, nil if not authenticated.
+func Claims(c Context) jwt.Claims {
+	if v, ok := c.Get(jwtauth.ClaimsKey); ok {
+		if claims, ok := v.(jwt.Claims); ok {
+			return claims
+		}
+	}
+
+	return nil
+}
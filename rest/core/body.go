@@ -0,0 +1,90 @@
+package core
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io/ioutil"
+	"reflect"
+
+	"github.com/tx991020/utils/rest/binding"
+)
+
+// RequestBodyKey is the context key that the request body is stored with.
+const RequestBodyKey = "requestBody"
+
+// RequestBody stores the raw request body with key "requestBody".
+func RequestBody(c Context) {
+	b, err := ioutil.ReadAll(c.Request().Body)
+	if err != nil {
+		Error(c, NewValidationError("Parse body failed."))
+	} else {
+		c.Set(RequestBodyKey, b)
+	}
+}
+
+// RequestBodyMap stores the json request body as map[string]interface{} with key "requestBody".
+func RequestBodyMap(c Context) {
+	b, err := ioutil.ReadAll(c.Request().Body)
+	if err != nil {
+		Error(c, NewValidationError("Parse body failed."))
+		return
+	}
+
+	m := map[string]interface{}{}
+	if err = json.Unmarshal(b, &m); err != nil {
+		Error(c, NewValidationError("Json unmarshal failed: %s, %v, %v", string(b), m, err))
+	} else {
+		c.Set(RequestBodyKey, m)
+	}
+}
+
+// RequestBodyObject decodes the request body into a new instance of t and stores it with key "requestBody".
+// idl = {json, xml}
+func RequestBodyObject(t reflect.Type, idl string) Handler {
+	return Describe(func(c Context) {
+		instance := reflect.New(t).Interface()
+
+		b, err := ioutil.ReadAll(c.Request().Body)
+		if err != nil {
+			Error(c, NewValidationError("Parse body failed."))
+			return
+		}
+
+		switch idl {
+		case "json":
+			if err = json.Unmarshal(b, instance); err != nil {
+				Error(c, NewValidationError("Json unmarshal failed: %s, %v, %v", string(b), instance, err))
+			} else {
+				c.Set(RequestBodyKey, instance)
+			}
+		case "xml":
+			if err = xml.Unmarshal(b, instance); err != nil {
+				Error(c, NewValidationError("Xml unmarshal failed: %s, %v, %v", string(b), instance, err))
+			} else {
+				c.Set(RequestBodyKey, instance)
+			}
+		}
+	}, Spec{
+		Body:    t,
+		BodyIDL: idl,
+	})
+}
+
+// RequestBind fills a new instance of t by its struct tags and stores it with key "request", see binding.Bind.
+func RequestBind(t reflect.Type) Handler {
+	return Describe(func(c Context) {
+		instance := reflect.New(t).Interface()
+		if err := Bind(c, instance); err != nil {
+			Error(c, err)
+		} else {
+			c.Set(binding.RequestKey, instance)
+		}
+	}, Spec{
+		Bind: t,
+	})
+}
+
+// Bind fills dst from the request of c, see binding.Bind.
+func Bind(c Context, dst interface{}) error {
+	return binding.Bind(c.Request(), c.Param, dst)
+}
@@ -0,0 +1,44 @@
+package core
+
+import "net/http"
+
+type (
+	// Context is the small set of framework operations that the handlers in core rely on,
+	// the adapters in xgin, xiris and xhttp implement it.
+	Context interface {
+		// Request returns the underlying http request.
+		Request() *http.Request
+		// Param returns the path parameter with the given name.
+		Param(name string) string
+		// Route returns the pattern of the matched route, or the request path if unknown.
+		Route() string
+		// Get returns the value stored with key during the request.
+		Get(key string) (interface{}, bool)
+		// Set stores the value with key during the request.
+		Set(key string, value interface{})
+		// Header returns the response header.
+		Header() http.Header
+		// Write writes the response with the given status and content type.
+		Write(status int, contentType string, body []byte)
+		// Status returns the status of the response, 200 if not written yet.
+		Status() int
+		// Next runs the pending handlers, handlers that don't call Next
+		// are continued by the adapters unless they are aborted.
+		Next()
+		// Abort stops the pending handlers.
+		Abort()
+	}
+
+	// A Handler handles requests against a Context.
+	Handler interface {
+		Handle(c Context)
+	}
+
+	// The HandlerFunc type is an adapter to allow the use of ordinary functions as handlers.
+	HandlerFunc func(c Context)
+)
+
+// Handle calls f(c).
+func (f HandlerFunc) Handle(c Context) {
+	f(c)
+}
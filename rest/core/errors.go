@@ -0,0 +1,136 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/tx991020/utils/rest/binding"
+	"github.com/tx991020/utils/rest/jwtauth"
+)
+
+// business codes of the response envelope
+const (
+	CodeOK           = 0
+	CodeValidation   = 40000
+	CodeUnauthorized = 40100
+	CodeForbidden    = 40300
+	CodeNotFound     = 40400
+	CodeRateLimited  = 42900
+	CodeInternal     = 50000
+	CodeUnavailable  = 50300
+)
+
+type (
+	// A CodeError is an error with the http status and the business code to respond with.
+	CodeError struct {
+		Status int
+		Code   int
+		Msg    string
+		// Key is the i18n message key, Msg is used if no translation is found.
+		Key  string
+		Args []interface{}
+		Data interface{}
+	}
+
+	// ErrorMapper maps errors that are not CodeErrors, returns false if not handled.
+	ErrorMapper func(err error) (*CodeError, bool)
+)
+
+var (
+	mappers    []ErrorMapper
+	mapperLock sync.RWMutex
+)
+
+func (e *CodeError) Error() string {
+	return e.Msg
+}
+
+// WithKey sets the i18n message key and its arguments.
+func (e *CodeError) WithKey(key string, args ...interface{}) *CodeError {
+	e.Key = key
+	e.Args = args
+	return e
+}
+
+// WithData sets the data of the response envelope.
+func (e *CodeError) WithData(data interface{}) *CodeError {
+	e.Data = data
+	return e
+}
+
+// NewCodeError returns a CodeError with the given http status, business code and message.
+func NewCodeError(status, code int, msg string) *CodeError {
+	return &CodeError{
+		Status: status,
+		Code:   code,
+		Msg:    msg,
+	}
+}
+
+func NewValidationError(format string, args ...interface{}) *CodeError {
+	return NewCodeError(http.StatusBadRequest, CodeValidation, fmt.Sprintf(format, args...))
+}
+
+func NewUnauthorizedError(format string, args ...interface{}) *CodeError {
+	return NewCodeError(http.StatusUnauthorized, CodeUnauthorized, fmt.Sprintf(format, args...))
+}
+
+func NewForbiddenError(format string, args ...interface{}) *CodeError {
+	return NewCodeError(http.StatusForbidden, CodeForbidden, fmt.Sprintf(format, args...))
+}
+
+func NewNotFoundError(format string, args ...interface{}) *CodeError {
+	return NewCodeError(http.StatusNotFound, CodeNotFound, fmt.Sprintf(format, args...))
+}
+
+func NewRateLimitedError(format string, args ...interface{}) *CodeError {
+	return NewCodeError(http.StatusTooManyRequests, CodeRateLimited, fmt.Sprintf(format, args...))
+}
+
+func NewInternalError(format string, args ...interface{}) *CodeError {
+	return NewCodeError(http.StatusInternalServerError, CodeInternal, fmt.Sprintf(format, args...))
+}
+
+func NewUnavailableError(format string, args ...interface{}) *CodeError {
+	return NewCodeError(http.StatusServiceUnavailable, CodeUnavailable, fmt.Sprintf(format, args...))
+}
+
+// RegisterErrorMapper registers fn to map errors of other packages, like sql.ErrNoRows to not found.
+func RegisterErrorMapper(fn ErrorMapper) {
+	mapperLock.Lock()
+	mappers = append(mappers, fn)
+	mapperLock.Unlock()
+}
+
+// ToCodeError maps err to a CodeError, unknown errors are mapped to internal errors
+// without exposing their messages.
+func ToCodeError(err error) *CodeError {
+	var cerr *CodeError
+	if errors.As(err, &cerr) {
+		return cerr
+	}
+
+	mapperLock.RLock()
+	defer mapperLock.RUnlock()
+	for _, mapper := range mappers {
+		if cerr, ok := mapper(err); ok {
+			return cerr
+		}
+	}
+
+	var verr *binding.ValidationError
+	if errors.As(err, &verr) {
+		return NewCodeError(http.StatusBadRequest, CodeValidation, verr.Error()).WithData(verr.Fields)
+	}
+
+	switch err {
+	case jwtauth.ErrTokenMissing, jwtauth.ErrTokenInvalid:
+		return NewCodeError(http.StatusUnauthorized, CodeUnauthorized, err.Error())
+	case jwtauth.ErrInsufficientScope, jwtauth.ErrInsufficientRole:
+		return NewCodeError(http.StatusForbidden, CodeForbidden, err.Error())
+	default:
+		return NewCodeError(http.StatusInternalServerError, CodeInternal, http.StatusText(http.StatusInternalServerError))
+	}
+}
@@ -0,0 +1,46 @@
+package core
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tx991020/utils/rest/binding"
+	"github.com/tx991020/utils/rest/jwtauth"
+)
+
+func TestToCodeError(t *testing.T) {
+	cerr := ToCodeError(fmt.Errorf("wrapped: %w", NewNotFoundError("user %d not found", 1)))
+	assert.Equal(t, http.StatusNotFound, cerr.Status)
+	assert.Equal(t, CodeNotFound, cerr.Code)
+	assert.Equal(t, "user 1 not found", cerr.Msg)
+
+	verr := &binding.ValidationError{Fields: []binding.FieldError{{Field: "Name", Message: "required"}}}
+	cerr = ToCodeError(verr)
+	assert.Equal(t, http.StatusBadRequest, cerr.Status)
+	assert.Equal(t, CodeValidation, cerr.Code)
+	assert.Equal(t, verr.Fields, cerr.Data)
+
+	assert.Equal(t, CodeUnauthorized, ToCodeError(jwtauth.ErrTokenInvalid).Code)
+	assert.Equal(t, http.StatusForbidden, ToCodeError(jwtauth.ErrInsufficientRole).Status)
+
+	cerr = ToCodeError(errors.New("db is down"))
+	assert.Equal(t, http.StatusInternalServerError, cerr.Status)
+	assert.Equal(t, CodeInternal, cerr.Code)
+	assert.NotContains(t, cerr.Msg, "db")
+}
+
+func TestRegisterErrorMapper(t *testing.T) {
+	RegisterErrorMapper(func(err error) (*CodeError, bool) {
+		if err == sql.ErrNoRows {
+			return NewNotFoundError("not found"), true
+		}
+		return nil, false
+	})
+
+	assert.Equal(t, CodeNotFound, ToCodeError(sql.ErrNoRows).Code)
+	assert.Equal(t, CodeInternal, ToCodeError(sql.ErrConnDone).Code)
+}
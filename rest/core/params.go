@@ -0,0 +1,179 @@
+package core
+
+import (
+	"strconv"
+
+	"github.com/tx991020/utils/rest/binding"
+)
+
+const MaxUint = ^uint64(0)
+const MaxInt = int64(MaxUint >> 1)
+
+// PathInt parses the path parameter match as int64 and stores it with key match and alias if not empty.
+func PathInt(match string, must bool, alias string) Handler {
+	return describeParam(func(c Context) {
+		if n, err := strconv.ParseInt(c.Param(match), 10, 64); err != nil {
+			if must {
+				Error(c, NewValidationError("Parse path %s failed.", match))
+			}
+		} else {
+			setWithAlias(c, match, alias, n)
+		}
+	}, binding.InPath, match, "integer", must, nil)
+}
+
+// PathString stores the path parameter match with key match and alias if not empty.
+func PathString(match string, must bool, alias string) Handler {
+	return describeParam(func(c Context) {
+		if val := c.Param(match); len(val) == 0 {
+			if must {
+				Error(c, NewValidationError("Parse path param: %s failed.", match))
+			}
+		} else {
+			setWithAlias(c, match, alias, val)
+		}
+	}, binding.InPath, match, "string", must, nil)
+}
+
+// HeaderInt parses the header match as int64 and stores it with key match and alias if not empty.
+func HeaderInt(match string, must bool, alias string) Handler {
+	return describeParam(func(c Context) {
+		if val := c.Request().Header.Get(match); len(val) > 0 {
+			if n, err := strconv.ParseInt(val, 10, 64); err != nil {
+				if must {
+					Error(c, NewValidationError("Parse header: %s failed.", match))
+				}
+			} else {
+				setWithAlias(c, match, alias, n)
+			}
+		} else if must {
+			Error(c, NewValidationError("Parse header: %s failed.", match))
+		}
+	}, binding.InHeader, match, "integer", must, nil)
+}
+
+// HeaderString stores the header match with key match and alias if not empty.
+func HeaderString(match string, must bool, alias string) Handler {
+	return describeParam(func(c Context) {
+		if val := c.Request().Header.Get(match); len(val) == 0 {
+			if must {
+				Error(c, NewValidationError("Parse header: %s failed.", match))
+			}
+		} else {
+			setWithAlias(c, match, alias, val)
+		}
+	}, binding.InHeader, match, "string", must, nil)
+}
+
+// HeaderStringDefault is like HeaderString, but stores defaultValue if the header is absent.
+func HeaderStringDefault(match string, must bool, alias, defaultValue string) Handler {
+	return describeParam(func(c Context) {
+		if val := c.Request().Header.Get(match); len(val) == 0 {
+			if must {
+				Error(c, NewValidationError("Parse header: %s failed.", match))
+				return
+			}
+			setWithAlias(c, match, alias, defaultValue)
+		} else {
+			setWithAlias(c, match, alias, val)
+		}
+	}, binding.InHeader, match, "string", must, defaultValue)
+}
+
+// QueryPositiveInt parses the query parameter match as a non-negative int64
+// and stores it with key match and alias if not empty.
+func QueryPositiveInt(match string, must bool, alias string) Handler {
+	var minimum int64
+	return Describe(func(c Context) {
+		if val := query(c, match); len(val) == 0 {
+			if must {
+				Error(c, NewValidationError("Parse query param: %s not exist.", match))
+			}
+		} else if n, err := strconv.ParseInt(val, 10, 64); err != nil {
+			Error(c, NewValidationError("Parse query param: %s failed.", match))
+		} else if n < 0 {
+			Error(c, NewValidationError("Parse query param: %s out of range.", match))
+		} else {
+			setWithAlias(c, match, alias, n)
+		}
+	}, Spec{
+		Params: []ParamSpec{{
+			In:       binding.InQuery,
+			Name:     match,
+			Type:     "integer",
+			Required: must,
+			Minimum:  &minimum,
+		}},
+	})
+}
+
+// QueryInt parses the query parameter match as int64 and stores it with key match and alias if not empty.
+// If the parameter is absent, defaultValue is stored unless it's MaxInt.
+func QueryInt(match string, must bool, alias string, defaultValue int64) Handler {
+	return describeParam(func(c Context) {
+		if val := query(c, match); len(val) == 0 {
+			if must {
+				Error(c, NewValidationError("Parse query param: %s not exist.", match))
+				return
+			}
+			if defaultValue != MaxInt {
+				setWithAlias(c, match, alias, defaultValue)
+			}
+		} else if n, err := strconv.ParseInt(val, 10, 64); err != nil {
+			Error(c, NewValidationError("Parse query param: %s failed.", match))
+		} else {
+			setWithAlias(c, match, alias, n)
+		}
+	}, binding.InQuery, match, "integer", must, queryIntDefault(defaultValue))
+}
+
+// QueryString stores the query parameter match with key match and alias if not empty.
+func QueryString(match string, must bool, alias string) Handler {
+	return describeParam(func(c Context) {
+		if val := query(c, match); len(val) == 0 {
+			if must {
+				Error(c, NewValidationError("Parse query param: %s failed.", match))
+			}
+		} else {
+			setWithAlias(c, match, alias, val)
+		}
+	}, binding.InQuery, match, "string", must, nil)
+}
+
+// QueryStringDefault is like QueryString, but stores defaultValue if the parameter is absent.
+func QueryStringDefault(match string, must bool, alias, defaultValue string) Handler {
+	return describeParam(func(c Context) {
+		if val := query(c, match); len(val) == 0 {
+			if must {
+				Error(c, NewValidationError("Parse query param: %s failed.", match))
+				return
+			}
+			setWithAlias(c, match, alias, defaultValue)
+		} else {
+			setWithAlias(c, match, alias, val)
+		}
+	}, binding.InQuery, match, "string", must, defaultValue)
+}
+
+func queryIntDefault(defaultValue int64) interface{} {
+	if defaultValue == MaxInt {
+		return nil
+	}
+
+	return defaultValue
+}
+
+func query(c Context, match string) string {
+	if values := c.Request().URL.Query()[match]; len(values) > 0 {
+		return values[0]
+	}
+
+	return ""
+}
+
+func setWithAlias(c Context, match, alias string, value interface{}) {
+	c.Set(match, value)
+	if len(alias) > 0 {
+		c.Set(alias, value)
+	}
+}
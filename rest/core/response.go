@@ -0,0 +1,165 @@
+package core
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/tx991020/utils"
+	"golang.org/x/text/language"
+)
+
+const (
+	// ResponseKey is the context key of the data that JsonResponse renders.
+	ResponseKey = "response"
+	// ErrorKey is the context key of the error that JsonResponse renders.
+	ErrorKey = "responseError"
+	// RequestIDKey is the context key of the request id.
+	RequestIDKey = "requestId"
+
+	okMsg           = "ok"
+	textContentType = "text/plain"
+	jsonContentType = "application/json"
+	requestIDLen    = 16
+)
+
+// Translator translates the message key into the given language, returns false if not found.
+type Translator func(lang, key string, args ...interface{}) (string, bool)
+
+var (
+	translator     Translator
+	translatorLock sync.RWMutex
+)
+
+// SetTranslator sets the translator for the messages of CodeErrors with keys,
+// the language is taken from the Accept-Language header.
+func SetTranslator(fn Translator) {
+	translatorLock.Lock()
+	translator = fn
+	translatorLock.Unlock()
+}
+
+// OK responds with data in the standard envelope.
+func OK(c Context, data interface{}) {
+	render(c, http.StatusOK, utils.Response{
+		Code: CodeOK,
+		Msg:  okMsg,
+		Data: data,
+	})
+}
+
+// Error responds with err in the standard envelope and aborts the pending handlers,
+// err is mapped to the http status and business code by ToCodeError.
+func Error(c Context, err error) {
+	cerr := ToCodeError(err)
+	render(c, cerr.Status, utils.Response{
+		Code: cerr.Code,
+		Msg:  translate(c, cerr),
+		Data: cerr.Data,
+	})
+	c.Abort()
+}
+
+// SetResponse stores data for JsonResponse to render.
+func SetResponse(c Context, data interface{}) {
+	c.Set(ResponseKey, data)
+}
+
+// SetError stores err for JsonResponse to render.
+func SetError(c Context, err error) {
+	c.Set(ErrorKey, err)
+}
+
+// JsonResponse runs the pending handlers, then renders the error stored by SetError
+// or the data stored by SetResponse in the standard envelope.
+func JsonResponse(c Context) {
+	c.Next()
+
+	if v, ok := c.Get(ErrorKey); ok {
+		if err, ok := v.(error); ok && err != nil {
+			Error(c, err)
+			return
+		}
+	}
+
+	if data, ok := c.Get(ResponseKey); ok {
+		OK(c, data)
+	}
+}
+
+// RequestID takes the request id from the given header, or generates one if absent,
+// stores it with key "requestId", echoes it in the response header and adds it to the envelope.
+func RequestID(header string) HandlerFunc {
+	return func(c Context) {
+		id := c.Request().Header.Get(header)
+		if len(id) == 0 {
+			id = newRequestID()
+		}
+		c.Set(RequestIDKey, id)
+		c.Header().Set(header, id)
+	}
+}
+
+// JSON writes v in json with the given status.
+func JSON(c Context, status int, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		c.Write(http.StatusInternalServerError, textContentType, []byte(err.Error()))
+		return
+	}
+
+	c.Write(status, jsonContentType, b)
+}
+
+// AbortWithJSON writes v in json with the given status and aborts the pending handlers.
+func AbortWithJSON(c Context, status int, v interface{}) {
+	JSON(c, status, v)
+	c.Abort()
+}
+
+func acceptLanguage(c Context) string {
+	tags, _, err := language.ParseAcceptLanguage(c.Request().Header.Get("Accept-Language"))
+	if err != nil || len(tags) == 0 {
+		return ""
+	}
+
+	return tags[0].String()
+}
+
+func newRequestID() string {
+	b := make([]byte, requestIDLen)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+
+	return hex.EncodeToString(b)
+}
+
+func render(c Context, status int, resp utils.Response) {
+	if v, ok := c.Get(RequestIDKey); ok {
+		resp.RequestID, _ = v.(string)
+	}
+
+	JSON(c, status, resp)
+}
+
+func translate(c Context, cerr *CodeError) string {
+	if len(cerr.Key) == 0 {
+		return cerr.Msg
+	}
+
+	translatorLock.RLock()
+	fn := translator
+	translatorLock.RUnlock()
+	if fn == nil {
+		return cerr.Msg
+	}
+
+	if msg, ok := fn(acceptLanguage(c), cerr.Key, cerr.Args...); ok {
+		return msg
+	}
+
+	return cerr.Msg
+}
@@ -0,0 +1,65 @@
+package core
+
+import "reflect"
+
+type (
+	// ParamSpec describes a parameter that a handler takes from the path, query or header.
+	ParamSpec struct {
+		In   string
+		Name string
+		// Type is integer or string.
+		Type     string
+		Required bool
+		Default  interface{}
+		Minimum  *int64
+	}
+
+	// Spec describes what a handler takes from the request, used to generate api documents.
+	Spec struct {
+		Params []ParamSpec
+		// Body is the type that the request body is decoded into, with BodyIDL json or xml.
+		Body    reflect.Type
+		BodyIDL string
+		// Bind is the type that is filled by struct tags, see binding.Bind.
+		Bind reflect.Type
+		// Security lists the names of the security schemes that the handler requires.
+		Security []string
+	}
+
+	// A Describer describes what it takes from the request.
+	Describer interface {
+		Describe() Spec
+	}
+
+	describedHandler struct {
+		HandlerFunc
+		spec Spec
+	}
+)
+
+// BearerAuth is the name of the security scheme of JWTAuth.
+const BearerAuth = "bearerAuth"
+
+// Describe returns a handler that runs fn and describes itself with spec.
+func Describe(fn HandlerFunc, spec Spec) Handler {
+	return describedHandler{
+		HandlerFunc: fn,
+		spec:        spec,
+	}
+}
+
+func (h describedHandler) Describe() Spec {
+	return h.spec
+}
+
+func describeParam(fn HandlerFunc, in, name, typ string, required bool, def interface{}) Handler {
+	return Describe(fn, Spec{
+		Params: []ParamSpec{{
+			In:       in,
+			Name:     name,
+			Type:     typ,
+			Required: required,
+			Default:  def,
+		}},
+	})
+}
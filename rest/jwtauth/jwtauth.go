@@ -0,0 +1,256 @@
+package jwtauth
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// ClaimsKey is the context key that the verified claims are stored with.
+const ClaimsKey = "claims"
+
+const (
+	defaultHeader = "Authorization"
+	bearerPrefix  = "Bearer "
+)
+
+var (
+	ErrTokenMissing      = errors.New("jwt token missing")
+	ErrTokenInvalid      = errors.New("jwt token invalid")
+	ErrInsufficientScope = errors.New("jwt token has insufficient scope")
+	ErrInsufficientRole  = errors.New("jwt token has insufficient role")
+)
+
+type (
+	// Grants is implemented by claims that carry scopes and roles.
+	Grants interface {
+		GetScopes() []string
+		GetRoles() []string
+	}
+
+	// Claims is the default claims type, custom claims can embed it to support scope and role checks.
+	Claims struct {
+		Scopes []string `json:"scopes,omitempty"`
+		Roles  []string `json:"roles,omitempty"`
+		jwt.StandardClaims
+	}
+
+	// ClaimsFunc creates an empty claims instance to unmarshal the token into.
+	ClaimsFunc func() jwt.Claims
+
+	AuthOption func(auth *Authenticator)
+
+	// An Authenticator extracts bearer tokens from requests and verifies them.
+	Authenticator struct {
+		keyFunc   jwt.Keyfunc
+		header    string
+		cookie    string
+		query     string
+		newClaims ClaimsFunc
+		methods   map[string]bool
+	}
+)
+
+// NewAuthenticator returns an Authenticator that verifies HMAC signed tokens with the given secret.
+// By default the token is read from the Authorization header with the Bearer scheme.
+func NewAuthenticator(secret string, opts ...AuthOption) *Authenticator {
+	auth := &Authenticator{
+		keyFunc: func(token *jwt.Token) (interface{}, error) {
+			return []byte(secret), nil
+		},
+		header: defaultHeader,
+		newClaims: func() jwt.Claims {
+			return new(Claims)
+		},
+		methods: map[string]bool{
+			jwt.SigningMethodHS256.Alg(): true,
+			jwt.SigningMethodHS384.Alg(): true,
+			jwt.SigningMethodHS512.Alg(): true,
+		},
+	}
+	for _, opt := range opts {
+		opt(auth)
+	}
+
+	return auth
+}
+
+// Authenticate extracts the token from r and returns the verified claims.
+func (auth *Authenticator) Authenticate(r *http.Request) (jwt.Claims, error) {
+	token := auth.extract(r)
+	if len(token) == 0 {
+		return nil, ErrTokenMissing
+	}
+
+	return auth.Parse(token)
+}
+
+// Parse verifies the given token and returns its claims.
+func (auth *Authenticator) Parse(token string) (jwt.Claims, error) {
+	claims := auth.newClaims()
+	tok, err := jwt.ParseWithClaims(token, claims, func(tok *jwt.Token) (interface{}, error) {
+		if !auth.methods[tok.Method.Alg()] {
+			return nil, ErrTokenInvalid
+		}
+		return auth.keyFunc(tok)
+	})
+	if err != nil || !tok.Valid {
+		return nil, ErrTokenInvalid
+	}
+
+	return claims, nil
+}
+
+func (auth *Authenticator) extract(r *http.Request) string {
+	if len(auth.header) > 0 {
+		if val := r.Header.Get(auth.header); len(val) > 0 {
+			if auth.header != defaultHeader {
+				return val
+			}
+			if len(val) > len(bearerPrefix) && strings.EqualFold(val[:len(bearerPrefix)], bearerPrefix) {
+				return strings.TrimSpace(val[len(bearerPrefix):])
+			}
+		}
+	}
+
+	if len(auth.cookie) > 0 {
+		if cookie, err := r.Cookie(auth.cookie); err == nil && len(cookie.Value) > 0 {
+			return cookie.Value
+		}
+	}
+
+	if len(auth.query) > 0 {
+		return r.URL.Query().Get(auth.query)
+	}
+
+	return ""
+}
+
+// RequireScopes returns ErrInsufficientScope if claims don't grant all of the given scopes.
+// ErrTokenMissing is returned if the request isn't authenticated.
+func RequireScopes(claims jwt.Claims, scopes ...string) error {
+	if claims == nil {
+		return ErrTokenMissing
+	}
+
+	grants, ok := claims.(Grants)
+	if !ok || !containsAll(grants.GetScopes(), scopes) {
+		return ErrInsufficientScope
+	}
+
+	return nil
+}
+
+// RequireRoles returns ErrInsufficientRole if claims don't have any of the given roles.
+// ErrTokenMissing is returned if the request isn't authenticated.
+func RequireRoles(claims jwt.Claims, roles ...string) error {
+	if claims == nil {
+		return ErrTokenMissing
+	} else if len(roles) == 0 {
+		return nil
+	}
+
+	grants, ok := claims.(Grants)
+	if !ok {
+		return ErrInsufficientRole
+	}
+
+	for _, role := range roles {
+		if contains(grants.GetRoles(), role) {
+			return nil
+		}
+	}
+
+	return ErrInsufficientRole
+}
+
+// StatusCode returns the http status code for the given authentication error.
+func StatusCode(err error) int {
+	switch err {
+	case ErrInsufficientScope, ErrInsufficientRole:
+		return http.StatusForbidden
+	default:
+		return http.StatusUnauthorized
+	}
+}
+
+func (c *Claims) GetSubject() string {
+	return c.Subject
+}
+
+func (c *Claims) GetScopes() []string {
+	return c.Scopes
+}
+
+func (c *Claims) GetRoles() []string {
+	return c.Roles
+}
+
+// WithHeader reads the token from the given header, the Authorization header requires the Bearer scheme.
+// An empty name disables reading from headers.
+func WithHeader(name string) AuthOption {
+	return func(auth *Authenticator) {
+		auth.header = name
+	}
+}
+
+// WithCookie reads the token from the given cookie if the header doesn't carry one.
+func WithCookie(name string) AuthOption {
+	return func(auth *Authenticator) {
+		auth.cookie = name
+	}
+}
+
+// WithQuery reads the token from the given query parameter if neither header nor cookie carry one.
+func WithQuery(name string) AuthOption {
+	return func(auth *Authenticator) {
+		auth.query = name
+	}
+}
+
+// WithClaims customizes the claims type that tokens are unmarshalled into.
+func WithClaims(fn ClaimsFunc) AuthOption {
+	return func(auth *Authenticator) {
+		auth.newClaims = fn
+	}
+}
+
+// WithKeyFunc customizes how the verification key is looked up, like for RSA or rotated keys.
+// The accepted signing methods should be set with WithSigningMethods accordingly.
+func WithKeyFunc(fn jwt.Keyfunc) AuthOption {
+	return func(auth *Authenticator) {
+		auth.keyFunc = fn
+	}
+}
+
+// WithSigningMethods restricts the accepted signing algorithms.
+func WithSigningMethods(methods ...jwt.SigningMethod) AuthOption {
+	return func(auth *Authenticator) {
+		auth.methods = make(map[string]bool)
+		for _, method := range methods {
+			auth.methods[method.Alg()] = true
+		}
+	}
+}
+
+func contains(items []string, item string) bool {
+	for _, each := range items {
+		if each == item {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsAll(items, required []string) bool {
+	for _, each := range required {
+		if !contains(items, each) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,85 @@
+package jwtauth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+)
+
+const secret = "any-secret"
+
+func newToken(t *testing.T, secret string, claims *Claims) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	assert.Nil(t, err)
+	return token
+}
+
+func TestAuthenticateHeader(t *testing.T) {
+	token := newToken(t, secret, &Claims{
+		Scopes: []string{"read"},
+		StandardClaims: jwt.StandardClaims{
+			Subject:   "kevin",
+			ExpiresAt: time.Now().Add(time.Hour).Unix(),
+		},
+	})
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+
+	claims, err := NewAuthenticator(secret).Authenticate(r)
+	assert.Nil(t, err)
+	assert.Equal(t, "kevin", claims.(*Claims).Subject)
+	assert.Equal(t, []string{"read"}, claims.(*Claims).Scopes)
+}
+
+func TestAuthenticateCookieAndQuery(t *testing.T) {
+	token := newToken(t, secret, &Claims{})
+	auth := NewAuthenticator(secret, WithCookie("token"), WithQuery("token"))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(&http.Cookie{Name: "token", Value: token})
+	_, err := auth.Authenticate(r)
+	assert.Nil(t, err)
+
+	r = httptest.NewRequest(http.MethodGet, "/?token="+token, nil)
+	_, err = auth.Authenticate(r)
+	assert.Nil(t, err)
+}
+
+func TestAuthenticateFailures(t *testing.T) {
+	auth := NewAuthenticator(secret)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	_, err := auth.Authenticate(r)
+	assert.Equal(t, ErrTokenMissing, err)
+
+	r.Header.Set("Authorization", "Bearer "+newToken(t, "other", &Claims{}))
+	_, err = auth.Authenticate(r)
+	assert.Equal(t, ErrTokenInvalid, err)
+
+	r.Header.Set("Authorization", "Bearer "+newToken(t, secret, &Claims{
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: time.Now().Add(-time.Minute).Unix(),
+		},
+	}))
+	_, err = auth.Authenticate(r)
+	assert.Equal(t, ErrTokenInvalid, err)
+	assert.Equal(t, http.StatusUnauthorized, StatusCode(err))
+}
+
+func TestRequireScopesAndRoles(t *testing.T) {
+	claims := &Claims{
+		Scopes: []string{"read", "write"},
+		Roles:  []string{"admin"},
+	}
+
+	assert.Nil(t, RequireScopes(claims, "read", "write"))
+	assert.Equal(t, ErrInsufficientScope, RequireScopes(claims, "read", "delete"))
+	assert.Nil(t, RequireRoles(claims, "user", "admin"))
+	assert.Equal(t, ErrInsufficientRole, RequireRoles(claims, "user"))
+	assert.Equal(t, http.StatusForbidden, StatusCode(ErrInsufficientRole))
+	assert.Equal(t, ErrTokenMissing, RequireScopes(nil, "read"))
+}
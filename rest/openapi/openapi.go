@@ -0,0 +1,301 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/tx991020/utils/rest/binding"
+	"github.com/tx991020/utils/rest/core"
+)
+
+const version = "3.0.3"
+
+var (
+	ginParamRegex  = regexp.MustCompile(`[:*]([^/]+)`)
+	irisParamRegex = regexp.MustCompile(`\{([^/:}]+)(:[^/}]*)?\}`)
+)
+
+type (
+	Info struct {
+		Title       string `json:"title"`
+		Description string `json:"description,omitempty"`
+		Version     string `json:"version"`
+	}
+
+	Parameter struct {
+		Name     string  `json:"name"`
+		In       string  `json:"in"`
+		Required bool    `json:"required,omitempty"`
+		Schema   *Schema `json:"schema"`
+	}
+
+	MediaType struct {
+		Schema *Schema `json:"schema"`
+	}
+
+	RequestBody struct {
+		Required bool                  `json:"required,omitempty"`
+		Content  map[string]*MediaType `json:"content"`
+	}
+
+	Response struct {
+		Description string                `json:"description"`
+		Content     map[string]*MediaType `json:"content,omitempty"`
+	}
+
+	SecurityScheme struct {
+		Type         string `json:"type"`
+		Scheme       string `json:"scheme,omitempty"`
+		BearerFormat string `json:"bearerFormat,omitempty"`
+	}
+
+	// An Operation describes a single api on a path.
+	Operation struct {
+		Summary     string                `json:"summary,omitempty"`
+		Description string                `json:"description,omitempty"`
+		OperationID string                `json:"operationId,omitempty"`
+		Tags        []string              `json:"tags,omitempty"`
+		Parameters  []*Parameter          `json:"parameters,omitempty"`
+		RequestBody *RequestBody          `json:"requestBody,omitempty"`
+		Responses   map[string]*Response  `json:"responses"`
+		Security    []map[string][]string `json:"security,omitempty"`
+
+		doc *Document
+	}
+
+	Components struct {
+		Schemas         map[string]*Schema         `json:"schemas,omitempty"`
+		SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+	}
+
+	// A Document is an OpenAPI 3 document that is built while registering routes.
+	Document struct {
+		OpenAPI    string                           `json:"openapi"`
+		Info       Info                             `json:"info"`
+		Paths      map[string]map[string]*Operation `json:"paths"`
+		Components Components                       `json:"components"`
+
+		lock sync.RWMutex
+	}
+)
+
+// NewDocument returns an empty Document with the given title and version.
+func NewDocument(title, ver string) *Document {
+	return &Document{
+		OpenAPI: version,
+		Info: Info{
+			Title:   title,
+			Version: ver,
+		},
+		Paths: make(map[string]map[string]*Operation),
+		Components: Components{
+			Schemas: make(map[string]*Schema),
+		},
+	}
+}
+
+// Add records the operation of method on path from the specs of the given handlers,
+// handlers that don't implement core.Describer are skipped.
+// Paths in gin (/users/:id) and iris (/users/{id:int}) styles are converted.
+func (d *Document) Add(method, path string, handlers ...core.Handler) *Operation {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	op := &Operation{
+		Responses: map[string]*Response{
+			strconv.Itoa(http.StatusOK): {
+				Description: http.StatusText(http.StatusOK),
+				Content:     jsonContent(envelopeSchema(nil)),
+			},
+		},
+		doc: d,
+	}
+	for _, h := range handlers {
+		if describer, ok := h.(core.Describer); ok {
+			d.addSpec(op, describer.Describe())
+		}
+	}
+
+	path = NormalizePath(path)
+	for _, param := range op.Parameters {
+		if param.In == binding.InPath {
+			param.Required = true
+		}
+	}
+
+	item, ok := d.Paths[path]
+	if !ok {
+		item = make(map[string]*Operation)
+		d.Paths[path] = item
+	}
+	item[strings.ToLower(method)] = op
+
+	return op
+}
+
+// ServeHTTP serves the document in json.
+func (d *Document) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b, err := d.MarshalJSON()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(b)
+}
+
+// MarshalJSON marshals the document safely against concurrent route registering.
+func (d *Document) MarshalJSON() ([]byte, error) {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	type document Document
+	return json.Marshal((*document)(d))
+}
+
+func (d *Document) addSpec(op *Operation, spec core.Spec) {
+	for _, param := range spec.Params {
+		schema := &Schema{
+			Type:    param.Type,
+			Default: param.Default,
+		}
+		if param.Type == "integer" {
+			schema.Format = "int64"
+		}
+		if param.Minimum != nil {
+			minimum := float64(*param.Minimum)
+			schema.Minimum = &minimum
+		}
+		op.addParameter(&Parameter{
+			Name:     param.Name,
+			In:       param.In,
+			Required: param.Required,
+			Schema:   schema,
+		})
+	}
+
+	if spec.Body != nil {
+		contentType := "application/json"
+		if spec.BodyIDL == "xml" {
+			contentType = "application/xml"
+		}
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content: map[string]*MediaType{
+				contentType: {Schema: d.schemaOf(spec.Body)},
+			},
+		}
+	}
+
+	if spec.Bind != nil {
+		params, body := d.bindSchemas(spec.Bind)
+		for _, param := range params {
+			op.addParameter(param)
+		}
+		if body != nil {
+			op.RequestBody = &RequestBody{
+				Content: map[string]*MediaType{
+					"application/json": {Schema: body},
+					"application/xml":  {Schema: body},
+				},
+			}
+		}
+	}
+
+	for _, name := range spec.Security {
+		d.addSecurity(op, name)
+	}
+}
+
+func (d *Document) addSecurity(op *Operation, name string) {
+	for _, each := range op.Security {
+		if _, ok := each[name]; ok {
+			return
+		}
+	}
+
+	op.Security = append(op.Security, map[string][]string{name: {}})
+	if name != core.BearerAuth {
+		return
+	}
+
+	if d.Components.SecuritySchemes == nil {
+		d.Components.SecuritySchemes = make(map[string]*SecurityScheme)
+	}
+	d.Components.SecuritySchemes[name] = &SecurityScheme{
+		Type:         "http",
+		Scheme:       "bearer",
+		BearerFormat: "JWT",
+	}
+}
+
+// Summarize sets the summary and the tags of the operation.
+func (op *Operation) Summarize(summary string, tags ...string) *Operation {
+	op.doc.lock.Lock()
+	defer op.doc.lock.Unlock()
+
+	op.Summary = summary
+	op.Tags = tags
+	return op
+}
+
+// WithResponse documents the response of the given status, with its envelope data of the type of data.
+func (op *Operation) WithResponse(status int, description string, data interface{}) *Operation {
+	op.doc.lock.Lock()
+	defer op.doc.lock.Unlock()
+
+	var schema *Schema
+	if data != nil {
+		schema = op.doc.schemaOf(typeOf(data))
+	}
+	op.Responses[strconv.Itoa(status)] = &Response{
+		Description: description,
+		Content:     jsonContent(envelopeSchema(schema)),
+	}
+
+	return op
+}
+
+func (op *Operation) addParameter(param *Parameter) {
+	for i, each := range op.Parameters {
+		if each.In == param.In && each.Name == param.Name {
+			op.Parameters[i] = param
+			return
+		}
+	}
+
+	op.Parameters = append(op.Parameters, param)
+}
+
+// NormalizePath converts the gin and iris path parameters into the OpenAPI style.
+func NormalizePath(path string) string {
+	path = irisParamRegex.ReplaceAllString(path, "{$1}")
+	return ginParamRegex.ReplaceAllString(path, "{$1}")
+}
+
+func envelopeSchema(data *Schema) *Schema {
+	props := map[string]*Schema{
+		"code": {Type: "integer"},
+		"msg":  {Type: "string"},
+	}
+	if data != nil {
+		props["data"] = data
+	}
+
+	return &Schema{
+		Type:       "object",
+		Properties: props,
+		Required:   []string{"code", "msg"},
+	}
+}
+
+func jsonContent(schema *Schema) map[string]*MediaType {
+	return map[string]*MediaType{
+		"application/json": {Schema: schema},
+	}
+}
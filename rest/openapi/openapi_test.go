@@ -0,0 +1,95 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tx991020/utils/rest/core"
+	"github.com/tx991020/utils/rest/jwtauth"
+)
+
+type (
+	User struct {
+		ID      int64    `json:"id"`
+		Name    string   `json:"name" validate:"required,max=20"`
+		Friends []*User  `json:"friends,omitempty"`
+		Tags    []string `json:"tags"`
+	}
+
+	listRequest struct {
+		Page  int    `in:"query" name:"page" default:"1" validate:"min=1,max=100"`
+		Order string `in:"query" name:"order" validate:"oneof=asc desc"`
+		Group int64  `in:"path" name:"group"`
+	}
+)
+
+func TestNormalizePath(t *testing.T) {
+	assert.Equal(t, "/users/{id}/files/{path}", NormalizePath("/users/:id/files/*path"))
+	assert.Equal(t, "/users/{id}/{name}", NormalizePath("/users/{id:int}/{name}"))
+}
+
+func TestDocumentAdd(t *testing.T) {
+	doc := NewDocument("users", "1.0")
+	doc.Add(http.MethodGet, "/users/:id", core.PathInt("id", true, ""),
+		core.QueryInt("size", false, "", 10), core.JWTAuth(jwtauth.NewAuthenticator("secret"))).
+		Summarize("get user", "users").
+		WithResponse(http.StatusOK, "the user", User{})
+	doc.Add(http.MethodPost, "/users", core.RequestBodyObject(reflect.TypeOf(User{}), "json"))
+	doc.Add(http.MethodGet, "/groups/:group/users", core.RequestBind(reflect.TypeOf(listRequest{})))
+
+	w := httptest.NewRecorder()
+	doc.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	var m map[string]interface{}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &m))
+	assert.Equal(t, "3.0.3", m["openapi"])
+
+	get := doc.Paths["/users/{id}"]["get"]
+	assert.Equal(t, "get user", get.Summary)
+	assert.Equal(t, 2, len(get.Parameters))
+	assert.Equal(t, "id", get.Parameters[0].Name)
+	assert.True(t, get.Parameters[0].Required)
+	assert.Equal(t, "integer", get.Parameters[0].Schema.Type)
+	assert.Equal(t, int64(10), get.Parameters[1].Schema.Default)
+	assert.Equal(t, []map[string][]string{{core.BearerAuth: {}}}, get.Security)
+	assert.Equal(t, "#/components/schemas/openapi.User",
+		get.Responses["200"].Content["application/json"].Schema.Properties["data"].Ref)
+
+	user := doc.Components.Schemas["openapi.User"]
+	assert.Equal(t, []string{"name"}, user.Required)
+	assert.Equal(t, 20, *user.Properties["name"].MaxLength)
+	assert.Equal(t, "#/components/schemas/openapi.User", user.Properties["friends"].Items.Ref)
+
+	post := doc.Paths["/users"]["post"]
+	assert.Equal(t, "#/components/schemas/openapi.User",
+		post.RequestBody.Content["application/json"].Schema.Ref)
+
+	list := doc.Paths["/groups/{group}/users"]["get"]
+	assert.Nil(t, list.RequestBody)
+	assert.Equal(t, 3, len(list.Parameters))
+	assert.Equal(t, "1", list.Parameters[0].Schema.Default)
+	assert.Equal(t, float64(100), *list.Parameters[0].Schema.Maximum)
+	assert.Equal(t, []interface{}{"asc", "desc"}, list.Parameters[1].Schema.Enum)
+	assert.True(t, list.Parameters[2].Required)
+}
+
+func TestDocumentServeWhileAdding(t *testing.T) {
+	doc := NewDocument("users", "1.0")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			doc.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+		}
+	}()
+
+	for i := 0; i < 100; i++ {
+		doc.Add(http.MethodGet, "/users/:id", core.PathInt("id", true, "")).
+			Summarize("get user", "users").
+			WithResponse(http.StatusOK, "the user", User{})
+	}
+	<-done
+}
@@ -0,0 +1,240 @@
+package openapi
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tx991020/utils/rest/binding"
+)
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+// A Schema is the OpenAPI schema object, only the commonly used fields are supported.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Default              interface{}        `json:"default,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+}
+
+// bindSchemas splits the struct t filled by binding.Bind into the parameters and the body schema.
+func (d *Document) bindSchemas(t reflect.Type) ([]*Parameter, *Schema) {
+	var params []*Parameter
+	body := &Schema{
+		Type:       "object",
+		Properties: make(map[string]*Schema),
+	}
+	d.walkBind(indirect(t), &params, body)
+
+	if len(body.Properties) == 0 {
+		return params, nil
+	}
+
+	return params, body
+}
+
+func (d *Document) walkBind(t reflect.Type, params *[]*Parameter, body *Schema) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		in := field.Tag.Get("in")
+		if field.Anonymous && len(in) == 0 && indirect(field.Type).Kind() == reflect.Struct {
+			d.walkBind(indirect(field.Type), params, body)
+			continue
+		}
+		if len(field.PkgPath) > 0 {
+			continue
+		}
+
+		if len(in) == 0 || in == binding.InBody {
+			d.addProperty(body, field)
+			continue
+		}
+
+		name := field.Tag.Get("name")
+		if len(name) == 0 {
+			name = field.Name
+		}
+		schema := d.schemaOf(field.Type)
+		if def, ok := field.Tag.Lookup("default"); ok {
+			schema.Default = def
+		}
+		required := applyRules(schema, field.Tag.Get("validate"))
+		*params = append(*params, &Parameter{
+			Name:     name,
+			In:       in,
+			Required: required,
+			Schema:   schema,
+		})
+	}
+}
+
+func (d *Document) addProperty(schema *Schema, field reflect.StructField) {
+	name := field.Name
+	if tag, ok := field.Tag.Lookup("json"); ok {
+		tag = strings.Split(tag, ",")[0]
+		if tag == "-" {
+			return
+		}
+		if len(tag) > 0 {
+			name = tag
+		}
+	}
+
+	prop := d.schemaOf(field.Type)
+	schema.Properties[name] = prop
+	if applyRules(prop, field.Tag.Get("validate")) {
+		schema.Required = append(schema.Required, name)
+	}
+}
+
+// schemaOf returns the schema of t, named structs are put into the components and referenced.
+func (d *Document) schemaOf(t reflect.Type) *Schema {
+	t = indirect(t)
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case durationType:
+		return &Schema{Type: "string", Format: "duration"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: d.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: d.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if len(t.Name()) == 0 {
+			return d.structSchema(t)
+		}
+		name := schemaName(t)
+		if _, ok := d.Components.Schemas[name]; !ok {
+			// placeholder against recursive types
+			d.Components.Schemas[name] = &Schema{Type: "object"}
+			d.Components.Schemas[name] = d.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	default:
+		return &Schema{}
+	}
+}
+
+func (d *Document) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{
+		Type:       "object",
+		Properties: make(map[string]*Schema),
+	}
+	d.walkStruct(t, schema)
+	return schema
+}
+
+func (d *Document) walkStruct(t reflect.Type, schema *Schema) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && indirect(field.Type).Kind() == reflect.Struct && len(field.Tag.Get("json")) == 0 {
+			d.walkStruct(indirect(field.Type), schema)
+			continue
+		}
+		if len(field.PkgPath) > 0 {
+			continue
+		}
+		d.addProperty(schema, field)
+	}
+}
+
+// applyRules applies the validate rules of binding to schema, returns true if required.
+func applyRules(schema *Schema, rules string) bool {
+	var required bool
+	for _, rule := range strings.Split(rules, ",") {
+		rule = strings.TrimSpace(rule)
+		var name, arg string
+		if i := strings.IndexByte(rule, '='); i >= 0 {
+			name, arg = rule[:i], rule[i+1:]
+		} else {
+			name = rule
+		}
+
+		switch name {
+		case "required":
+			required = true
+		case "min", "max":
+			n, err := strconv.ParseFloat(arg, 64)
+			if err != nil {
+				continue
+			}
+			if schema.Type == "string" {
+				size := int(n)
+				if name == "min" {
+					schema.MinLength = &size
+				} else {
+					schema.MaxLength = &size
+				}
+			} else if name == "min" {
+				schema.Minimum = &n
+			} else {
+				schema.Maximum = &n
+			}
+		case "oneof":
+			for _, option := range strings.Fields(arg) {
+				schema.Enum = append(schema.Enum, option)
+			}
+		}
+	}
+
+	return required
+}
+
+func indirect(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+func schemaName(t reflect.Type) string {
+	pkg := t.PkgPath()
+	if i := strings.LastIndexByte(pkg, '/'); i >= 0 {
+		pkg = pkg[i+1:]
+	}
+	if len(pkg) == 0 {
+		return t.Name()
+	}
+
+	return pkg + "." + t.Name()
+}
+
+func typeOf(v interface{}) reflect.Type {
+	if t, ok := v.(reflect.Type); ok {
+		return t
+	}
+
+	return reflect.TypeOf(v)
+}
@@ -0,0 +1,410 @@
+package ratelimit
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/tal-tech/go-zero/core/logx"
+	"github.com/tal-tech/go-zero/core/stores/redis"
+	"github.com/tx991020/utils/collection"
+	"github.com/tx991020/utils/limit"
+	"github.com/tx991020/utils/rest/core"
+)
+
+const (
+	HeaderLimit      = "X-RateLimit-Limit"
+	HeaderRemaining  = "X-RateLimit-Remaining"
+	HeaderReset      = "X-RateLimit-Reset"
+	HeaderRetryAfter = "Retry-After"
+
+	KeyByIP      = "ip"
+	KeyBySubject = "subject"
+	KeyByRoute   = "route"
+	// KeyByHeader is followed by the header name, like header:X-Api-Key.
+	KeyByHeader = "header:"
+
+	tokenLimiterExpire = time.Minute * 10
+	// the keys of the default and the route quotas are in separate namespaces,
+	// for a key value not to be charged against the quota of another route
+	defaultKeyPrefix = "default:"
+	routeKeyPrefix   = "route:"
+)
+
+type (
+	// Quota is the result of taking a token for a key.
+	Quota struct {
+		Allowed bool
+		Limit   int
+		// Remaining is negative if the limiter doesn't know it.
+		Remaining int
+		// Reset is zero if the limiter doesn't know it.
+		Reset time.Duration
+	}
+
+	// A Limiter takes a token for the given key.
+	Limiter interface {
+		Take(key string) (Quota, error)
+	}
+
+	// KeyFunc returns the key to limit the request on.
+	KeyFunc func(c core.Context) string
+
+	// RouteConf is the quota of a route, Path is the route pattern as registered, like /users/:id.
+	RouteConf struct {
+		Method string `json:"method"`
+		Path   string `json:"path"`
+		Period int    `json:"period"`
+		Quota  int    `json:"quota"`
+	}
+
+	// Conf configures the rate limit middleware.
+	Conf struct {
+		KeyPrefix string `json:"keyPrefix"`
+		// KeyBy is one of ip, subject, route or header:<name>, defaults to ip.
+		KeyBy string `json:"keyBy"`
+		// Period in seconds and Quota are the default quota, zero Quota means no default limit.
+		Period int         `json:"period"`
+		Quota  int         `json:"quota"`
+		Routes []RouteConf `json:"routes"`
+		// TrustedProxies are the ips or CIDRs of the proxies whose X-Forwarded-For and X-Real-IP
+		// are respected on keying by the client ip, the headers are ignored if empty.
+		TrustedProxies []string `json:"trustedProxies"`
+	}
+
+	periodLimiter struct {
+		limit *limit.PeriodLimit
+	}
+
+	tokenLimiter struct {
+		rate     int
+		burst    int
+		store    *redis.Redis
+		prefix   string
+		limiters *collection.Cache
+	}
+
+	routeLimiter struct {
+		routes   map[string]Limiter
+		fallback Limiter
+	}
+
+	unlimited struct{}
+)
+
+// NewPeriodLimiter returns a Limiter that allows quota requests per period seconds, see limit.PeriodLimit.
+func NewPeriodLimiter(period, quota int, store *redis.Redis, keyPrefix string, opts ...limit.LimitOption) Limiter {
+	return periodLimiter{
+		limit: limit.NewPeriodLimit(period, quota, store, keyPrefix, opts...),
+	}
+}
+
+func (l periodLimiter) Take(key string) (Quota, error) {
+	state, err := l.limit.TakeState(key)
+	if err != nil {
+		return Quota{}, err
+	}
+
+	return Quota{
+		Allowed:   state.Code != limit.OverQuota,
+		Limit:     state.Quota,
+		Remaining: state.Remaining,
+		Reset:     state.Reset,
+	}, nil
+}
+
+// NewTokenLimiter returns a Limiter that allows rate requests per second with bursts of burst,
+// with a limit.TokenLimiter per key.
+func NewTokenLimiter(rate, burst int, store *redis.Redis, keyPrefix string) (Limiter, error) {
+	if rate <= 0 {
+		return nil, fmt.Errorf("invalid rate limit rate: %d", rate)
+	}
+
+	limiters, err := collection.NewCache(tokenLimiterExpire)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tokenLimiter{
+		rate:     rate,
+		burst:    burst,
+		store:    store,
+		prefix:   keyPrefix,
+		limiters: limiters,
+	}, nil
+}
+
+func (l *tokenLimiter) Take(key string) (Quota, error) {
+	val, err := l.limiters.Take(key, func() (interface{}, error) {
+		return limit.NewTokenLimiter(l.rate, l.burst, l.store, l.prefix+key), nil
+	})
+	if err != nil {
+		return Quota{}, err
+	}
+
+	allowed := val.(*limit.TokenLimiter).Allow()
+	quota := Quota{
+		Allowed:   allowed,
+		Limit:     l.rate,
+		Remaining: -1,
+	}
+	if !allowed {
+		quota.Reset = time.Second / time.Duration(l.rate)
+	}
+
+	return quota, nil
+}
+
+// limiterOf returns the limiter of the route of c, the one of the route with any method
+// if the method has none, or the default one.
+func (l routeLimiter) limiterOf(c core.Context) Limiter {
+	if limiter, ok := l.routes[routeName(c.Request().Method, c.Route())]; ok {
+		return limiter
+	}
+	if limiter, ok := l.routes[routeName("", c.Route())]; ok {
+		return limiter
+	}
+	if l.fallback == nil {
+		return unlimited{}
+	}
+
+	return l.fallback
+}
+
+func (l unlimited) Take(key string) (Quota, error) {
+	return Quota{Allowed: true, Remaining: -1}, nil
+}
+
+// Middleware returns a core handler that limits the requests by the keys from keyFn,
+// the X-RateLimit headers are set, 429 is responded with Retry-After if over quota.
+// Requests are let through if the limiter fails.
+func Middleware(limiter Limiter, keyFn KeyFunc) core.Handler {
+	return middleware(func(c core.Context) Limiter {
+		return limiter
+	}, keyFn)
+}
+
+// middleware limits the requests by the keys from keyFn with the limiter that limiterFn chooses.
+func middleware(limiterFn func(c core.Context) Limiter, keyFn KeyFunc) core.Handler {
+	return core.HandlerFunc(func(c core.Context) {
+		key := keyFn(c)
+		quota, err := limiterFn(c).Take(key)
+		if err != nil {
+			logx.Errorf("rate limit on key %s failed: %v", key, err)
+			return
+		}
+
+		header := c.Header()
+		if quota.Limit > 0 {
+			header.Set(HeaderLimit, strconv.Itoa(quota.Limit))
+		}
+		if quota.Remaining >= 0 {
+			header.Set(HeaderRemaining, strconv.Itoa(quota.Remaining))
+		}
+		reset := seconds(quota.Reset)
+		if quota.Reset > 0 {
+			header.Set(HeaderReset, reset)
+		}
+
+		if !quota.Allowed {
+			if quota.Reset > 0 {
+				header.Set(HeaderRetryAfter, reset)
+			}
+			core.Error(c, core.NewRateLimitedError(http.StatusText(http.StatusTooManyRequests)))
+		}
+	})
+}
+
+// NewMiddleware returns a rate limit middleware with the default and per route period quotas of conf.
+func NewMiddleware(conf Conf, store *redis.Redis) (core.Handler, error) {
+	keyFn, err := ParseKeyFunc(conf.KeyBy, conf.TrustedProxies...)
+	if err != nil {
+		return nil, err
+	}
+
+	limiter := routeLimiter{
+		routes: make(map[string]Limiter),
+	}
+	if conf.Quota > 0 {
+		limiter.fallback = NewPeriodLimiter(conf.Period, conf.Quota, store, conf.KeyPrefix+defaultKeyPrefix)
+	}
+	for _, route := range conf.Routes {
+		name := routeName(strings.ToUpper(route.Method), route.Path)
+		limiter.routes[name] = NewPeriodLimiter(route.Period, route.Quota, store,
+			conf.KeyPrefix+routeKeyPrefix+name+"|")
+	}
+
+	// the route is used to choose the limiter, not parsed from the key, which might contain anything
+	return middleware(limiter.limiterOf, keyFn), nil
+}
+
+// MustNewMiddleware is like NewMiddleware, but panics on error.
+func MustNewMiddleware(conf Conf, store *redis.Redis) core.Handler {
+	h, err := NewMiddleware(conf, store)
+	if err != nil {
+		logx.Must(err)
+	}
+
+	return h
+}
+
+// ParseKeyFunc returns the KeyFunc of ip, subject, route or header:<name>.
+// The forwarding headers are respected on keying by the client ip only if sent by trustedProxies,
+// see TrustedClientIP.
+func ParseKeyFunc(keyBy string, trustedProxies ...string) (KeyFunc, error) {
+	var ipFn KeyFunc = ClientIP
+	if len(trustedProxies) > 0 {
+		fn, err := TrustedClientIP(trustedProxies...)
+		if err != nil {
+			return nil, err
+		}
+		ipFn = fn
+	}
+
+	switch {
+	case len(keyBy) == 0 || keyBy == KeyByIP:
+		return ipFn, nil
+	case keyBy == KeyBySubject:
+		return subjectOr(ipFn), nil
+	case keyBy == KeyByRoute:
+		return Route, nil
+	case strings.HasPrefix(keyBy, KeyByHeader) && len(keyBy) > len(KeyByHeader):
+		return headerOr(keyBy[len(KeyByHeader):], ipFn), nil
+	default:
+		return nil, fmt.Errorf("unknown rate limit key: %s", keyBy)
+	}
+}
+
+// ClientIP keys by the ip of the peer, the forwarding headers are ignored because any client can send them,
+// use TrustedClientIP behind proxies.
+func ClientIP(c core.Context) string {
+	return remoteIP(c.Request())
+}
+
+// TrustedClientIP returns a KeyFunc that keys by the client ip, X-Forwarded-For and X-Real-IP are respected
+// only on the requests from the proxies, which are ips or CIDRs. The client ip is the rightmost one
+// in X-Forwarded-For that is not a trusted proxy, because the ones on the left can be forged.
+func TrustedClientIP(proxies ...string) (KeyFunc, error) {
+	var nets []*net.IPNet
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			if ip := net.ParseIP(proxy); ip != nil && ip.To4() != nil {
+				proxy += "/32"
+			} else {
+				proxy += "/128"
+			}
+		}
+
+		_, ipnet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy: %s", proxy)
+		}
+		nets = append(nets, ipnet)
+	}
+
+	trusted := func(addr string) bool {
+		ip := net.ParseIP(addr)
+		if ip == nil {
+			return false
+		}
+
+		for _, ipnet := range nets {
+			if ipnet.Contains(ip) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return func(c core.Context) string {
+		r := c.Request()
+		remote := remoteIP(r)
+		if !trusted(remote) {
+			return remote
+		}
+
+		if forwarded := r.Header.Get("X-Forwarded-For"); len(forwarded) > 0 {
+			ips := strings.Split(forwarded, ",")
+			for i := len(ips) - 1; i >= 0; i-- {
+				ip := strings.TrimSpace(ips[i])
+				if i == 0 || !trusted(ip) {
+					return ip
+				}
+			}
+		}
+		if ip := r.Header.Get("X-Real-IP"); len(ip) > 0 {
+			return ip
+		}
+
+		return remote
+	}, nil
+}
+
+// Header keys by the given header, falls back to the client ip if absent.
+func Header(name string) KeyFunc {
+	return headerOr(name, ClientIP)
+}
+
+// Subject keys by the subject of the jwt claims stored by core.JWTAuth, falls back to the client ip.
+func Subject(c core.Context) string {
+	return subjectOr(ClientIP)(c)
+}
+
+// Route keys by the method and the route, which limits the route as a whole.
+func Route(c core.Context) string {
+	return routeName(c.Request().Method, c.Route())
+}
+
+func routeName(method, route string) string {
+	if len(method) == 0 {
+		return route
+	}
+
+	return method + " " + route
+}
+
+func seconds(d time.Duration) string {
+	secs := int64((d + time.Second - 1) / time.Second)
+	return strconv.FormatInt(secs, 10)
+}
+
+func headerOr(name string, fallback KeyFunc) KeyFunc {
+	return func(c core.Context) string {
+		if val := c.Request().Header.Get(name); len(val) > 0 {
+			return val
+		}
+
+		return fallback(c)
+	}
+}
+
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+func subjectOr(fallback KeyFunc) KeyFunc {
+	return func(c core.Context) string {
+		switch claims := core.Claims(c).(type) {
+		case interface{ GetSubject() string }:
+			if sub := claims.GetSubject(); len(sub) > 0 {
+				return sub
+			}
+		case jwt.MapClaims:
+			if sub, ok := claims["sub"].(string); ok && len(sub) > 0 {
+				return sub
+			}
+		}
+
+		return fallback(c)
+	}
+}
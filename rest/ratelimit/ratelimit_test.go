@@ -0,0 +1,186 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alicebob/miniredis"
+	"github.com/stretchr/testify/assert"
+	"github.com/tal-tech/go-zero/core/logx"
+	"github.com/tal-tech/go-zero/core/stores/redis"
+	"github.com/tx991020/utils/rest/core"
+	"github.com/tx991020/utils/rest/xhttp"
+)
+
+func init() {
+	logx.Disable()
+}
+
+func serve(h http.Handler, path, ip string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, path, nil)
+	r.RemoteAddr = ip + ":1234"
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestMiddleware(t *testing.T) {
+	s, err := miniredis.Run()
+	assert.Nil(t, err)
+	defer s.Close()
+
+	store := redis.NewRedis(s.Addr(), redis.NodeType)
+	h := xhttp.Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+		xhttp.Wrap(Middleware(NewPeriodLimiter(60, 2, store, "rate:"), ClientIP)))
+
+	w := serve(h, "/", "1.1.1.1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", w.Header().Get(HeaderLimit))
+	assert.Equal(t, "1", w.Header().Get(HeaderRemaining))
+	assert.Equal(t, "60", w.Header().Get(HeaderReset))
+
+	w = serve(h, "/", "1.1.1.1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "0", w.Header().Get(HeaderRemaining))
+
+	w = serve(h, "/", "1.1.1.1")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "60", w.Header().Get(HeaderRetryAfter))
+	assert.JSONEq(t, `{"code":42900,"msg":"Too Many Requests"}`, w.Body.String())
+
+	w = serve(h, "/", "2.2.2.2")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestMiddlewareFailOpen(t *testing.T) {
+	s, err := miniredis.Run()
+	assert.Nil(t, err)
+	store := redis.NewRedis(s.Addr(), redis.NodeType)
+	s.Close()
+
+	h := xhttp.Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+		xhttp.Wrap(Middleware(NewPeriodLimiter(60, 1, store, "rate:"), ClientIP)))
+	assert.Equal(t, http.StatusOK, serve(h, "/", "1.1.1.1").Code)
+	assert.Equal(t, http.StatusOK, serve(h, "/", "1.1.1.1").Code)
+}
+
+func TestNewMiddlewareWithRoutes(t *testing.T) {
+	s, err := miniredis.Run()
+	assert.Nil(t, err)
+	defer s.Close()
+
+	m, err := NewMiddleware(Conf{
+		KeyPrefix: "rate:",
+		Period:    60,
+		Quota:     3,
+		Routes: []RouteConf{{
+			Method: http.MethodGet,
+			Path:   "/slow",
+			Period: 60,
+			Quota:  1,
+		}},
+	}, redis.NewRedis(s.Addr(), redis.NodeType))
+	assert.Nil(t, err)
+	h := xhttp.Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), xhttp.Wrap(m))
+
+	assert.Equal(t, http.StatusOK, serve(h, "/slow", "1.1.1.1").Code)
+	assert.Equal(t, http.StatusTooManyRequests, serve(h, "/slow", "1.1.1.1").Code)
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, serve(h, "/fast", "1.1.1.1").Code)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, serve(h, "/other", "1.1.1.1").Code)
+
+	_, err = NewMiddleware(Conf{KeyBy: "cookie"}, nil)
+	assert.NotNil(t, err)
+}
+
+func TestNewMiddlewareForgedRouteKey(t *testing.T) {
+	s, err := miniredis.Run()
+	assert.Nil(t, err)
+	defer s.Close()
+
+	m, err := NewMiddleware(Conf{
+		KeyPrefix: "rate:",
+		KeyBy:     "header:X-Api-Key",
+		Period:    60,
+		Quota:     3,
+		Routes: []RouteConf{{
+			Method: http.MethodGet,
+			Path:   "/slow",
+			Period: 60,
+			Quota:  1,
+		}},
+	}, redis.NewRedis(s.Addr(), redis.NodeType))
+	assert.Nil(t, err)
+	h := xhttp.Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), xhttp.Wrap(m))
+	serveKey := func(path, key string) int {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r.Header.Set("X-Api-Key", key)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	// the key looks like the one of the route, but it's charged against the default quota
+	assert.Equal(t, http.StatusOK, serveKey("/fast", "GET /slow|victim"))
+	assert.Equal(t, http.StatusOK, serveKey("/slow", "victim"))
+	assert.Equal(t, http.StatusTooManyRequests, serveKey("/slow", "victim"))
+}
+
+func TestKeyFuncs(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/users", nil)
+	r.RemoteAddr = "3.3.3.3:1234"
+	c := xhttp.NewContext(httptest.NewRecorder(), r)
+	assert.Equal(t, "3.3.3.3", ClientIP(c))
+	assert.Equal(t, "3.3.3.3", Subject(c))
+	assert.Equal(t, "GET /users", Route(c))
+
+	r.Header.Set("X-Api-Key", "key")
+	keyFn, err := ParseKeyFunc("header:X-Api-Key")
+	assert.Nil(t, err)
+	assert.Equal(t, "key", keyFn(c))
+}
+
+func TestClientIPIgnoresForwardingHeaders(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/users", nil)
+	r.RemoteAddr = "3.3.3.3:1234"
+	r.Header.Set("X-Forwarded-For", "1.1.1.1")
+	r.Header.Set("X-Real-IP", "2.2.2.2")
+	// the context is created on every call, for the changes of r to be seen
+	newContext := func() core.Context {
+		return xhttp.NewContext(httptest.NewRecorder(), r)
+	}
+	assert.Equal(t, "3.3.3.3", ClientIP(newContext()))
+
+	keyFn, err := TrustedClientIP("10.0.0.0/8", "192.168.1.1")
+	assert.Nil(t, err)
+	assert.Equal(t, "3.3.3.3", keyFn(newContext()), "not from a trusted proxy")
+
+	r.RemoteAddr = "10.0.0.1:1234"
+	r.Header.Set("X-Forwarded-For", "6.6.6.6, 1.1.1.1, 192.168.1.1")
+	assert.Equal(t, "1.1.1.1", keyFn(newContext()), "the forged ones on the left are skipped")
+	r.Header.Set("X-Forwarded-For", "192.168.1.1, 10.0.0.2")
+	assert.Equal(t, "192.168.1.1", keyFn(newContext()))
+	r.Header.Del("X-Forwarded-For")
+	assert.Equal(t, "2.2.2.2", keyFn(newContext()))
+	r.Header.Del("X-Real-IP")
+	assert.Equal(t, "10.0.0.1", keyFn(newContext()))
+
+	r.Header.Set("X-Forwarded-For", "1.1.1.1")
+	keyFn, err = ParseKeyFunc(KeyBySubject, "10.0.0.1")
+	assert.Nil(t, err)
+	assert.Equal(t, "1.1.1.1", keyFn(newContext()))
+
+	_, err = TrustedClientIP("10.0.0.0/33")
+	assert.NotNil(t, err)
+	_, err = ParseKeyFunc(KeyByIP, "proxy")
+	assert.NotNil(t, err)
+}
+
+func TestNewTokenLimiterInvalidRate(t *testing.T) {
+	_, err := NewTokenLimiter(0, 10, nil, "rate:")
+	assert.NotNil(t, err)
+	_, err = NewTokenLimiter(-1, 10, nil, "rate:")
+	assert.NotNil(t, err)
+}
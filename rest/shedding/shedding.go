@@ -0,0 +1,39 @@
+package shedding
+
+import (
+	"net/http"
+
+	"github.com/tal-tech/go-zero/core/logx"
+	"github.com/tx991020/utils/load"
+	"github.com/tx991020/utils/rest/core"
+)
+
+// Middleware returns a core handler that rejects the requests with 503 if shedder doesn't allow them,
+// like load.NewAdaptiveShedder or load.NewConcurrencyLimiter.
+// The requests responded with 503 or panicked by the pending handlers are counted as failed.
+func Middleware(shedder load.Shedder) core.Handler {
+	return core.HandlerFunc(func(c core.Context) {
+		promise, err := shedder.Allow()
+		if err != nil {
+			r := c.Request()
+			logx.Errorf("[http] dropped, %s - %s - %s", r.RequestURI, r.RemoteAddr, r.UserAgent())
+			core.Error(c, core.NewUnavailableError(err.Error()))
+			return
+		}
+
+		defer func() {
+			if p := recover(); p != nil {
+				promise.Fail()
+				panic(p)
+			}
+
+			if c.Status() == http.StatusServiceUnavailable {
+				promise.Fail()
+			} else {
+				promise.Pass()
+			}
+		}()
+
+		c.Next()
+	})
+}
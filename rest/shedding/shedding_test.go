@@ -0,0 +1,62 @@
+package shedding
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tal-tech/go-zero/core/logx"
+	"github.com/tx991020/utils/load"
+	"github.com/tx991020/utils/rest/xhttp"
+)
+
+func init() {
+	logx.Disable()
+}
+
+func TestMiddleware(t *testing.T) {
+	limiter := load.NewConcurrencyLimiter(load.WithLimits(1, 1, 1))
+	var inner int
+	var h http.Handler
+	h = xhttp.Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, 1, limiter.Inflight())
+		if inner == 0 {
+			inner++
+			w2 := httptest.NewRecorder()
+			h.ServeHTTP(w2, httptest.NewRequest(http.MethodGet, "/", nil))
+			assert.Equal(t, http.StatusServiceUnavailable, w2.Code)
+		}
+	}), xhttp.Wrap(Middleware(limiter)))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, inner)
+	assert.Equal(t, 0, limiter.Inflight())
+}
+
+func TestMiddleware_Fail(t *testing.T) {
+	limiter := load.NewConcurrencyLimiter(load.WithLimits(10, 1, 10), load.WithBackoff(0.5))
+	h := xhttp.Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}), xhttp.Wrap(Middleware(limiter)))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, 5, limiter.Limit())
+	assert.Equal(t, 0, limiter.Inflight())
+}
+
+func TestMiddleware_Panic(t *testing.T) {
+	limiter := load.NewConcurrencyLimiter()
+	h := xhttp.Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}), xhttp.Wrap(Middleware(limiter)))
+
+	assert.Panics(t, func() {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+	assert.Equal(t, 0, limiter.Inflight())
+}
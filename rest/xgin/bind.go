@@ -0,0 +1,19 @@
+package xgin
+
+import (
+	"reflect"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tx991020/utils/rest/core"
+)
+
+// GRequestBind fills a new instance of t from path, query, header, form and body by its struct tags,
+// and stores it with key "request", see binding.Bind.
+func GRequestBind(t reflect.Type) func(*gin.Context) {
+	return Wrap(core.RequestBind(t))
+}
+
+// Bind fills dst from the request of c, see binding.Bind.
+func Bind(c *gin.Context, dst interface{}) error {
+	return core.Bind(NewContext(c), dst)
+}
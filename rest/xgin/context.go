@@ -0,0 +1,69 @@
+package xgin
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tx991020/utils/rest/core"
+)
+
+type ginContext struct {
+	c *gin.Context
+}
+
+// Wrap converts the core handler into a gin handler.
+func Wrap(h core.Handler) func(*gin.Context) {
+	return func(c *gin.Context) {
+		h.Handle(NewContext(c))
+	}
+}
+
+// NewContext returns a core.Context backed by c.
+func NewContext(c *gin.Context) core.Context {
+	return ginContext{c: c}
+}
+
+func (gc ginContext) Request() *http.Request {
+	return gc.c.Request
+}
+
+func (gc ginContext) Param(name string) string {
+	return gc.c.Param(name)
+}
+
+func (gc ginContext) Route() string {
+	if route := gc.c.FullPath(); len(route) > 0 {
+		return route
+	}
+
+	return gc.c.Request.URL.Path
+}
+
+func (gc ginContext) Get(key string) (interface{}, bool) {
+	return gc.c.Get(key)
+}
+
+func (gc ginContext) Set(key string, value interface{}) {
+	gc.c.Set(key, value)
+}
+
+func (gc ginContext) Header() http.Header {
+	return gc.c.Writer.Header()
+}
+
+func (gc ginContext) Write(status int, contentType string, body []byte) {
+	gc.c.Data(status, contentType, body)
+}
+
+func (gc ginContext) Status() int {
+	return gc.c.Writer.Status()
+}
+
+// Next runs the pending handlers, gin continues with them on return anyway.
+func (gc ginContext) Next() {
+	gc.c.Next()
+}
+
+func (gc ginContext) Abort() {
+	gc.c.Abort()
+}
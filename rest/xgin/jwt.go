@@ -0,0 +1,28 @@
+package xgin
+
+import (
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+	"github.com/tx991020/utils/rest/core"
+	"github.com/tx991020/utils/rest/jwtauth"
+)
+
+// GJWTAuth verifies the bearer token of the request and stores the claims with key "claims".
+func GJWTAuth(auth *jwtauth.Authenticator) func(*gin.Context) {
+	return Wrap(core.JWTAuth(auth))
+}
+
+// GRequireScopes requires the claims stored by GJWTAuth to grant all of the given scopes.
+func GRequireScopes(scopes ...string) func(*gin.Context) {
+	return Wrap(core.RequireScopes(scopes...))
+}
+
+// GRequireRoles requires the claims stored by GJWTAuth to have any of the given roles.
+func GRequireRoles(roles ...string) func(*gin.Context) {
+	return Wrap(core.RequireRoles(roles...))
+}
+
+// GClaims returns the claims stored by GJWTAuth, nil if not authenticated.
+func GClaims(c *gin.Context) jwt.Claims {
+	return core.Claims(NewContext(c))
+}
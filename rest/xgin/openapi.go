@@ -0,0 +1,76 @@
+package xgin
+
+import (
+	"net/http"
+	"path"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tx991020/utils/rest/core"
+	"github.com/tx991020/utils/rest/openapi"
+)
+
+// A Router registers routes on gin and documents them by the specs of their core middlewares.
+type Router struct {
+	routes gin.IRoutes
+	doc    *openapi.Document
+}
+
+// NewRouter returns a Router that registers routes on routes, like a *gin.Engine or a *gin.RouterGroup.
+func NewRouter(routes gin.IRoutes, doc *openapi.Document) *Router {
+	return &Router{
+		routes: routes,
+		doc:    doc,
+	}
+}
+
+// Handle registers the middlewares followed by handler on method and path, and documents the route.
+func (r *Router) Handle(method, relativePath string, handler func(*gin.Context),
+	middlewares ...core.Handler) *openapi.Operation {
+	handlers := make([]gin.HandlerFunc, 0, len(middlewares)+1)
+	for _, middleware := range middlewares {
+		handlers = append(handlers, Wrap(middleware))
+	}
+	handlers = append(handlers, handler)
+	r.routes.Handle(method, relativePath, handlers...)
+
+	return r.doc.Add(method, r.fullPath(relativePath), middlewares...)
+}
+
+func (r *Router) GET(relativePath string, handler func(*gin.Context), middlewares ...core.Handler) *openapi.Operation {
+	return r.Handle(http.MethodGet, relativePath, handler, middlewares...)
+}
+
+func (r *Router) POST(relativePath string, handler func(*gin.Context), middlewares ...core.Handler) *openapi.Operation {
+	return r.Handle(http.MethodPost, relativePath, handler, middlewares...)
+}
+
+func (r *Router) PUT(relativePath string, handler func(*gin.Context), middlewares ...core.Handler) *openapi.Operation {
+	return r.Handle(http.MethodPut, relativePath, handler, middlewares...)
+}
+
+func (r *Router) PATCH(relativePath string, handler func(*gin.Context), middlewares ...core.Handler) *openapi.Operation {
+	return r.Handle(http.MethodPatch, relativePath, handler, middlewares...)
+}
+
+func (r *Router) DELETE(relativePath string, handler func(*gin.Context), middlewares ...core.Handler) *openapi.Operation {
+	return r.Handle(http.MethodDelete, relativePath, handler, middlewares...)
+}
+
+// ServeDoc serves the OpenAPI document on the given path.
+func (r *Router) ServeDoc(relativePath string) {
+	r.routes.GET(relativePath, gin.WrapH(r.doc))
+}
+
+func (r *Router) fullPath(relativePath string) string {
+	group, ok := r.routes.(interface{ BasePath() string })
+	if !ok {
+		return relativePath
+	}
+
+	full := path.Join(group.BasePath(), relativePath)
+	if len(relativePath) > 0 && relativePath[len(relativePath)-1] == '/' && full[len(full)-1] != '/' {
+		full += "/"
+	}
+
+	return full
+}
@@ -0,0 +1,22 @@
+package xgin
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/tal-tech/go-zero/core/stores/redis"
+	"github.com/tx991020/utils/rest/ratelimit"
+)
+
+// GRateLimit limits the requests by the keys from keyFn, like ratelimit.ClientIP or ratelimit.Subject.
+func GRateLimit(limiter ratelimit.Limiter, keyFn ratelimit.KeyFunc) func(*gin.Context) {
+	return Wrap(ratelimit.Middleware(limiter, keyFn))
+}
+
+// GRateLimitConf limits the requests with the default and per route quotas of conf.
+func GRateLimitConf(conf ratelimit.Conf, store *redis.Redis) (func(*gin.Context), error) {
+	h, err := ratelimit.NewMiddleware(conf, store)
+	if err != nil {
+		return nil, err
+	}
+
+	return Wrap(h), nil
+}
@@ -0,0 +1,13 @@
+package xgin
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/tx991020/utils/load"
+	"github.com/tx991020/utils/rest/shedding"
+)
+
+// GShedding rejects the requests with 503 if shedder is overloaded,
+// like load.NewAdaptiveShedder or load.NewConcurrencyLimiter.
+func GShedding(shedder load.Shedder) func(*gin.Context) {
+	return Wrap(shedding.Middleware(shedder))
+}
@@ -1,72 +1,26 @@
 package xgin
 
 import (
-	"encoding/json"
-	"fmt"
-	"io/ioutil"
-	"net/http"
 	"reflect"
-	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/tx991020/utils/rest/core"
 )
 
-const tp = "text/plain"
-
-const MaxUint = ^uint64(0)
-const MaxInt = int64(MaxUint >> 1)
+const MaxUint = core.MaxUint
+const MaxInt = core.MaxInt
 
 func GRequestBody(c *gin.Context) {
-	b, err := ioutil.ReadAll(c.Request.Body)
-	if err != nil {
-		c.Data(http.StatusBadRequest, tp, []byte("Parse body failed."))
-		c.Abort()
-	} else {
-		c.Set("requestBody", b)
-	}
-	return
+	Wrap(core.HandlerFunc(core.RequestBody))(c)
 }
 
 func GRequestBodyMap(c *gin.Context) {
-	b, err := ioutil.ReadAll(c.Request.Body)
-	if err != nil {
-		c.Data(http.StatusBadRequest, tp, []byte("Parse body failed."))
-		c.Abort()
-	} else {
-		m := map[string]interface{}{}
-		if err = json.Unmarshal(b, &m); err != nil {
-			c.Data(http.StatusBadRequest, tp, []byte(fmt.Sprintf("Json unmarshal failed: %s, %v, %v", string(b), m, err)))
-		} else {
-			c.Set("requestBody", m)
-		}
-	}
-	return
+	Wrap(core.HandlerFunc(core.RequestBodyMap))(c)
 }
 
 // idl = {json, xml}
 func GRequestBodyObject(t reflect.Type, idl string) func(*gin.Context) {
-	return func(c *gin.Context) {
-		instance := reflect.New(t).Interface()
-
-		b, err := ioutil.ReadAll(c.Request.Body)
-		if err != nil {
-			c.Data(http.StatusBadRequest, tp, []byte("Parse body failed."))
-			c.Abort()
-		} else {
-			switch idl {
-			case "json":
-				if err = json.Unmarshal(b, instance); err != nil {
-					c.Data(http.StatusBadRequest, tp, []byte(fmt.Sprintf("Json unmarshal failed: %s, %v, %v", string(b), instance, err)))
-					c.Abort()
-				} else {
-					c.Set("requestBody", instance)
-				}
-			case "xml":
-			default:
-			}
-		}
-		return
-	}
+	return Wrap(core.RequestBodyObject(t, idl))
 }
 
 func GPathRequireInt(match string) func(*gin.Context) { return GPathInt(match, true, "") }
@@ -80,19 +34,7 @@ func GPathOptionalIntAlias(match string, alias string) func(*gin.Context) {
 }
 
 func GPathInt(match string, must bool, alias string) func(*gin.Context) {
-	return func(c *gin.Context) {
-		if n, err := strconv.ParseInt(c.Param(match), 10, 64); err != nil {
-			if must {
-				c.Data(http.StatusBadRequest, tp, []byte(fmt.Sprintf("Parse path %s failed.", match)))
-				c.Abort()
-			}
-		} else {
-			c.Set(match, n)
-			if len(alias) > 0 {
-				c.Set(alias, n)
-			}
-		}
-	}
+	return Wrap(core.PathInt(match, must, alias))
 }
 
 func GPathRequireString(match string) func(*gin.Context) {
@@ -110,19 +52,7 @@ func GPathOptionalStringAlias(match string, alias string) func(*gin.Context) {
 }
 
 func GPathString(match string, must bool, alias string) func(*gin.Context) {
-	return func(c *gin.Context) {
-		if len(c.Param(match)) == 0 {
-			if must {
-				c.Data(http.StatusBadRequest, tp, []byte(fmt.Sprintf("Parse path param: %s failed.", match)))
-				c.Abort()
-			}
-		} else {
-			c.Set(match, c.Param(match))
-			if len(alias) > 0 {
-				c.Set(alias, c.Param(match))
-			}
-		}
-	}
+	return Wrap(core.PathString(match, must, alias))
 }
 
 func GHeaderRequireInt(match string) func(*gin.Context) { return GHeaderInt(match, true, "") }
@@ -136,24 +66,7 @@ func GHeaderOptionalIntAlias(match string, alias string) func(*gin.Context) {
 }
 
 func GHeaderInt(match string, must bool, alias string) func(*gin.Context) {
-	return func(c *gin.Context) {
-		if len(c.Request.Header[match]) > 0 {
-			if id, err := strconv.ParseInt(c.GetHeader(match), 10, 64); err != nil {
-				if must {
-					c.Data(http.StatusBadRequest, tp, []byte(fmt.Sprintf("Parse header: %s failed.", match)))
-					c.Abort()
-				}
-			} else {
-				c.Set(match, id)
-				if len(alias) > 0 {
-					c.Set(alias, id)
-				}
-			}
-		} else if must {
-			c.Data(http.StatusBadRequest, tp, []byte(fmt.Sprintf("Parse header: %s failed.", match)))
-			c.Abort()
-		}
-	}
+	return Wrap(core.HeaderInt(match, must, alias))
 }
 
 func GHeaderRequireString(match string) func(*gin.Context) {
@@ -171,21 +84,7 @@ func GHeaderOptionalStringAlias(match string, alias string) func(*gin.Context) {
 }
 
 func GHeaderString(match string, must bool, alias string) func(*gin.Context) {
-	return func(c *gin.Context) {
-		if len(c.GetHeader(match)) == 0 {
-			if must {
-				c.Data(http.StatusBadRequest, tp, []byte(fmt.Sprintf("Parse header: %s failed.", match)))
-				c.Abort()
-			}
-		} else {
-
-			c.Set(match, c.GetHeader(match))
-			if len(alias) > 0 {
-				c.Set(alias, c.Request.Header[match])
-
-			}
-		}
-	}
+	return Wrap(core.HeaderString(match, must, alias))
 }
 
 func GHeaderOptionalStringDefault(match string, defaultValue string) func(*gin.Context) {
@@ -193,23 +92,7 @@ func GHeaderOptionalStringDefault(match string, defaultValue string) func(*gin.C
 }
 
 func GHeaderStringDefault(match string, must bool, alias, defaultValue string) func(*gin.Context) {
-	return func(c *gin.Context) {
-		if len(c.Request.Header[match]) == 0 {
-			if must {
-				c.Data(http.StatusBadRequest, tp, []byte(fmt.Sprintf("Parse header: %s failed.", match)))
-				c.Abort()
-			}
-			c.Set(match, defaultValue)
-			if len(alias) > 0 {
-				c.Set(alias, defaultValue)
-			}
-		} else {
-			c.Set(match, c.Request.Header.Get(match))
-			if len(alias) > 0 {
-				c.Set(alias, c.Request.Header.Get(match))
-			}
-		}
-	}
+	return Wrap(core.HeaderStringDefault(match, must, alias, defaultValue))
 }
 
 func GQueryRequirePositiveInt(match string) func(*gin.Context) {
@@ -227,26 +110,7 @@ func GQueryOptionalPositiveIntAlias(match string, alias string) func(*gin.Contex
 }
 
 func GQueryPositiveInt(match string, must bool, alias string) func(*gin.Context) {
-	return func(c *gin.Context) {
-		q := c.Request.URL.Query()
-		if q[match] == nil || len(q[match][0]) == 0 {
-			if must {
-				c.Data(http.StatusBadRequest, tp, []byte(fmt.Sprintf("Parse query param: %s not exist.", match)))
-				c.Abort()
-			}
-		} else if n, err := strconv.ParseInt(q[match][0], 10, 64); err != nil {
-			c.Data(http.StatusBadRequest, tp, []byte(fmt.Sprintf("Parse query param: %s failed.", match)))
-			c.Abort()
-		} else if n < 0 {
-			c.Data(http.StatusBadRequest, tp, []byte(fmt.Sprintf("Parse query param: %s out of range.", match)))
-			c.Abort()
-		} else {
-			c.Set(match, n)
-			if len(alias) > 0 {
-				c.Set(alias, n)
-			}
-		}
-	}
+	return Wrap(core.QueryPositiveInt(match, must, alias))
 }
 
 func GQueryRequireInt(match string) func(*gin.Context) { return GQueryInt(match, true, "", MaxInt) }
@@ -264,30 +128,7 @@ func GQueryOptionalIntDefault(match string, defaultValue int64) func(*gin.Contex
 }
 
 func GQueryInt(match string, must bool, alias string, defaultValue int64) func(*gin.Context) {
-	return func(c *gin.Context) {
-		q := c.Request.URL.Query()
-		if q[match] == nil || len(q[match][0]) == 0 {
-			if must {
-				c.Data(http.StatusBadRequest, tp, []byte(fmt.Sprintf("Parse query param: %s not exist.", match)))
-				c.Abort()
-			}
-			if defaultValue == MaxInt {
-				return
-			}
-			c.Set(match, defaultValue)
-			if len(alias) > 0 {
-				c.Set(alias, defaultValue)
-			}
-		} else if n, err := strconv.ParseInt(q[match][0], 10, 64); err != nil {
-			c.Data(http.StatusBadRequest, tp, []byte(fmt.Sprintf("Parse query param: %s failed.", match)))
-			c.Abort()
-		} else {
-			c.Set(match, n)
-			if len(alias) > 0 {
-				c.Set(alias, n)
-			}
-		}
-	}
+	return Wrap(core.QueryInt(match, must, alias, defaultValue))
 }
 
 func GQueryRequireString(match string) func(*gin.Context) { return GQueryString(match, true, "") }
@@ -301,20 +142,7 @@ func GQueryOptionalStringAlias(match string, alias string) func(*gin.Context) {
 }
 
 func GQueryString(match string, must bool, alias string) func(*gin.Context) {
-	return func(c *gin.Context) {
-		q := c.Request.URL.Query()
-		if q[match] == nil || len(q[match][0]) == 0 {
-			if must {
-				c.Data(http.StatusBadRequest, tp, []byte(fmt.Sprintf("Parse query param: %s failed.", match)))
-				c.Abort()
-			}
-		} else {
-			c.Set(match, q[match][0])
-			if len(alias) > 0 {
-				c.Set(alias, q[match][0])
-			}
-		}
-	}
+	return Wrap(core.QueryString(match, must, alias))
 }
 
 func GQueryOptionalStringDefault(match string, defaultValue string) func(*gin.Context) {
@@ -322,28 +150,11 @@ func GQueryOptionalStringDefault(match string, defaultValue string) func(*gin.Co
 }
 
 func GQueryStringDefault(match string, must bool, alias, defaultValue string) func(*gin.Context) {
-	return func(c *gin.Context) {
-		q := c.Request.URL.Query()
-		if q[match] == nil || len(q[match][0]) == 0 {
-			if must {
-				c.Data(http.StatusBadRequest, tp, []byte(fmt.Sprintf("Parse query param: %s failed.", match)))
-				c.Abort()
-			}
-			c.Set(match, defaultValue)
-			if len(alias) > 0 {
-				c.Set(alias, defaultValue)
-			}
-		} else {
-			c.Set(match, q[match][0])
-			if len(alias) > 0 {
-				c.Set(alias, q[match][0])
-			}
-		}
-	}
+	return Wrap(core.QueryStringDefault(match, must, alias, defaultValue))
 }
 
+// GJsonResponse renders the data stored by core.SetResponse or the error stored by core.SetError
+// in the standard envelope after the pending handlers.
 func GJsonResponse(c *gin.Context) {
-	c.Header("Content-Type", "application/json")
+	Wrap(core.HandlerFunc(core.JsonResponse))(c)
 }
-
-
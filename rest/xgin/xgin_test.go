@@ -0,0 +1,70 @@
+package xgin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/tx991020/utils/load"
+	"github.com/tx991020/utils/rest/core"
+	"github.com/tx991020/utils/rest/openapi"
+)
+
+func TestGPathAndQuery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.GET("/users/:id", GPathRequireInt("id"), GQueryOptionalIntDefault("page", 1),
+		func(c *gin.Context) {
+			c.JSON(http.StatusOK, gin.H{
+				"id":   c.GetInt64("id"),
+				"page": c.GetInt64("page"),
+			})
+		})
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/3", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"id":3,"page":1}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/abc", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"code":40000,"msg":"Parse path id failed."}`, w.Body.String())
+}
+
+func TestRouter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	doc := openapi.NewDocument("users", "1.0")
+	router := NewRouter(engine.Group("/api"), doc)
+	router.GET("/users/:id", func(c *gin.Context) {
+		OK(c, c.GetInt64("id"))
+	}, core.PathInt("id", true, ""))
+	router.ServeDoc("/openapi.json")
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/users/3", nil))
+	assert.JSONEq(t, `{"code":0,"msg":"ok","data":3}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"/api/users/{id}"`)
+}
+
+func TestGShedding(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	limiter := load.NewConcurrencyLimiter(load.WithLimits(10, 1, 10), load.WithBackoff(0.5))
+	engine.GET("/busy", GShedding(limiter), func(c *gin.Context) {
+		c.Status(http.StatusServiceUnavailable)
+	})
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/busy", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, 5, limiter.Limit())
+	assert.Equal(t, 0, limiter.Inflight())
+}
@@ -0,0 +1,17 @@
+package xhttp
+
+import (
+	"net/http"
+
+	"github.com/tx991020/utils/rest/core"
+)
+
+// OK responds with data in the standard envelope.
+func OK(w http.ResponseWriter, r *http.Request, data interface{}) {
+	core.OK(NewContext(w, r), data)
+}
+
+// Error responds with err in the standard envelope, see core.ToCodeError.
+func Error(w http.ResponseWriter, r *http.Request, err error) {
+	core.Error(NewContext(w, r), err)
+}
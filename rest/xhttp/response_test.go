@@ -0,0 +1,64 @@
+package xhttp
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tx991020/utils/rest/core"
+)
+
+func TestOK(t *testing.T) {
+	w := httptest.NewRecorder()
+	OK(w, httptest.NewRequest(http.MethodGet, "/", nil), map[string]int{"count": 1})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"code":0,"msg":"ok","data":{"count":1}}`, w.Body.String())
+}
+
+func TestErrorTranslated(t *testing.T) {
+	core.SetTranslator(func(lang, key string, args ...interface{}) (string, bool) {
+		if lang == "zh" && key == "user.notfound" {
+			return fmt.Sprintf("用户%v不存在", args...), true
+		}
+		return "", false
+	})
+	defer core.SetTranslator(nil)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept-Language", "zh;q=0.9, en;q=0.8")
+	w := httptest.NewRecorder()
+	Error(w, r, core.NewNotFoundError("user not found").WithKey("user.notfound", 7))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.JSONEq(t, `{"code":40400,"msg":"用户7不存在"}`, w.Body.String())
+
+	r.Header.Set("Accept-Language", "en")
+	w = httptest.NewRecorder()
+	Error(w, r, core.NewNotFoundError("user not found").WithKey("user.notfound", 7))
+	assert.JSONEq(t, `{"code":40400,"msg":"user not found"}`, w.Body.String())
+}
+
+func TestJsonResponseWithRequestID(t *testing.T) {
+	h := Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		core.SetError(NewContext(w, r), core.NewRateLimitedError("slow down"))
+	}), Wrap(core.RequestID("X-Request-Id")), Wrap(core.HandlerFunc(core.JsonResponse)))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-Request-Id", "abc")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "abc", w.Header().Get("X-Request-Id"))
+	assert.JSONEq(t, `{"code":42900,"msg":"slow down","requestId":"abc"}`, w.Body.String())
+
+	h = Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		core.SetResponse(NewContext(w, r), "done")
+	}), Wrap(core.RequestID("X-Request-Id")), Wrap(core.HandlerFunc(core.JsonResponse)))
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, w.Header().Get("X-Request-Id"), 32)
+	assert.Contains(t, w.Body.String(), `"data":"done"`)
+}
@@ -0,0 +1,193 @@
+package xhttp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+
+	"github.com/tx991020/utils/rest/core"
+)
+
+type (
+	// ParamFunc returns the path parameter with the given name, supplied by the router in use.
+	ParamFunc func(r *http.Request, name string) string
+
+	// Middleware is the standard net/http middleware.
+	Middleware func(http.Handler) http.Handler
+
+	Option func(opts *options)
+
+	options struct {
+		params ParamFunc
+	}
+
+	valuesKey struct{}
+
+	values map[string]interface{}
+
+	statusWriter struct {
+		http.ResponseWriter
+		status int
+	}
+
+	httpContext struct {
+		w       *statusWriter
+		r       *http.Request
+		next    http.Handler
+		params  ParamFunc
+		nexted  bool
+		aborted bool
+	}
+)
+
+// Wrap converts the core handler into a net/http middleware,
+// the next handler is served if h neither calls Next nor aborts.
+func Wrap(h core.Handler, opts ...Option) Middleware {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			c := &httpContext{
+				w:      newStatusWriter(w),
+				r:      withValues(r),
+				next:   next,
+				params: o.params,
+			}
+			h.Handle(c)
+			if !c.nexted && !c.aborted {
+				next.ServeHTTP(c.w, c.r)
+			}
+		})
+	}
+}
+
+// Chain returns h wrapped by the middlewares, the first middleware runs first.
+func Chain(h http.Handler, middlewares ...Middleware) http.Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		h = middlewares[i](h)
+	}
+
+	return h
+}
+
+// Value returns the value stored with key by the wrapped core handlers.
+func Value(r *http.Request, key string) (interface{}, bool) {
+	vals, ok := r.Context().Value(valuesKey{}).(values)
+	if !ok {
+		return nil, false
+	}
+
+	v, ok := vals[key]
+	return v, ok
+}
+
+// WithParamFunc customizes how path parameters are looked up.
+func WithParamFunc(fn ParamFunc) Option {
+	return func(opts *options) {
+		opts.params = fn
+	}
+}
+
+// NewContext returns a core.Context backed by w and r, Next does nothing on it.
+func NewContext(w http.ResponseWriter, r *http.Request) core.Context {
+	return &httpContext{
+		w: newStatusWriter(w),
+		r: withValues(r),
+	}
+}
+
+func withValues(r *http.Request) *http.Request {
+	if _, ok := r.Context().Value(valuesKey{}).(values); ok {
+		return r
+	}
+
+	return r.WithContext(context.WithValue(r.Context(), valuesKey{}, make(values)))
+}
+
+func (hc *httpContext) Request() *http.Request {
+	return hc.r
+}
+
+func (hc *httpContext) Param(name string) string {
+	if hc.params == nil {
+		return ""
+	}
+
+	return hc.params(hc.r, name)
+}
+
+func (hc *httpContext) Route() string {
+	return hc.r.URL.Path
+}
+
+func (hc *httpContext) Get(key string) (interface{}, bool) {
+	return Value(hc.r, key)
+}
+
+func (hc *httpContext) Set(key string, value interface{}) {
+	hc.r.Context().Value(valuesKey{}).(values)[key] = value
+}
+
+func (hc *httpContext) Header() http.Header {
+	return hc.w.Header()
+}
+
+func (hc *httpContext) Write(status int, contentType string, body []byte) {
+	hc.w.Header().Set("Content-Type", contentType)
+	hc.w.WriteHeader(status)
+	hc.w.Write(body)
+}
+
+func (hc *httpContext) Status() int {
+	return hc.w.status
+}
+
+func (hc *httpContext) Next() {
+	if hc.next == nil || hc.nexted || hc.aborted {
+		return
+	}
+
+	hc.nexted = true
+	hc.next.ServeHTTP(hc.w, hc.r)
+}
+
+func (hc *httpContext) Abort() {
+	hc.aborted = true
+}
+
+func newStatusWriter(w http.ResponseWriter) *statusWriter {
+	if sw, ok := w.(*statusWriter); ok {
+		return sw
+	}
+
+	return &statusWriter{
+		ResponseWriter: w,
+		status:         http.StatusOK,
+	}
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Flush implements http.Flusher if the underlying writer does.
+func (w *statusWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker for websockets.
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if hijacker, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return hijacker.Hijack()
+	}
+
+	return nil, nil, errors.New("http.Hijacker not implemented")
+}
@@ -0,0 +1,91 @@
+package xhttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tx991020/utils/rest/binding"
+	"github.com/tx991020/utils/rest/core"
+)
+
+func pathParams(r *http.Request, name string) string {
+	if name == "id" {
+		return strings.TrimPrefix(r.URL.Path, "/users/")
+	}
+	return ""
+}
+
+func TestChainStoresValues(t *testing.T) {
+	var id, page, token interface{}
+	h := Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, _ = Value(r, "id")
+		page, _ = Value(r, "p")
+		token, _ = Value(r, "X-Token")
+	}), Wrap(core.PathInt("id", true, ""), WithParamFunc(pathParams)),
+		Wrap(core.QueryInt("page", false, "p", 1)),
+		Wrap(core.HeaderString("X-Token", true, "")))
+
+	r := httptest.NewRequest(http.MethodGet, "/users/12", nil)
+	r.Header.Set("X-Token", "abc")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, int64(12), id)
+	assert.Equal(t, int64(1), page)
+	assert.Equal(t, "abc", token)
+}
+
+func TestChainAborts(t *testing.T) {
+	var called bool
+	h := Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}), Wrap(core.QueryPositiveInt("page", true, "")))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/?page=-1", nil))
+	assert.False(t, called)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"code":40000,"msg":"Parse query param: page out of range."}`, w.Body.String())
+}
+
+func TestChainNext(t *testing.T) {
+	var steps []string
+	h := Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		steps = append(steps, "handler")
+	}), Wrap(core.HandlerFunc(func(c core.Context) {
+		steps = append(steps, "before")
+		c.Next()
+		steps = append(steps, "after")
+	})))
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, []string{"before", "handler", "after"}, steps)
+}
+
+func TestChainBind(t *testing.T) {
+	type request struct {
+		ID   int64  `in:"path" name:"id"`
+		Name string `json:"name" validate:"required"`
+	}
+
+	var req interface{}
+	h := Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, _ = Value(r, binding.RequestKey)
+	}), Wrap(core.RequestBind(reflect.TypeOf(request{})), WithParamFunc(pathParams)))
+
+	r := httptest.NewRequest(http.MethodPost, "/users/3", strings.NewReader(`{"name":"kevin"}`))
+	r.Header.Set("Content-Type", "application/json")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	assert.Equal(t, &request{ID: 3, Name: "kevin"}, req)
+
+	r = httptest.NewRequest(http.MethodPost, "/users/3", strings.NewReader(`{}`))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `"field":"Name"`)
+}
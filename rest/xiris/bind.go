@@ -0,0 +1,19 @@
+package xiris
+
+import (
+	"reflect"
+
+	"github.com/kataras/iris/v12"
+	"github.com/tx991020/utils/rest/core"
+)
+
+// GRequestBind fills a new instance of t from path, query, header, form and body by its struct tags,
+// and stores it with key "request", see binding.Bind.
+func GRequestBind(t reflect.Type) func(iris.Context) {
+	return Wrap(core.RequestBind(t))
+}
+
+// Bind fills dst from the request of c, see binding.Bind.
+func Bind(c iris.Context, dst interface{}) error {
+	return core.Bind(NewContext(c), dst)
+}
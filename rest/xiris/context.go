@@ -0,0 +1,88 @@
+package xiris
+
+import (
+	"net/http"
+
+	"github.com/kataras/iris/v12"
+	"github.com/tx991020/utils/rest/core"
+)
+
+type irisContext struct {
+	c      iris.Context
+	nexted *bool
+}
+
+// Wrap converts the core handler into an iris handler,
+// the pending handlers are run if h neither calls Next nor aborts.
+func Wrap(h core.Handler) func(iris.Context) {
+	return func(c iris.Context) {
+		var nexted bool
+		h.Handle(irisContext{
+			c:      c,
+			nexted: &nexted,
+		})
+		if !nexted && !c.IsStopped() {
+			c.Next()
+		}
+	}
+}
+
+// NewContext returns a core.Context backed by c.
+func NewContext(c iris.Context) core.Context {
+	return irisContext{
+		c:      c,
+		nexted: new(bool),
+	}
+}
+
+func (ic irisContext) Request() *http.Request {
+	return ic.c.Request()
+}
+
+func (ic irisContext) Param(name string) string {
+	return ic.c.Params().Get(name)
+}
+
+func (ic irisContext) Route() string {
+	if route := ic.c.GetCurrentRoute(); route != nil {
+		return route.Path()
+	}
+
+	return ic.c.Path()
+}
+
+func (ic irisContext) Get(key string) (interface{}, bool) {
+	entry, ok := ic.c.Values().GetEntry(key)
+	if !ok {
+		return nil, false
+	}
+
+	return entry.ValueRaw, true
+}
+
+func (ic irisContext) Set(key string, value interface{}) {
+	ic.c.Values().Set(key, value)
+}
+
+func (ic irisContext) Header() http.Header {
+	return ic.c.ResponseWriter().Header()
+}
+
+func (ic irisContext) Write(status int, contentType string, body []byte) {
+	ic.c.StatusCode(status)
+	ic.c.ContentType(contentType)
+	ic.c.Write(body)
+}
+
+func (ic irisContext) Status() int {
+	return ic.c.GetStatusCode()
+}
+
+func (ic irisContext) Next() {
+	*ic.nexted = true
+	ic.c.Next()
+}
+
+func (ic irisContext) Abort() {
+	ic.c.StopExecution()
+}
@@ -0,0 +1,28 @@
+package xiris
+
+import (
+	"github.com/dgrijalva/jwt-go"
+	"github.com/kataras/iris/v12"
+	"github.com/tx991020/utils/rest/core"
+	"github.com/tx991020/utils/rest/jwtauth"
+)
+
+// GJWTAuth verifies the bearer token of the request and stores the claims with key "claims".
+func GJWTAuth(auth *jwtauth.Authenticator) func(iris.Context) {
+	return Wrap(core.JWTAuth(auth))
+}
+
+// GRequireScopes requires the claims stored by GJWTAuth to grant all of the given scopes.
+func GRequireScopes(scopes ...string) func(iris.Context) {
+	return Wrap(core.RequireScopes(scopes...))
+}
+
+// GRequireRoles requires the claims stored by GJWTAuth to have any of the given roles.
+func GRequireRoles(roles ...string) func(iris.Context) {
+	return Wrap(core.RequireRoles(roles...))
+}
+
+// GClaims returns the claims stored by GJWTAuth, nil if not authenticated.
+func GClaims(c iris.Context) jwt.Claims {
+	return core.Claims(NewContext(c))
+}
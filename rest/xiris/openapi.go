@@ -0,0 +1,62 @@
+package xiris
+
+import (
+	"net/http"
+
+	"github.com/kataras/iris/v12"
+	"github.com/kataras/iris/v12/context"
+	"github.com/tx991020/utils/rest/core"
+	"github.com/tx991020/utils/rest/openapi"
+)
+
+// A Router registers routes on iris and documents them by the specs of their core middlewares.
+type Router struct {
+	party iris.Party
+	doc   *openapi.Document
+}
+
+// NewRouter returns a Router that registers routes on party, like an *iris.Application.
+func NewRouter(party iris.Party, doc *openapi.Document) *Router {
+	return &Router{
+		party: party,
+		doc:   doc,
+	}
+}
+
+// Handle registers the middlewares followed by handler on method and path, and documents the route.
+func (r *Router) Handle(method, relativePath string, handler func(iris.Context),
+	middlewares ...core.Handler) *openapi.Operation {
+	handlers := make([]context.Handler, 0, len(middlewares)+1)
+	for _, middleware := range middlewares {
+		handlers = append(handlers, Wrap(middleware))
+	}
+	handlers = append(handlers, handler)
+	route := r.party.Handle(method, relativePath, handlers...)
+
+	return r.doc.Add(method, route.Path, middlewares...)
+}
+
+func (r *Router) GET(relativePath string, handler func(iris.Context), middlewares ...core.Handler) *openapi.Operation {
+	return r.Handle(http.MethodGet, relativePath, handler, middlewares...)
+}
+
+func (r *Router) POST(relativePath string, handler func(iris.Context), middlewares ...core.Handler) *openapi.Operation {
+	return r.Handle(http.MethodPost, relativePath, handler, middlewares...)
+}
+
+func (r *Router) PUT(relativePath string, handler func(iris.Context), middlewares ...core.Handler) *openapi.Operation {
+	return r.Handle(http.MethodPut, relativePath, handler, middlewares...)
+}
+
+func (r *Router) PATCH(relativePath string, handler func(iris.Context), middlewares ...core.Handler) *openapi.Operation {
+	return r.Handle(http.MethodPatch, relativePath, handler, middlewares...)
+}
+
+func (r *Router) DELETE(relativePath string, handler func(iris.Context), middlewares ...core.Handler) *openapi.Operation {
+	return r.Handle(http.MethodDelete, relativePath, handler, middlewares...)
+}
+
+// ServeDoc serves the OpenAPI document on the given path.
+func (r *Router) ServeDoc(relativePath string) {
+	r.party.Get(relativePath, iris.FromStd(r.doc))
+}
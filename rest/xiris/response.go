@@ -0,0 +1,21 @@
+package xiris
+
+import (
+	"github.com/kataras/iris/v12"
+	"github.com/tx991020/utils/rest/core"
+)
+
+// OK responds with data in the standard envelope.
+func OK(c iris.Context, data interface{}) {
+	core.OK(NewContext(c), data)
+}
+
+// Error responds with err in the standard envelope and aborts the pending handlers, see core.ToCodeError.
+func Error(c iris.Context, err error) {
+	core.Error(NewContext(c), err)
+}
+
+// GRequestID takes the request id from the given header or generates one, and adds it to the envelope.
+func GRequestID(header string) func(iris.Context) {
+	return Wrap(core.RequestID(header))
+}
@@ -0,0 +1,13 @@
+package xiris
+
+import (
+	"github.com/kataras/iris/v12"
+	"github.com/tx991020/utils/load"
+	"github.com/tx991020/utils/rest/shedding"
+)
+
+// GShedding rejects the requests with 503 if shedder is overloaded,
+// like load.NewAdaptiveShedder or load.NewConcurrencyLimiter.
+func GShedding(shedder load.Shedder) func(iris.Context) {
+	return Wrap(shedding.Middleware(shedder))
+}
@@ -1,75 +1,26 @@
 package xiris
 
 import (
-	"encoding/json"
-	"fmt"
-	"io/ioutil"
 	"reflect"
-	"strconv"
 
 	"github.com/kataras/iris/v12"
+	"github.com/tx991020/utils/rest/core"
 )
 
-const MaxUint = ^uint64(0)
-const MaxInt = int64(MaxUint >> 1)
+const MaxUint = core.MaxUint
+const MaxInt = core.MaxInt
 
 func GRequestBody(c iris.Context) {
-	b, err := ioutil.ReadAll(c.Request().Body)
-	if err != nil {
-		c.Text("Parse body failed.")
-		c.StopExecution()
-	} else {
-
-		c.Values().Set("requestBody", b)
-		c.Next()
-	}
-	return
+	Wrap(core.HandlerFunc(core.RequestBody))(c)
 }
 
 func GRequestBodyMap(c iris.Context) {
-	b, err := ioutil.ReadAll(c.Request().Body)
-	if err != nil {
-		c.Text("Parse body failed.")
-		c.StopExecution()
-
-	} else {
-		m := map[string]interface{}{}
-		if err = json.Unmarshal(b, &m); err != nil {
-			c.Text(fmt.Sprintf("Json unmarshal failed: %s, %v, %v", string(b), m, err))
-			c.StopExecution()
-		} else {
-			c.Values().Set("requestBody", m)
-			c.Next()
-		}
-	}
-	return
+	Wrap(core.HandlerFunc(core.RequestBodyMap))(c)
 }
 
 // idl = {json, xml}
 func GRequestBodyObject(t reflect.Type, idl string) func(iris.Context) {
-	return func(c iris.Context) {
-		instance := reflect.New(t).Interface()
-
-		b, err := ioutil.ReadAll(c.Request().Body)
-		if err != nil {
-			c.Text("Parse body failed.")
-			c.StopExecution()
-		} else {
-			switch idl {
-			case "json":
-				if err = json.Unmarshal(b, instance); err != nil {
-					c.Text(fmt.Sprintf("Json unmarshal failed: %s, %v, %v", string(b), instance, err))
-					c.StopExecution()
-				} else {
-					c.Values().Set("requestBody", instance)
-					c.Next()
-				}
-			case "xml":
-			default:
-			}
-		}
-		return
-	}
+	return Wrap(core.RequestBodyObject(t, idl))
 }
 
 func GPathRequireInt(match string) func(iris.Context) { return GPathInt(match, true, "") }
@@ -83,22 +34,7 @@ func GPathOptionalIntAlias(match string, alias string) func(iris.Context) {
 }
 
 func GPathInt(match string, must bool, alias string) func(iris.Context) {
-	return func(c iris.Context) {
-		c.Params()
-		if n, err := strconv.ParseInt(c.Params().Get(match), 10, 64); err != nil {
-			if must {
-				c.Text(fmt.Sprintf("Parse path %s failed.", match))
-				c.StopExecution()
-			}
-		} else {
-			c.Values().Set(match, n)
-			c.Next()
-			if len(alias) > 0 {
-				c.Values().Set(alias, n)
-				c.Next()
-			}
-		}
-	}
+	return Wrap(core.PathInt(match, must, alias))
 }
 
 func GPathRequireString(match string) func(iris.Context) {
@@ -116,22 +52,7 @@ func GPathOptionalStringAlias(match string, alias string) func(iris.Context) {
 }
 
 func GPathString(match string, must bool, alias string) func(iris.Context) {
-	return func(c iris.Context) {
-		if len(c.Params().Get(match)) == 0 {
-			if must {
-
-				c.Text(fmt.Sprintf("Parse path param: %s failed.", match))
-				c.StopExecution()
-			}
-		} else {
-			c.Values().Set(match, c.Params().Get(match))
-			c.Next()
-			if len(alias) > 0 {
-				c.Values().Set(alias, c.Params().Get(match))
-				c.Next()
-			}
-		}
-	}
+	return Wrap(core.PathString(match, must, alias))
 }
 
 func GHeaderRequireInt(match string) func(iris.Context) { return GHeaderInt(match, true, "") }
@@ -145,26 +66,7 @@ func GHeaderOptionalIntAlias(match string, alias string) func(iris.Context) {
 }
 
 func GHeaderInt(match string, must bool, alias string) func(iris.Context) {
-	return func(c iris.Context) {
-		if len(c.GetHeader(match)) > 0 {
-			if id, err := strconv.ParseInt(c.GetHeader(match), 10, 64); err != nil {
-				if must {
-					c.Text(fmt.Sprintf("Parse header: %s failed.", match))
-					c.StopExecution()
-				}
-			} else {
-				c.Values().Set(match, id)
-				c.Next()
-				if len(alias) > 0 {
-					c.Values().Set(alias, id)
-					c.Next()
-				}
-			}
-		} else if must {
-			c.Text(fmt.Sprintf("Parse header: %s failed.", match))
-			c.StopExecution()
-		}
-	}
+	return Wrap(core.HeaderInt(match, must, alias))
 }
 
 func GHeaderRequireString(match string) func(iris.Context) {
@@ -182,23 +84,7 @@ func GHeaderOptionalStringAlias(match string, alias string) func(iris.Context) {
 }
 
 func GHeaderString(match string, must bool, alias string) func(iris.Context) {
-	return func(c iris.Context) {
-		if len(c.GetHeader(match)) == 0 {
-			if must {
-				c.Text(fmt.Sprintf("Parse header: %s failed.", match))
-				c.StopExecution()
-			}
-		} else {
-
-			c.Values().Set(match, c.GetHeader(match))
-			c.Next()
-			if len(alias) > 0 {
-				c.Values().Set(alias, c.GetHeader(match))
-				c.Next()
-
-			}
-		}
-	}
+	return Wrap(core.HeaderString(match, must, alias))
 }
 
 func GHeaderOptionalStringDefault(match string, defaultValue string) func(iris.Context) {
@@ -206,27 +92,7 @@ func GHeaderOptionalStringDefault(match string, defaultValue string) func(iris.C
 }
 
 func GHeaderStringDefault(match string, must bool, alias, defaultValue string) func(iris.Context) {
-	return func(c iris.Context) {
-		if len(c.GetHeader(match)) == 0 {
-			if must {
-				c.Text(fmt.Sprintf("Parse header: %s failed.", match))
-				c.StopExecution()
-			}
-			c.Values().Set(match, defaultValue)
-			c.Next()
-			if len(alias) > 0 {
-				c.Values().Set(alias, defaultValue)
-				c.Next()
-			}
-		} else {
-			c.Values().Set(match, c.GetHeader(match))
-			c.Next()
-			if len(alias) > 0 {
-				c.Values().Set(alias, c.GetHeader(match))
-				c.Next()
-			}
-		}
-	}
+	return Wrap(core.HeaderStringDefault(match, must, alias, defaultValue))
 }
 
 func GQueryRequirePositiveInt(match string) func(iris.Context) {
@@ -244,28 +110,7 @@ func GQueryOptionalPositiveIntAlias(match string, alias string) func(iris.Contex
 }
 
 func GQueryPositiveInt(match string, must bool, alias string) func(iris.Context) {
-	return func(c iris.Context) {
-		q := c.Request().URL.Query()
-		if q[match] == nil || len(q[match][0]) == 0 {
-			if must {
-				c.Text(fmt.Sprintf("Parse query param: %s failed.", match))
-				c.StopExecution()
-			}
-		} else if n, err := strconv.ParseInt(q[match][0], 10, 64); err != nil {
-			c.Text(fmt.Sprintf("Parse query param: %s failed.", match))
-			c.StopExecution()
-		} else if n < 0 {
-			c.Text(fmt.Sprintf("Parse query param: %s out of range.", match))
-			c.StopExecution()
-		} else {
-			c.Values().Set(match, n)
-			c.Next()
-			if len(alias) > 0 {
-				c.Values().Set(alias, n)
-				c.Next()
-			}
-		}
-	}
+	return Wrap(core.QueryPositiveInt(match, must, alias))
 }
 
 func GQueryRequireInt(match string) func(iris.Context) { return GQueryInt(match, true, "", MaxInt) }
@@ -283,34 +128,7 @@ func GQueryOptionalIntDefault(match string, defaultValue int64) func(iris.Contex
 }
 
 func GQueryInt(match string, must bool, alias string, defaultValue int64) func(iris.Context) {
-	return func(c iris.Context) {
-		q := c.Request().URL.Query()
-		if q[match] == nil || len(q[match][0]) == 0 {
-			if must {
-				c.Text(fmt.Sprintf("Parse query param: %s failed.", match))
-				c.StopExecution()
-			}
-			if defaultValue == MaxInt {
-				return
-			}
-			c.Values().Set(match, defaultValue)
-			c.Next()
-			if len(alias) > 0 {
-				c.Values().Set(alias, defaultValue)
-				c.Next()
-			}
-		} else if n, err := strconv.ParseInt(q[match][0], 10, 64); err != nil {
-			c.Text(fmt.Sprintf("Parse query param: %s failed.", match))
-			c.StopExecution()
-		} else {
-			c.Values().Set(match, n)
-			c.Next()
-			if len(alias) > 0 {
-				c.Values().Set(alias, n)
-				c.Next()
-			}
-		}
-	}
+	return Wrap(core.QueryInt(match, must, alias, defaultValue))
 }
 
 func GQueryRequireString(match string) func(iris.Context) { return GQueryString(match, true, "") }
@@ -324,22 +142,7 @@ func GQueryOptionalStringAlias(match string, alias string) func(iris.Context) {
 }
 
 func GQueryString(match string, must bool, alias string) func(iris.Context) {
-	return func(c iris.Context) {
-		q := c.Request().URL.Query()
-		if q[match] == nil || len(q[match][0]) == 0 {
-			if must {
-				c.Text(fmt.Sprintf("Parse query param: %s failed.", match))
-				c.StopExecution()
-			}
-		} else {
-			c.Values().Set(match, q[match][0])
-			c.Next()
-			if len(alias) > 0 {
-				c.Values().Set(alias, q[match][0])
-				c.Next()
-			}
-		}
-	}
+	return Wrap(core.QueryString(match, must, alias))
 }
 
 func GQueryOptionalStringDefault(match string, defaultValue string) func(iris.Context) {
@@ -347,29 +150,11 @@ func GQueryOptionalStringDefault(match string, defaultValue string) func(iris.Co
 }
 
 func GQueryStringDefault(match string, must bool, alias, defaultValue string) func(iris.Context) {
-	return func(c iris.Context) {
-		q := c.Request().URL.Query()
-		if q[match] == nil || len(q[match][0]) == 0 {
-			if must {
-				c.Text(fmt.Sprintf("Parse query param: %s failed.", match))
-				c.StopExecution()
-			}
-			c.Values().Set(match, defaultValue)
-			if len(alias) > 0 {
-				c.Values().Set(alias, defaultValue)
-			}
-		} else {
-			c.Values().Set(match, q[match][0])
-			c.Next()
-
-			if len(alias) > 0 {
-				c.Values().Set(alias, q[match][0])
-				c.Next()
-			}
-		}
-	}
+	return Wrap(core.QueryStringDefault(match, must, alias, defaultValue))
 }
 
+// GJsonResponse renders the data stored by core.SetResponse or the error stored by core.SetError
+// in the standard envelope after the pending handlers.
 func GJsonResponse(c iris.Context) {
-	c.Header("Content-Type", "application/json")
+	Wrap(core.HandlerFunc(core.JsonResponse))(c)
 }
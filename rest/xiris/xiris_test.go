@@ -0,0 +1,32 @@
+package xiris
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kataras/iris/v12"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGPathAndQuery(t *testing.T) {
+	app := iris.New()
+	app.Get("/users/{id}", GPathRequireIntAlias("id", "uid"), GQueryOptionalIntDefault("page", 1),
+		func(c iris.Context) {
+			c.JSON(iris.Map{
+				"id":   c.Values().Get("uid"),
+				"page": c.Values().Get("page"),
+			})
+		})
+	assert.Nil(t, app.Build())
+
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/3", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"id":3,"page":1}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/abc", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"code":40000,"msg":"Parse path id failed."}`, w.Body.String())
+}